@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// run-task runs a single task configured from TASK_* environment
+// variables and exits with the container's exit code.
+func main() {
+	c, err := task.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid task config: %v\n", err)
+	}
+
+	d, err := task.NewDocker(&c)
+	if err != nil {
+		log.Fatalf("%v\n", err)
+	}
+	result := d.Run()
+	if result.Error != nil {
+		log.Fatalf("Error running task: %v\n", result.Error)
+	}
+	log.Printf("Container %s is running\n", result.ContainerId)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	statusCh, errCh := d.Client.ContainerWait(context.Background(), result.ContainerId, container.WaitConditionNotRunning)
+	select {
+	case s := <-sig:
+		log.Printf("Received %v, stopping container %s\n", s, result.ContainerId)
+		d.Stop(result.ContainerId)
+		os.Exit(1)
+	case err := <-errCh:
+		log.Fatalf("Error waiting for container %s: %v\n", result.ContainerId, err)
+	case status := <-statusCh:
+		log.Printf("Container %s exited with code %d\n", result.ContainerId, status.StatusCode)
+		d.Stop(result.ContainerId)
+		os.Exit(int(status.StatusCode))
+	}
+}
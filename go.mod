@@ -6,4 +6,6 @@ require (
 	github.com/docker/go-connections v0.5.0
 	github.com/golang-collections/collections v0.0.0-20130729185459-604e922904d3
 	github.com/google/uuid v1.6.0
+	google.golang.org/grpc v1.67.1
+	google.golang.org/protobuf v1.34.2
 )
@@ -7,6 +7,7 @@ import (
 
 	"github.com/sajalkmr/ordo/manager"
 	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/scheduler"
 	"github.com/sajalkmr/ordo/task"
 	"github.com/sajalkmr/ordo/worker"
 
@@ -38,21 +39,21 @@ func main() {
 		Queue: *queue.New(),
 		Db:    make(map[uuid.UUID]*task.Task),
 	}
-	fmt.Printf("worker: %v\n", w)
+	fmt.Printf("worker: %v\n", &w)
 	w.CollectStats()
 	w.RunTask()
-	w.StartTask()
-	w.StopTask()
 
 	m := manager.Manager{
-		Pending: *queue.New(),
-		TaskDb:  make(map[string][]task.Task),
-		EventDb: make(map[string][]task.TaskEvent),
-		Workers: []string{w.Name},
+		TaskDb:        make(map[string][]task.Task),
+		EventDb:       make(map[string][]task.TaskEvent),
+		Workers:       []string{w.Name},
+		WorkerTaskMap: make(map[string][]uuid.UUID),
+		TaskWorkerMap: make(map[uuid.UUID]string),
+		Scheduler:     &scheduler.RoundRobin{Name: "roundrobin"},
 	}
 
-	fmt.Printf("manager: %v\n", m)
-	m.SelectWorker()
+	fmt.Printf("manager: %v\n", &m)
+	m.AddTask(te)
 	m.UpdateTasks()
 	m.SendWork()
 
@@ -0,0 +1,35 @@
+package manager
+
+import "log"
+
+// Pause stops the manager from placing tasks. Submitted tasks are still
+// accepted and queued as Pending, and running tasks are left alone.
+func (m *Manager) Pause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.paused {
+		log.Println("Scheduling paused")
+	}
+	m.paused = true
+}
+
+// Resume re-enables scheduling. The pending backlog is placed in
+// submission order on the next SendWork.
+func (m *Manager) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paused {
+		log.Printf("Scheduling resumed with %d pending tasks\n", m.Pending.Len())
+	}
+	m.paused = false
+	m.wake()
+}
+
+func (m *Manager) Paused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.paused
+}
@@ -0,0 +1,156 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type Health struct {
+	Status string
+	Paused bool
+}
+
+func (a *Api) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Health{Status: "ok", Paused: a.Manager.Paused()})
+}
+
+func (a *Api) PauseHandler(w http.ResponseWriter, r *http.Request) {
+	a.Manager.Pause()
+	writeJSON(w, http.StatusOK, Health{Status: "ok", Paused: true})
+}
+
+func (a *Api) ResumeHandler(w http.ResponseWriter, r *http.Request) {
+	a.Manager.Resume()
+	writeJSON(w, http.StatusOK, Health{Status: "ok", Paused: false})
+}
+
+// PruneImagesHandler prunes images on every worker. Only dangling images
+// are removed unless ?all=true.
+func (a *Api) PruneImagesHandler(w http.ResponseWriter, r *http.Request) {
+	all := false
+	if v := r.URL.Query().Get("all"); v != "" {
+		var err error
+		all, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid all parameter %q", v))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, a.Manager.PruneImages(all))
+}
+
+// ShutdownHandler stops every task in the cluster and then shuts the
+// manager down. It requires ?confirm=true so a stray request can't take
+// the cluster down.
+func (a *Api) ShutdownHandler(w http.ResponseWriter, r *http.Request) {
+	if confirm, _ := strconv.ParseBool(r.URL.Query().Get("confirm")); !confirm {
+		writeError(w, http.StatusBadRequest, "Shutting down the cluster requires ?confirm=true")
+		return
+	}
+	writeJSON(w, http.StatusOK, a.Manager.Shutdown(r.Header.Get(RequestIDHeader)))
+}
+
+// WarmImageHandler has workers pull an image before tasks need it,
+// reporting how each pull went.
+func (a *Api) WarmImageHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var req WarmImageRequest
+	if err := d.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+	report, err := a.Manager.WarmImage(req, r.Header.Get(RequestIDHeader))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// GetOrphansHandler lists containers ordo created that no task refers
+// to.
+func (a *Api) GetOrphansHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.Orphans())
+}
+
+// AdoptContainerHandler creates a task tracking an orphaned container.
+func (a *Api) AdoptContainerHandler(w http.ResponseWriter, r *http.Request) {
+	t, err := a.Manager.AdoptContainer(r.PathValue("containerID"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// ReloadHandler re-reads the config file and applies it, answering with
+// the config now in effect.
+func (a *Api) ReloadHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := a.Reload()
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// GetWebhooksHandler lists the registered webhooks.
+func (a *Api) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.Webhooks())
+}
+
+// AddWebhookHandler registers a webhook for task events.
+func (a *Api) AddWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var wh Webhook
+	if err := d.Decode(&wh); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+	wh, err := a.Manager.AddWebhook(wh)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, wh)
+}
+
+func (a *Api) RemoveWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.Manager.RemoveWebhook(r.PathValue("id")); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportHandler returns a snapshot of the manager's state. Registry
+// passwords are redacted unless the request sets secrets=true.
+func (a *Api) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	secrets, _ := strconv.ParseBool(r.URL.Query().Get("secrets"))
+	writeJSON(w, http.StatusOK, a.Manager.Export(secrets))
+}
+
+// ImportHandler restores a snapshot from GET /admin/export into a
+// manager that has no state yet.
+func (a *Api) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	var s Snapshot
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+	if err := a.Manager.Import(s); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) ReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.Reconcile())
+}
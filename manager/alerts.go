@@ -0,0 +1,73 @@
+package manager
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultAlertWindow = 3
+
+// checkAlerts compares a usage sample with the task's alert thresholds
+// and publishes an alert once a threshold has been exceeded for
+// AlertWindow samples in a row. It fires once per breach; usage has to
+// drop back under the threshold before the same alert fires again.
+func (m *Manager) checkAlerts(u taskUsage) {
+	t := m.getTask(u.ID)
+	if t == nil || t.State != task.Running {
+		delete(m.breaches, u.ID)
+		return
+	}
+
+	if t.CpuAlertThreshold > 0 && t.CPU > 0 {
+		m.checkAlert(t, task.AlertCPU, u.CPU/t.CPU*100, t.CpuAlertThreshold)
+	}
+	if t.MemoryAlertThreshold > 0 && t.Memory > 0 {
+		m.checkAlert(t, task.AlertMemory, float64(u.Memory)/float64(t.Memory)*100, t.MemoryAlertThreshold)
+	}
+}
+
+// pruneBreaches forgets the breaches of tasks that are no longer running,
+// which stop being sampled and so never drop back under their threshold.
+// The lock must be held.
+func (m *Manager) pruneBreaches() {
+	for id := range m.breaches {
+		if t := m.getTask(id); t == nil || t.State != task.Running {
+			delete(m.breaches, id)
+		}
+	}
+}
+
+func (m *Manager) checkAlert(t *task.Task, resource string, pct, threshold float64) {
+	if pct <= threshold {
+		delete(m.breaches[t.ID], resource)
+		return
+	}
+	if m.breaches == nil {
+		m.breaches = make(map[uuid.UUID]map[string]int)
+	}
+	if m.breaches[t.ID] == nil {
+		m.breaches[t.ID] = make(map[string]int)
+	}
+	m.breaches[t.ID][resource]++
+
+	window := m.AlertWindow
+	if window <= 0 {
+		window = defaultAlertWindow
+	}
+	if m.breaches[t.ID][resource] != window {
+		return
+	}
+
+	log.Printf("[%s] Task %v is using %.1f%% of its %s limit (alert threshold %.1f%%)\n",
+		t.CorrelationID, t.ID, pct, resource, threshold)
+	m.Events.Publish(task.TaskEvent{
+		ID:            uuid.New(),
+		State:         t.State,
+		Timestamp:     m.clock().Now(),
+		Task:          *t,
+		CorrelationID: t.CorrelationID,
+		Alert:         &task.Alert{Resource: resource, Value: pct, Threshold: threshold},
+	})
+}
@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// RequestIDHeader carries the correlation ID of a request between
+// clients, the manager and workers.
+const RequestIDHeader = "X-Request-ID"
+
+type Api struct {
+	Address string
+	Port    int
+	// RPCPort serves the gRPC API when non-zero.
+	RPCPort int
+	Manager *Manager
+	Router  *http.ServeMux
+	// AdminToken is the bearer token destructive admin endpoints
+	// require. They are refused while it's empty.
+	AdminToken string
+	// ConfigPath is the config file POST /admin/reload re-reads.
+	ConfigPath string
+}
+
+func (a *Api) initRouter() {
+	a.Router = http.NewServeMux()
+	a.Router.HandleFunc("POST /tasks", a.StartTaskHandler)
+	a.Router.HandleFunc("GET /tasks", a.GetTasksHandler)
+	a.Router.HandleFunc("GET /tasks/history", a.GetHistoryHandler)
+	a.Router.HandleFunc("GET /tasks/{id}", a.GetTaskHandler)
+	a.Router.HandleFunc("PATCH /tasks/{id}", a.PatchTaskHandler)
+	a.Router.HandleFunc("DELETE /tasks/{id}", a.StopTaskHandler)
+	a.Router.HandleFunc("POST /tasks/{id}/restart", a.RestartTaskHandler)
+	a.Router.HandleFunc("POST /tasks/{id}/requeue", a.RequeueTaskHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/attach", a.requireAdmin(a.AttachTaskHandler))
+	a.Router.HandleFunc("GET /tasks/{id}/logs", a.GetTaskLogsHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/usage", a.GetTaskUsageHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/stats", a.GetTaskStatsHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/diff", a.GetTaskDiffHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/placement", a.GetPlacementHandler)
+	a.Router.HandleFunc("GET /usage", a.GetUsageHandler)
+	a.Router.HandleFunc("GET /metrics/latency", a.GetLatencyHandler)
+	a.Router.HandleFunc("GET /cronjobs", a.GetCronJobsHandler)
+	a.Router.HandleFunc("DELETE /cronjobs/{id}", a.DeleteCronJobHandler)
+	a.Router.HandleFunc("POST /cronjobs/{id}/disable", a.DisableCronJobHandler)
+	a.Router.HandleFunc("POST /cronjobs/{id}/enable", a.EnableCronJobHandler)
+	a.Router.HandleFunc("GET /quotas", a.GetQuotasHandler)
+	a.Router.HandleFunc("GET /pulls/tokens", a.GetPullTokensHandler)
+	a.Router.HandleFunc("POST /pulls/tokens", a.AcquirePullTokenHandler)
+	a.Router.HandleFunc("DELETE /pulls/tokens/{token}", a.ReleasePullTokenHandler)
+	a.Router.HandleFunc("GET /cluster/stats", a.GetClusterStatsHandler)
+	a.Router.HandleFunc("PATCH /tasks/{id}/resources", a.PatchResourcesHandler)
+	a.Router.HandleFunc("GET /healthz", a.HealthHandler)
+	a.Router.HandleFunc("POST /nodes/{name}/drain", a.requireAdmin(a.DrainNodeHandler))
+	a.Router.HandleFunc("PUT /nodes/{name}/capacity", a.requireAdmin(a.UpdateNodeCapacityHandler))
+	a.Router.HandleFunc("POST /admin/pause", a.requireAdmin(a.PauseHandler))
+	a.Router.HandleFunc("POST /admin/resume", a.requireAdmin(a.ResumeHandler))
+	a.Router.HandleFunc("POST /admin/reconcile", a.requireAdmin(a.ReconcileHandler))
+	a.Router.HandleFunc("POST /admin/prune-images", a.requireAdmin(a.PruneImagesHandler))
+	a.Router.HandleFunc("POST /admin/warm-image", a.requireAdmin(a.WarmImageHandler))
+	a.Router.HandleFunc("GET /admin/orphans", a.requireAdmin(a.GetOrphansHandler))
+	a.Router.HandleFunc("POST /admin/adopt/{containerID}", a.requireAdmin(a.AdoptContainerHandler))
+	a.Router.HandleFunc("POST /admin/shutdown", a.requireAdmin(a.ShutdownHandler))
+	a.Router.HandleFunc("POST /admin/reload", a.requireAdmin(a.ReloadHandler))
+	a.Router.HandleFunc("GET /admin/export", a.requireAdmin(a.ExportHandler))
+	a.Router.HandleFunc("POST /admin/import", a.requireAdmin(a.ImportHandler))
+	a.Router.HandleFunc("GET /admin/webhooks", a.requireAdmin(a.GetWebhooksHandler))
+	a.Router.HandleFunc("POST /admin/webhooks", a.requireAdmin(a.AddWebhookHandler))
+	a.Router.HandleFunc("DELETE /admin/webhooks/{id}", a.requireAdmin(a.RemoveWebhookHandler))
+}
+
+func (a *Api) Start() error {
+	if err := task.ValidateRestartPolicy(a.Manager.DefaultRestartPolicy); err != nil {
+		return fmt.Errorf("default restart policy: %w", err)
+	}
+	a.initRouter()
+	if a.RPCPort != 0 {
+		go func() {
+			err := ServeRPC(fmt.Sprintf("%s:%d", a.Address, a.RPCPort), a.Manager)
+			log.Printf("gRPC server stopped: %v\n", err)
+		}()
+	}
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", a.Address, a.Port),
+		Handler: requestID(jsonErrors(a.Router)),
+	}
+	go func() {
+		<-a.Manager.Done()
+		log.Println("Stopping API server")
+		srv.Shutdown(context.Background())
+	}()
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// requireAdmin only lets requests carrying AdminToken as a bearer token
+// through.
+func (a *Api) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.AdminToken == "" {
+			writeError(w, http.StatusForbidden, "Admin endpoint disabled: no admin token configured")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.AdminToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, "Missing or invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requestID makes sure every request has a correlation ID, generating one
+// when the client didn't send it, and echoes it in the response.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = uuid.NewString()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// attachWorker opens a raw attach stream to the container of a running
+// task on its worker. Output is read from the returned reader and input
+// written to the connection.
+func (m *Manager) attachWorker(id uuid.UUID, correlationID string) (net.Conn, *bufio.Reader, error) {
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil {
+		m.mu.Unlock()
+		return nil, nil, ErrTaskNotFound
+	}
+	n := m.getNode(m.TaskWorkerMap[id])
+	if t.State.Terminal() || n == nil || n.Api == "" {
+		m.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: task %v is %v and not placed on a worker", ErrTaskNotRunning, id, t.State)
+	}
+	node := *n
+	m.mu.Unlock()
+
+	u, err := url.Parse(node.Api)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, workerClient.Timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: error connecting to worker %s: %v", ErrWorkerUnavailable, node.Name, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/tasks/%v/attach", node.Api, id), nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	if correlationID != "" {
+		req.Header.Set(RequestIDHeader, correlationID)
+	}
+
+	conn.SetDeadline(time.Now().Add(workerClient.Timeout))
+	br := bufio.NewReader(conn)
+	var resp *http.Response
+	if err = req.Write(conn); err == nil {
+		resp, err = http.ReadResponse(br, req)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("%w: error attaching on worker %s: %v", ErrWorkerUnavailable, node.Name, err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer conn.Close()
+		e := ErrResponse{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return nil, nil, &WorkerError{Node: node.Name, StatusCode: resp.StatusCode, Code: e.Error.Code, Message: e.Error.Message}
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, br, nil
+}
+
+// AttachTaskHandler bridges a websocket client to the streams of a
+// running task's container, wherever it runs. Binary or text messages
+// from the client go to the container's stdin, and its stdout and stderr
+// come back as binary messages. The detach keys (ctrl-p ctrl-q unless the
+// worker is configured otherwise) end the attach and leave the task
+// running; the connection also closes when the container exits or either
+// side goes away. It gives a shell into the task, so it needs the admin
+// token.
+func (a *Api) AttachTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+	if !isWebSocketRequest(r) {
+		writeError(w, http.StatusBadRequest, "Attaching requires a websocket connection")
+		return
+	}
+
+	correlationID := r.Header.Get(RequestIDHeader)
+	conn, out, err := a.Manager.attachWorker(id, correlationID)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	defer conn.Close()
+
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Printf("[%s] Error upgrading attach to task %v: %v\n", correlationID, id, err)
+		return
+	}
+	defer ws.Close()
+
+	log.Printf("[%s] Client attached to task %v\n", correlationID, id)
+	go func() {
+		io.Copy(conn, ws)
+		if tc, ok := conn.(*net.TCPConn); ok {
+			tc.CloseWrite()
+		}
+	}()
+	io.Copy(ws, out)
+	log.Printf("[%s] Client detached from task %v\n", correlationID, id)
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	schedulingBackoffBase = 1 * time.Second
+	schedulingBackoffMax  = 5 * time.Minute
+)
+
+// backoff tracks how long an unschedulable task waits before the
+// manager tries to place it again.
+type backoff struct {
+	attempts int
+	next     time.Time
+}
+
+func (m *Manager) deferTask(id uuid.UUID) *backoff {
+	if m.backoffs == nil {
+		m.backoffs = make(map[uuid.UUID]*backoff)
+	}
+	b, ok := m.backoffs[id]
+	if !ok {
+		b = &backoff{}
+		m.backoffs[id] = b
+	}
+	b.attempts++
+
+	delay := schedulingBackoffBase << (b.attempts - 1)
+	if delay > schedulingBackoffMax || delay <= 0 {
+		delay = schedulingBackoffMax
+	}
+	b.next = m.clock().Now().Add(delay)
+	return b
+}
+
+// CapacityChanged makes every deferred task eligible for scheduling on
+// the next SendWork instead of waiting out its backoff.
+func (m *Manager) CapacityChanged() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.wake()
+}
+
+func (m *Manager) wake() {
+	for _, b := range m.backoffs {
+		b.next = time.Time{}
+	}
+	if m.room != nil {
+		close(m.room)
+		m.room = nil
+	}
+}
+
+// roomSignal returns a channel that's closed the next time capacity
+// changes. The lock must be held.
+func (m *Manager) roomSignal() <-chan struct{} {
+	if m.room == nil {
+		m.room = make(chan struct{})
+	}
+	return m.room
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// CapacityUpdate is the outcome of a node reporting new capacity.
+type CapacityUpdate struct {
+	Node          string
+	Overcommitted bool
+	// Evicted lists the tasks moved off the node to make it fit again.
+	Evicted []uuid.UUID
+}
+
+// UpdateNodeCapacity records a node's memory and disk capacity. If its
+// allocations no longer fit, the node is marked overcommitted and gets no
+// new tasks until they do. With EvictOnOvercommit, the lowest priority
+// tasks, newest first, are rescheduled until it fits, and the worker is
+// told to stop them once the lock is released. A stop that fails is left
+// to Reconcile, which stops containers the manager no longer expects.
+func (m *Manager) UpdateNodeCapacity(name string, memory, disk int) (CapacityUpdate, error) {
+	m.mu.Lock()
+	n := m.getNode(name)
+	if n == nil {
+		m.mu.Unlock()
+		return CapacityUpdate{}, ErrNodeNotFound
+	}
+	n.Memory, n.Disk = memory, disk
+	update := CapacityUpdate{Node: name}
+
+	var evicted []task.Task
+	if overcommitted(n) && m.EvictOnOvercommit {
+		for _, t := range m.evictionOrder(n) {
+			if !overcommitted(n) {
+				break
+			}
+			m.reschedule(n, t, fmt.Sprintf("evicted: node %s's capacity shrank below its allocations", name))
+			update.Evicted = append(update.Evicted, t.ID)
+			evicted = append(evicted, *t)
+		}
+	}
+
+	was := n.Overcommitted
+	n.Overcommitted = overcommitted(n)
+	update.Overcommitted = n.Overcommitted
+	switch {
+	case n.Overcommitted && !was:
+		log.Printf("Node %s is overcommitted: %d/%d memory and %d/%d disk allocated\n",
+			name, n.MemoryAllocated, n.Memory, n.DiskAllocated, n.Disk)
+	case !n.Overcommitted && was:
+		log.Printf("Node %s is no longer overcommitted\n", name)
+	}
+	if len(update.Evicted) > 0 || !n.Overcommitted && was {
+		m.wake()
+	}
+	m.mu.Unlock()
+
+	if n.Api == "" {
+		return update, nil
+	}
+	for _, t := range evicted {
+		err := callWorker(n, http.MethodDelete, fmt.Sprintf("/tasks/%v", t.ID), t.CorrelationID, nil, nil)
+		if err != nil {
+			log.Printf("[%s] Error stopping task %v evicted from overcommitted node %s: %v\n", t.CorrelationID, t.ID, name, err)
+		}
+	}
+	return update, nil
+}
+
+func overcommitted(n *node.Node) bool {
+	return n.MemoryAllocated > n.Memory || n.DiskAllocated > n.Disk
+}
+
+// evictionOrder lists the node's active tasks in the order they're
+// evicted: lowest priority first, and the most recently created first
+// among equals. The lock must be held.
+func (m *Manager) evictionOrder(n *node.Node) []*task.Task {
+	var tasks []*task.Task
+	for _, id := range m.WorkerTaskMap[n.Name] {
+		if t := m.getTask(id); t != nil && !t.State.Terminal() {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority < tasks[j].Priority
+		}
+		return tasks[i].CreateTime.After(tasks[j].CreateTime)
+	})
+	return tasks
+}
@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/sajalkmr/ordo/node"
+)
+
+// WorkerStats is the part of a worker's GET /stats response the manager
+// uses.
+type WorkerStats struct {
+	TaskCount   int
+	DiskFree    uint64
+	DiskTotal   uint64
+	MemTotal    uint64
+	MemUsed     uint64
+	CPUs        int
+	CPUUsed     float64
+	UsernsRemap string
+	Network     NetworkStats
+}
+
+// NetworkStats is the rate of network traffic through task containers.
+type NetworkStats struct {
+	RxBytesPerSec   float64
+	TxBytesPerSec   float64
+	RxPacketsPerSec float64
+	TxPacketsPerSec float64
+}
+
+func (n *NetworkStats) add(o NetworkStats) {
+	n.RxBytesPerSec += o.RxBytesPerSec
+	n.TxBytesPerSec += o.TxBytesPerSec
+	n.RxPacketsPerSec += o.RxPacketsPerSec
+	n.TxPacketsPerSec += o.TxPacketsPerSec
+}
+
+// ResourceStats compares what the scheduler has allocated of a resource
+// with what is actually in use.
+type ResourceStats struct {
+	Total            float64
+	Allocated        float64
+	Used             float64
+	AllocatedPercent float64
+	UsedPercent      float64
+}
+
+func (r *ResourceStats) add(o ResourceStats) {
+	r.Total += o.Total
+	r.Allocated += o.Allocated
+	r.Used += o.Used
+}
+
+func (r *ResourceStats) computePercents() {
+	if r.Total > 0 {
+		r.AllocatedPercent = r.Allocated / r.Total * 100
+		r.UsedPercent = r.Used / r.Total * 100
+	}
+}
+
+type NodeStats struct {
+	Name   string
+	CPU    ResourceStats
+	Memory ResourceStats
+	Disk   ResourceStats
+	Tasks  int
+	// Network is the traffic of the node's running tasks as the worker
+	// last sampled it.
+	Network NetworkStats
+	// UsernsRemap is the worker's userns-remap mode, "disabled" or the
+	// host UID:GID container root maps to.
+	UsernsRemap string `json:",omitempty"`
+	// Error is set when the worker's usage couldn't be fetched; Used is
+	// zero for such nodes.
+	Error string `json:",omitempty"`
+}
+
+// ClusterStats is the cluster-wide view of capacity, allocation and real
+// usage. CPU is in cores and memory and disk in bytes.
+type ClusterStats struct {
+	CPU     ResourceStats
+	Memory  ResourceStats
+	Disk    ResourceStats
+	Network NetworkStats
+	Tasks   map[string]int
+	Nodes   []NodeStats
+}
+
+// total sums the per-node figures into the cluster-wide ones.
+func (s *ClusterStats) total() {
+	for _, n := range s.Nodes {
+		s.CPU.add(n.CPU)
+		s.Memory.add(n.Memory)
+		s.Disk.add(n.Disk)
+		s.Network.add(n.Network)
+	}
+	s.CPU.computePercents()
+	s.Memory.computePercents()
+	s.Disk.computePercents()
+}
+
+// ClusterStats combines the manager's allocations with the usage each
+// worker reports. Workers are queried in parallel and unreachable ones
+// are reported with an error rather than failing the whole request.
+func (m *Manager) ClusterStats() ClusterStats {
+	m.mu.Lock()
+	stats := ClusterStats{Tasks: make(map[string]int)}
+	cpuAllocated := make(map[string]float64)
+	for _, versions := range m.TaskDb {
+		t := versions[len(versions)-1]
+		stats.Tasks[t.State.String()]++
+		if !t.State.Terminal() {
+			cpuAllocated[m.TaskWorkerMap[t.ID]] += t.CPU
+		}
+	}
+	nodes := make([]node.Node, len(m.WorkerNodes))
+	for i, n := range m.WorkerNodes {
+		nodes[i] = *n
+	}
+	m.mu.Unlock()
+
+	stats.Nodes = make([]NodeStats, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		stats.Nodes[i] = NodeStats{
+			Name:   n.Name,
+			CPU:    ResourceStats{Total: float64(n.Cores), Allocated: cpuAllocated[n.Name]},
+			Memory: ResourceStats{Total: float64(n.Memory), Allocated: float64(n.MemoryAllocated)},
+			Disk:   ResourceStats{Total: float64(n.Disk), Allocated: float64(n.DiskAllocated)},
+			Tasks:  n.TaskCount,
+		}
+		if n.Api == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(ns *NodeStats, n node.Node) {
+			defer wg.Done()
+			var ws WorkerStats
+			if err := callWorker(&n, http.MethodGet, "/stats", "", nil, &ws); err != nil {
+				ns.Error = err.Error()
+				return
+			}
+			ns.CPU.Used = ws.CPUUsed
+			ns.Memory.Used = float64(ws.MemUsed)
+			if ws.DiskTotal > ws.DiskFree {
+				ns.Disk.Used = float64(ws.DiskTotal - ws.DiskFree)
+			}
+			ns.Tasks = ws.TaskCount
+			ns.UsernsRemap = ws.UsernsRemap
+			ns.Network = ws.Network
+		}(&stats.Nodes[i], n)
+	}
+	wg.Wait()
+
+	for i := range stats.Nodes {
+		stats.Nodes[i].CPU.computePercents()
+		stats.Nodes[i].Memory.computePercents()
+		stats.Nodes[i].Disk.computePercents()
+	}
+	stats.total()
+	return stats
+}
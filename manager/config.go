@@ -0,0 +1,248 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sajalkmr/ordo/scheduler"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// Config is the manager's config file. Everything except the listen
+// addresses can be changed by POST /admin/reload without a restart.
+type Config struct {
+	Address string
+	Port    int
+	RPCPort int
+
+	// Scheduler is "roundrobin", "spread" or "weightedrandom", or a
+	// comma-separated list of them tried in order, e.g.
+	// "spread,roundrobin".
+	Scheduler             string
+	ReconcileInterval     Duration
+	PollConcurrency       int
+	PollTimeout           Duration
+	RescheduleConcurrency int
+	HistoryTTL            Duration
+	ShutdownTimeout       Duration
+	DefaultRestartPolicy  string
+	NameTemplate          string
+	UsageBasedScheduling  bool
+	UsageWindow           int
+	AlertWindow           int
+	// MaxConcurrentPulls caps image pulls across the cluster; zero
+	// doesn't limit them.
+	MaxConcurrentPulls int
+	// Quotas cap what groups of tasks may use across the cluster.
+	Quotas []Quota
+	// MaxTaskAge recycles tasks labelled ordo.recycle=true once they've
+	// run that long.
+	MaxTaskAge        Duration
+	RecycleReschedule bool
+	RecycleStagger    Duration
+	// EvictOnOvercommit moves tasks off a node whose capacity shrinks
+	// below its allocations.
+	EvictOnOvercommit bool
+	// MaxSubmitWait caps the wait POST /tasks?wait= may ask for.
+	MaxSubmitWait Duration
+}
+
+// Duration is a time.Duration written as a string such as "30s" in the
+// config file.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads and validates a config file. Unknown fields are
+// rejected so a typo doesn't silently leave a setting unchanged.
+func LoadConfig(path string) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+
+	var c Config
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c Config) Validate() error {
+	if _, err := newScheduler(c.Scheduler); err != nil {
+		return err
+	}
+	if err := task.ValidateRestartPolicy(c.DefaultRestartPolicy); err != nil {
+		return fmt.Errorf("default restart policy: %w", err)
+	}
+	for name, d := range map[string]Duration{
+		"reconcile interval": c.ReconcileInterval,
+		"poll timeout":       c.PollTimeout,
+		"history TTL":        c.HistoryTTL,
+		"shutdown timeout":   c.ShutdownTimeout,
+		"max task age":       c.MaxTaskAge,
+		"recycle stagger":    c.RecycleStagger,
+	} {
+		if d < 0 {
+			return fmt.Errorf("%s must not be negative, got %v", name, time.Duration(d))
+		}
+	}
+	for _, q := range c.Quotas {
+		if err := q.Validate(); err != nil {
+			return err
+		}
+	}
+	for name, n := range map[string]int{
+		"poll concurrency":       c.PollConcurrency,
+		"reschedule concurrency": c.RescheduleConcurrency,
+		"usage window":           c.UsageWindow,
+		"alert window":           c.AlertWindow,
+		"max concurrent pulls":   c.MaxConcurrentPulls,
+	} {
+		if n < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", name, n)
+		}
+	}
+	return nil
+}
+
+func newScheduler(name string) (scheduler.Scheduler, error) {
+	if strings.Contains(name, ",") {
+		var chain []scheduler.Scheduler
+		for _, n := range strings.Split(name, ",") {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				return nil, fmt.Errorf("empty scheduler in chain %q", name)
+			}
+			s, err := newScheduler(n)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, s)
+		}
+		return scheduler.NewChainScheduler(chain...), nil
+	}
+
+	switch name {
+	case "", "roundrobin":
+		return &scheduler.RoundRobin{Name: "roundrobin"}, nil
+	case "spread":
+		return &scheduler.SpreadScheduler{Name: "spread"}, nil
+	case "weightedrandom":
+		return scheduler.NewWeightedRandomScheduler("weightedrandom", nil), nil
+	}
+	return nil, fmt.Errorf("unknown scheduler %q", name)
+}
+
+// ApplyConfig switches the manager to the reloadable settings in c. They
+// change together, between reconciliation passes, so no pass or
+// placement sees a mix of old and new settings. A scheduler of the same
+// kind is kept rather than replaced, so it doesn't lose its state.
+func (m *Manager) ApplyConfig(c Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	sched, _ := newScheduler(c.Scheduler)
+	quotas, _ := parseQuotas(c.Quotas)
+
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Scheduler == nil || scheduler.NameOf(m.Scheduler) != scheduler.NameOf(sched) {
+		m.Scheduler = sched
+	}
+	m.ReconcileInterval = time.Duration(c.ReconcileInterval)
+	m.PollConcurrency = c.PollConcurrency
+	m.PollTimeout = time.Duration(c.PollTimeout)
+	m.RescheduleConcurrency = c.RescheduleConcurrency
+	m.HistoryTTL = time.Duration(c.HistoryTTL)
+	m.ShutdownTimeout = time.Duration(c.ShutdownTimeout)
+	m.DefaultRestartPolicy = c.DefaultRestartPolicy
+	m.NameTemplate = c.NameTemplate
+	m.UsageBasedScheduling = c.UsageBasedScheduling
+	m.UsageWindow = c.UsageWindow
+	m.AlertWindow = c.AlertWindow
+	m.quotas = quotas
+	m.MaxTaskAge = time.Duration(c.MaxTaskAge)
+	m.RecycleReschedule = c.RecycleReschedule
+	m.RecycleStagger = time.Duration(c.RecycleStagger)
+	m.EvictOnOvercommit = c.EvictOnOvercommit
+	m.MaxSubmitWait = time.Duration(c.MaxSubmitWait)
+	if m.MaxConcurrentPulls != c.MaxConcurrentPulls {
+		m.MaxConcurrentPulls = c.MaxConcurrentPulls
+		m.pulls.mu.Lock()
+		m.pulls.broadcast()
+		m.pulls.mu.Unlock()
+	}
+	return nil
+}
+
+var (
+	// ErrInvalidConfig is returned when a reload can't use the config
+	// file.
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrNotReloadable is returned when a reload changes a setting that
+	// only takes effect on restart.
+	ErrNotReloadable = errors.New("setting can't be changed without a restart")
+)
+
+// Reload re-reads ConfigPath and applies it. A config that fails to load
+// or validate, or that changes the listen addresses, is refused and the
+// running config is left as it was.
+func (a *Api) Reload() (Config, error) {
+	if a.ConfigPath == "" {
+		return Config{}, fmt.Errorf("%w: the manager was started without a config file", ErrInvalidConfig)
+	}
+	c, err := LoadConfig(a.ConfigPath)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	var changed []string
+	if c.Address != a.Address {
+		changed = append(changed, fmt.Sprintf("Address %q -> %q", a.Address, c.Address))
+	}
+	if c.Port != a.Port {
+		changed = append(changed, fmt.Sprintf("Port %d -> %d", a.Port, c.Port))
+	}
+	if c.RPCPort != a.RPCPort {
+		changed = append(changed, fmt.Sprintf("RPCPort %d -> %d", a.RPCPort, c.RPCPort))
+	}
+	if len(changed) > 0 {
+		return Config{}, fmt.Errorf("%w: %s", ErrNotReloadable, strings.Join(changed, ", "))
+	}
+
+	if err := a.Manager.ApplyConfig(c); err != nil {
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	log.Printf("Reloaded config from %s\n", a.ConfigPath)
+	return c, nil
+}
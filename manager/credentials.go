@@ -0,0 +1,162 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// CredentialStore maps label selectors to registry credentials so tasks
+// don't have to carry registry passwords themselves. A selector such as
+// "app=internal,team=payments" matches tasks that have every one of its
+// labels.
+type CredentialStore struct {
+	mu      sync.Mutex
+	entries []credentialEntry
+}
+
+type credentialEntry struct {
+	selector map[string]string
+	auth     task.RegistryAuth
+}
+
+// Add registers credentials for tasks matching selector. Adding the same
+// selector again replaces its credentials.
+func (s *CredentialStore) Add(selector string, auth task.RegistryAuth) error {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.entries {
+		if sameSelector(e.selector, sel) {
+			s.entries[i].auth = auth
+			return nil
+		}
+	}
+	s.entries = append(s.entries, credentialEntry{selector: sel, auth: auth})
+	return nil
+}
+
+// Resolve returns the credentials for a task with these labels. When
+// several selectors match, the one with the most labels wins, and among
+// equally specific ones the one added first.
+func (s *CredentialStore) Resolve(labels map[string]string) (task.RegistryAuth, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	best := -1
+	for i, e := range s.entries {
+		if !matchesSelector(e.selector, labels) {
+			continue
+		}
+		if best == -1 || len(e.selector) > len(s.entries[best].selector) {
+			best = i
+		}
+	}
+	if best == -1 {
+		return task.RegistryAuth{}, false
+	}
+	return s.entries[best].auth, true
+}
+
+func parseSelector(selector string) (map[string]string, error) {
+	sel := make(map[string]string)
+	for _, term := range strings.Split(selector, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(term), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid selector term %q, want key=value", term)
+		}
+		sel[key] = value
+	}
+	return sel, nil
+}
+
+func matchesSelector(sel, labels map[string]string) bool {
+	for k, v := range sel {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func sameSelector(a, b map[string]string) bool {
+	return len(a) == len(b) && matchesSelector(a, b)
+}
+
+// keepRegistryAuth sets aside the credentials a task was submitted with.
+// They're taken off the stored task, so they never show up in the
+// manager's API or events. The lock must be held.
+func (m *Manager) keepRegistryAuth(id uuid.UUID, auth *task.RegistryAuth) {
+	if auth == nil {
+		return
+	}
+	if m.registryAuth == nil {
+		m.registryAuth = make(map[uuid.UUID]task.RegistryAuth)
+	}
+	m.registryAuth[id] = *auth
+}
+
+// withCredentials fills in the task's registry credentials, its own if it
+// was submitted with some and otherwise from the store. It's applied to
+// the copy sent to a worker rather than the stored task. The lock must be
+// held.
+func (m *Manager) withCredentials(t task.Task) task.Task {
+	if t.RegistryAuth != nil {
+		return t
+	}
+	if auth, ok := m.registryAuth[t.ID]; ok {
+		t.RegistryAuth = &auth
+		return t
+	}
+	if auth, ok := m.Credentials.Resolve(t.Labels); ok {
+		t.RegistryAuth = &auth
+	}
+	return t
+}
+
+// Credential is a credential store entry as it's exported.
+type Credential struct {
+	Selector string
+	Auth     task.RegistryAuth
+}
+
+// Entries lists the store's credentials in the order they were added.
+func (s *CredentialStore) Entries() []Credential {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds := make([]Credential, len(s.entries))
+	for i, e := range s.entries {
+		creds[i] = Credential{Selector: formatSelector(e.selector), Auth: e.auth}
+	}
+	return creds
+}
+
+func parseCredentials(creds []Credential) ([]credentialEntry, error) {
+	entries := make([]credentialEntry, 0, len(creds))
+	for _, c := range creds {
+		sel, err := parseSelector(c.Selector)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, credentialEntry{selector: sel, auth: c.Auth})
+	}
+	return entries, nil
+}
+
+func formatSelector(sel map[string]string) string {
+	terms := make([]string, 0, len(sel))
+	for k, v := range sel {
+		terms = append(terms, k+"="+v)
+	}
+	slices.Sort(terms)
+	return strings.Join(terms, ",")
+}
@@ -0,0 +1,245 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/cron"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// ErrCronJobNotFound is returned for an unknown recurring task.
+var ErrCronJobNotFound = errors.New("recurring task not found")
+
+const (
+	defaultCronHistory  = 10
+	defaultCronInterval = 10 * time.Second
+)
+
+// CronJob is a recurring task: a template the manager starts a new task
+// from each time its schedule fires.
+type CronJob struct {
+	ID                uuid.UUID
+	Name              string
+	Schedule          string
+	ConcurrencyPolicy string
+	// Disabled recurring tasks start no runs until they're enabled
+	// again.
+	Disabled bool
+	LastRun  time.Time
+	NextRun  time.Time
+	// Runs are the most recent runs, oldest first.
+	Runs []CronRun
+
+	template task.Task
+	sched    *cron.Schedule
+}
+
+// CronRun is one scheduled time of a CronJob. Skipped says why no task
+// was started for it; otherwise TaskID is the task that was.
+type CronRun struct {
+	ScheduledAt time.Time
+	TaskID      uuid.UUID
+	State       task.State
+	Skipped     string
+}
+
+// addCronJob registers t as the template of a recurring task. The lock
+// must be held.
+func (m *Manager) addCronJob(t task.Task) error {
+	job, err := m.newCronJob(t)
+	if err != nil {
+		return err
+	}
+	if m.cronJobs == nil {
+		m.cronJobs = make(map[uuid.UUID]*CronJob)
+	}
+	m.cronJobs[t.ID] = job
+	log.Printf("[%s] Added recurring task %s (%v), next run at %v\n", t.CorrelationID, t.Name, t.ID, job.NextRun)
+	return nil
+}
+
+// newCronJob checks a recurring task's schedule and works out its first
+// run.
+func (m *Manager) newCronJob(t task.Task) (*CronJob, error) {
+	sched, err := cron.Parse(t.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	if err := task.ValidateConcurrencyPolicy(t.ConcurrencyPolicy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	if t.ConcurrencyPolicy == "" {
+		t.ConcurrencyPolicy = task.ConcurrencyAllow
+	}
+	next := sched.Next(m.clock().Now())
+	if next.IsZero() {
+		return nil, fmt.Errorf("%w: schedule %q never fires", ErrInvalidTask, t.Schedule)
+	}
+	return &CronJob{
+		ID:                t.ID,
+		Name:              t.Name,
+		Schedule:          t.Schedule,
+		ConcurrencyPolicy: t.ConcurrencyPolicy,
+		NextRun:           next,
+		template:          t,
+		sched:             sched,
+	}, nil
+}
+
+// CronJobs lists the recurring tasks with the current state of their
+// recent runs.
+func (m *Manager) CronJobs() []CronJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]CronJob, 0, len(m.cronJobs))
+	for _, j := range m.cronJobs {
+		job := *j
+		job.Runs = append([]CronRun(nil), j.Runs...)
+		for i, r := range job.Runs {
+			if t := m.getTask(r.TaskID); t != nil {
+				job.Runs[i].State = t.State
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].NextRun.Before(jobs[j].NextRun)
+	})
+	return jobs
+}
+
+// RunCronJobs starts a task for every recurring task whose scheduled time
+// has come. If more than one scheduled time has passed since the last
+// check, the runs were missed, and CatchUpMissedRuns decides whether one
+// of them still runs.
+func (m *Manager) RunCronJobs() {
+	var start []task.TaskEvent
+	var stop []uuid.UUID
+
+	m.mu.Lock()
+	now := m.clock().Now()
+	for _, j := range m.cronJobs {
+		if j.Disabled || j.NextRun.IsZero() || now.Before(j.NextRun) {
+			continue
+		}
+		at := j.NextRun
+		missed := !j.sched.Next(at).After(now)
+		j.NextRun = j.sched.Next(now)
+
+		run := CronRun{ScheduledAt: at}
+		var active []uuid.UUID
+		for _, r := range j.Runs {
+			if t := m.getTask(r.TaskID); t != nil && !t.State.Terminal() {
+				active = append(active, r.TaskID)
+			}
+		}
+		switch {
+		case missed && !m.CatchUpMissedRuns:
+			run.Skipped = fmt.Sprintf("missed runs up to %v", now.Truncate(time.Minute))
+		case len(active) > 0 && j.ConcurrencyPolicy == task.ConcurrencyForbid:
+			run.Skipped = fmt.Sprintf("previous run %v is still active", active[0])
+		default:
+			if j.ConcurrencyPolicy == task.ConcurrencyReplace {
+				stop = append(stop, active...)
+			}
+			t := j.template
+			t.ID = uuid.New()
+			t.Schedule = ""
+			t.ConcurrencyPolicy = ""
+			t.CreateTime = time.Time{}
+			if auth, ok := m.registryAuth[j.ID]; ok {
+				t.RegistryAuth = &auth
+			}
+			if t.Name != "" {
+				t.Name = fmt.Sprintf("%s-%d", t.Name, at.Unix())
+			}
+			start = append(start, task.TaskEvent{
+				ID:            uuid.New(),
+				State:         task.Pending,
+				Timestamp:     now,
+				Task:          t,
+				CorrelationID: t.CorrelationID,
+			})
+			run.TaskID = t.ID
+			j.LastRun = at
+		}
+		if run.Skipped != "" {
+			log.Printf("[%s] Skipped run of recurring task %s at %v: %s\n", j.template.CorrelationID, j.Name, at, run.Skipped)
+		}
+
+		history := m.CronHistory
+		if history <= 0 {
+			history = defaultCronHistory
+		}
+		j.Runs = append(j.Runs, run)
+		if len(j.Runs) > history {
+			j.Runs = j.Runs[len(j.Runs)-history:]
+		}
+	}
+	m.mu.Unlock()
+
+	for _, id := range stop {
+		if _, err := m.StopTask(id); err != nil {
+			log.Printf("Error stopping previous run %v of a recurring task: %v\n", id, err)
+		}
+	}
+	for _, te := range start {
+		if err := m.AddTask(te); err != nil {
+			log.Printf("[%s] Error starting run of recurring task: %v\n", te.CorrelationID, err)
+		}
+	}
+}
+
+// CronLoop runs RunCronJobs every interval, or every defaultCronInterval
+// if it's zero.
+func (m *Manager) CronLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultCronInterval
+	}
+	for {
+		m.RunCronJobs()
+		<-m.clock().After(interval)
+	}
+}
+
+// DeleteCronJob removes a recurring task so it starts no more runs. Runs
+// already started are left alone.
+func (m *Manager) DeleteCronJob(id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.cronJobs[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrCronJobNotFound, id)
+	}
+	delete(m.cronJobs, id)
+	delete(m.registryAuth, id)
+	log.Printf("[%s] Deleted recurring task %s (%v)\n", j.template.CorrelationID, j.Name, id)
+	return nil
+}
+
+// SetCronJobDisabled disables or enables a recurring task. An enabled
+// task's next run is worked out afresh, so runs that fell while it was
+// disabled are skipped rather than caught up.
+func (m *Manager) SetCronJobDisabled(id uuid.UUID, disabled bool) (CronJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.cronJobs[id]
+	if !ok {
+		return CronJob{}, fmt.Errorf("%w: %v", ErrCronJobNotFound, id)
+	}
+	if j.Disabled && !disabled {
+		j.NextRun = j.sched.Next(m.clock().Now())
+	}
+	j.Disabled = disabled
+	job := *j
+	job.Runs = append([]CronRun(nil), j.Runs...)
+	return job, nil
+}
@@ -0,0 +1,158 @@
+package manager
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ErrResponse is the body of every API error.
+type ErrResponse struct {
+	Error ErrBody `json:"error"`
+}
+
+type ErrBody struct {
+	Code          string         `json:"code"`
+	Message       string         `json:"message"`
+	Details       map[string]any `json:"details,omitempty"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+}
+
+// Error codes are part of the API; clients branch on them, so existing
+// codes must not change.
+const (
+	CodeValidationFailed  = "VALIDATION_FAILED"
+	CodeNotFound          = "NOT_FOUND"
+	CodeUnauthorized      = "UNAUTHORIZED"
+	CodeForbidden         = "FORBIDDEN"
+	CodeMethodNotAllowed  = "METHOD_NOT_ALLOWED"
+	CodeTaskNotFound      = "TASK_NOT_FOUND"
+	CodeNodeNotFound      = "NODE_NOT_FOUND"
+	CodeTaskNotRunning    = "TASK_NOT_RUNNING"
+	CodeTaskNotPending    = "TASK_NOT_PENDING"
+	CodeNoCandidateNode   = "NO_CANDIDATE_NODE"
+	CodeNoCapacity        = "NO_CAPACITY"
+	CodeSingletonRunning  = "SINGLETON_RUNNING"
+	CodeNameConflict      = "NAME_CONFLICT"
+	CodeConflict          = "CONFLICT"
+	CodeNotReloadable     = "NOT_RELOADABLE"
+	CodeQuotaExceeded     = "QUOTA_EXCEEDED"
+	CodeWorkerError       = "WORKER_ERROR"
+	CodeWorkerUnavailable = "WORKER_UNAVAILABLE"
+	CodeInternal          = "INTERNAL_ERROR"
+)
+
+// errorCodes maps the manager's typed errors to a status and code. The
+// first match wins.
+var errorCodes = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrTaskNotFound, http.StatusNotFound, CodeTaskNotFound},
+	{ErrNodeNotFound, http.StatusNotFound, CodeNodeNotFound},
+	{ErrOrphanNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrWebhookNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrPullTokenNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrNoPlacement, http.StatusNotFound, CodeNotFound},
+	{ErrCronJobNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrInvalidTask, http.StatusBadRequest, CodeValidationFailed},
+	{ErrInvalidCursor, http.StatusBadRequest, CodeValidationFailed},
+	{ErrInvalidConfig, http.StatusBadRequest, CodeValidationFailed},
+	{ErrInvalidWebhook, http.StatusBadRequest, CodeValidationFailed},
+	{ErrInvalidSnapshot, http.StatusBadRequest, CodeValidationFailed},
+	{ErrNotReloadable, http.StatusConflict, CodeNotReloadable},
+	{ErrManagerNotEmpty, http.StatusConflict, CodeConflict},
+	{ErrTaskNotRunning, http.StatusConflict, CodeTaskNotRunning},
+	{ErrTaskNotPending, http.StatusConflict, CodeTaskNotPending},
+	{ErrSingletonRunning, http.StatusConflict, CodeSingletonRunning},
+	{ErrNameConflict, http.StatusConflict, CodeNameConflict},
+	{ErrNoCapacity, http.StatusConflict, CodeNoCapacity},
+	{ErrQuotaExceeded, http.StatusForbidden, CodeQuotaExceeded},
+	{ErrNoCandidateNodes, http.StatusServiceUnavailable, CodeNoCandidateNode},
+	{ErrWorkerUnavailable, http.StatusBadGateway, CodeWorkerUnavailable},
+}
+
+// writeAPIError writes err with the status and code of the typed error it
+// wraps. Errors a worker returned keep the worker's code in the details.
+func writeAPIError(w http.ResponseWriter, err error) {
+	body := ErrBody{Code: CodeInternal, Message: err.Error()}
+	status := http.StatusInternalServerError
+
+	var we *WorkerError
+	if errors.As(err, &we) {
+		status, body.Code = http.StatusBadGateway, CodeWorkerError
+		body.Details = map[string]any{"node": we.Node, "status": we.StatusCode, "code": we.Code}
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			status, body.Code = c.status, c.code
+			break
+		}
+	}
+	writeErrorBody(w, status, body)
+}
+
+// writeError writes an error that doesn't come from a typed error, such
+// as a malformed request, with a generic code for the status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	code := CodeInternal
+	switch status {
+	case http.StatusBadRequest:
+		code = CodeValidationFailed
+	case http.StatusUnauthorized:
+		code = CodeUnauthorized
+	case http.StatusForbidden:
+		code = CodeForbidden
+	case http.StatusNotFound:
+		code = CodeNotFound
+	case http.StatusMethodNotAllowed:
+		code = CodeMethodNotAllowed
+	case http.StatusConflict:
+		code = CodeConflict
+	}
+	writeErrorBody(w, status, ErrBody{Code: code, Message: msg})
+}
+
+func writeErrorBody(w http.ResponseWriter, status int, body ErrBody) {
+	body.CorrelationID = w.Header().Get(RequestIDHeader)
+	log.Printf("[%s] %s: %s\n", body.CorrelationID, body.Code, body.Message)
+	writeJSON(w, status, ErrResponse{Error: body})
+}
+
+// jsonErrors rewrites the plain-text errors written outside our handlers,
+// such as the router's 404 and 405 responses, into the JSON error format.
+func jsonErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&errorWriter{ResponseWriter: w}, r)
+	})
+}
+
+type errorWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (ew *errorWriter) WriteHeader(status int) {
+	if status >= http.StatusBadRequest && strings.HasPrefix(ew.Header().Get("Content-Type"), "text/plain") {
+		ew.replaced = true
+		ew.Header().Del("X-Content-Type-Options")
+		writeError(ew.ResponseWriter, status, http.StatusText(status))
+		return
+	}
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+func (ew *errorWriter) Write(b []byte) (int, error) {
+	if ew.replaced {
+		return len(b), nil
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// handlers can hijack the connection.
+func (ew *errorWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
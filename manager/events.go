@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"sync"
+
+	"github.com/sajalkmr/ordo/task"
+)
+
+// EventBus fans task events out to subscribers. Publishing never blocks:
+// a subscriber that falls behind its buffer misses events.
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[int]chan task.TaskEvent
+	next int
+}
+
+func (b *EventBus) Subscribe(buffer int) (<-chan task.TaskEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = make(map[int]chan task.TaskEvent)
+	}
+	id := b.next
+	b.next++
+	ch := make(chan task.TaskEvent, buffer)
+	b.subs[id] = ch
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[id]; ok {
+			delete(b.subs, id)
+			close(ch)
+		}
+	}
+}
+
+func (b *EventBus) Publish(te task.TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- te:
+		default:
+		}
+	}
+}
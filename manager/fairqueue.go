@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultFairnessKey = "app"
+
+// FairQueue holds pending tasks in one FIFO per value of a fairness label
+// and takes from them in turn, so an app that submits a thousand tasks
+// can't hold up another app's one. Tasks without the label share a
+// queue. The zero value groups by the "app" label.
+type FairQueue struct {
+	// Key is the label tasks are grouped by.
+	Key string
+
+	queues map[string][]task.TaskEvent
+	// keys lists the groups with queued tasks in the order they take
+	// turns; next is whose turn it is.
+	keys []string
+	next int
+	len  int
+}
+
+func (q *FairQueue) Enqueue(te task.TaskEvent) {
+	key := q.Key
+	if key == "" {
+		key = defaultFairnessKey
+	}
+	group := te.Task.Labels[key]
+
+	if q.queues == nil {
+		q.queues = make(map[string][]task.TaskEvent)
+	}
+	if len(q.queues[group]) == 0 {
+		q.keys = append(q.keys, group)
+	}
+	q.queues[group] = append(q.queues[group], te)
+	q.len++
+}
+
+// Dequeue takes the oldest task of the group whose turn it is.
+func (q *FairQueue) Dequeue() (task.TaskEvent, bool) {
+	if q.len == 0 {
+		return task.TaskEvent{}, false
+	}
+	if q.next >= len(q.keys) {
+		q.next = 0
+	}
+
+	group := q.keys[q.next]
+	te := q.queues[group][0]
+	q.queues[group] = q.queues[group][1:]
+	q.len--
+	if len(q.queues[group]) == 0 {
+		delete(q.queues, group)
+		q.keys = append(q.keys[:q.next], q.keys[q.next+1:]...)
+	} else {
+		q.next++
+	}
+	return te, true
+}
+
+func (q *FairQueue) Len() int {
+	return q.len
+}
+
+// PushFront queues te ahead of everything else, including other groups'
+// tasks, so it's the next one Dequeue returns.
+func (q *FairQueue) PushFront(te task.TaskEvent) {
+	key := q.Key
+	if key == "" {
+		key = defaultFairnessKey
+	}
+	group := te.Task.Labels[key]
+
+	if q.queues == nil {
+		q.queues = make(map[string][]task.TaskEvent)
+	}
+	if q.next >= len(q.keys) {
+		q.next = 0
+	}
+	if len(q.queues[group]) == 0 {
+		q.keys = slices.Insert(q.keys, q.next, group)
+	} else {
+		q.next = slices.Index(q.keys, group)
+	}
+	q.queues[group] = append([]task.TaskEvent{te}, q.queues[group]...)
+	q.len++
+}
+
+// Remove drops every queued event for the task.
+func (q *FairQueue) Remove(id uuid.UUID) {
+	for i := 0; i < len(q.keys); i++ {
+		group := q.keys[i]
+		before := len(q.queues[group])
+		q.queues[group] = slices.DeleteFunc(q.queues[group], func(te task.TaskEvent) bool {
+			return te.Task.ID == id
+		})
+		q.len -= before - len(q.queues[group])
+		if len(q.queues[group]) > 0 {
+			continue
+		}
+		delete(q.queues, group)
+		q.keys = slices.Delete(q.keys, i, i+1)
+		if i < q.next {
+			q.next--
+		}
+		i--
+	}
+}
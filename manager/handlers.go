@@ -0,0 +1,398 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// StartTaskHandler submits a task. With ?wait= such as 30s it holds the
+// request until the task is placed and answers with the placement, or
+// with 503 and the reason it couldn't be placed once the wait, capped at
+// the manager's MaxSubmitWait, runs out.
+func (a *Api) StartTaskHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	te := task.TaskEvent{}
+	err := d.Decode(&te)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	if te.Task.CorrelationID == "" {
+		te.Task.CorrelationID = r.Header.Get(RequestIDHeader)
+	}
+	te.CorrelationID = te.Task.CorrelationID
+
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		wait, err = time.ParseDuration(v)
+		if err != nil || wait < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid wait parameter %q", v))
+			return
+		}
+		if te.Task.Schedule != "" {
+			writeError(w, http.StatusBadRequest, "Recurring tasks can't be waited on")
+			return
+		}
+	}
+
+	err = a.Manager.AddTask(te)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	log.Printf("[%s] Added task %v\n", te.CorrelationID, te.Task.ID)
+	te.Task.RegistryAuth = nil
+	if wait == 0 {
+		writeJSON(w, http.StatusCreated, te.Task)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), a.Manager.submitWait(wait))
+	defer cancel()
+	p, err := a.Manager.WaitScheduled(ctx, te.Task.ID)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// GetTasksHandler returns task summaries unless the caller asks for
+// complete task objects with ?full=true. ?app= and ?state= filter the
+// list; ?app= also adds the app's zone distribution. ?limit= and
+// ?cursor= page through it: the total is in X-Total-Count and the cursor
+// for the next page in X-Next-Cursor. ?scheduledBefore= takes an RFC 3339
+// time and lists the tasks waiting to start before it.
+func (a *Api) GetTasksHandler(w http.ResponseWriter, r *http.Request) {
+	full := false
+	if v := r.URL.Query().Get("full"); v != "" {
+		var err error
+		full, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid full parameter %q", v))
+			return
+		}
+	}
+
+	q := TaskQuery{
+		App:    r.URL.Query().Get("app"),
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+	if v := r.URL.Query().Get("state"); v != "" {
+		s, err := task.ParseState(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid state parameter %q", v))
+			return
+		}
+		q.State = &s
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit parameter %q", v))
+			return
+		}
+		q.Limit = limit
+	}
+	if v := r.URL.Query().Get("scheduledBefore"); v != "" {
+		before, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid scheduledBefore parameter %q", v))
+			return
+		}
+		q.ScheduledBefore = &before
+	}
+
+	tasks, next, total, err := a.Manager.ListTasks(q)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+
+	if full {
+		writeJSON(w, http.StatusOK, tasks)
+		return
+	}
+	if q.App == "" {
+		writeJSON(w, http.StatusOK, a.Manager.Summarize(tasks))
+		return
+	}
+	writeJSON(w, http.StatusOK, AppTasks{
+		Tasks: a.Manager.Summarize(tasks),
+		Zones: a.Manager.ZoneDistribution(q.App),
+	})
+}
+
+// AppTasks is the response to GET /tasks?app=..., which also reports how
+// the app's active tasks are spread across zones.
+type AppTasks struct {
+	Tasks []task.TaskSummary
+	Zones map[string]int
+}
+
+// GetHistoryHandler lists finished tasks. It accepts an RFC 3339 ?since=
+// and a ?state= of Completed or Failed.
+func (a *Api) GetHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid since parameter %q", v))
+			return
+		}
+	}
+
+	var state *task.State
+	if v := r.URL.Query().Get("state"); v != "" {
+		s, err := task.ParseState(v)
+		if err != nil || !s.Terminal() {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid state parameter %q", v))
+			return
+		}
+		state = &s
+	}
+
+	writeJSON(w, http.StatusOK, a.Manager.History(since, state))
+}
+
+// GetCronJobsHandler lists recurring tasks with their last and next run
+// times and recent runs.
+func (a *Api) GetCronJobsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.CronJobs())
+}
+
+// DeleteCronJobHandler removes a recurring task. Runs it already started
+// keep running.
+func (a *Api) DeleteCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid recurring task ID %q", r.PathValue("id")))
+		return
+	}
+	if err := a.Manager.DeleteCronJob(id); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) DisableCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	a.setCronJobDisabled(w, r, true)
+}
+
+func (a *Api) EnableCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	a.setCronJobDisabled(w, r, false)
+}
+
+func (a *Api) setCronJobDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid recurring task ID %q", r.PathValue("id")))
+		return
+	}
+	job, err := a.Manager.SetCronJobDisabled(id, disabled)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// GetQuotasHandler lists the quotas with what their groups currently
+// use.
+func (a *Api) GetQuotasHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.Quotas())
+}
+
+// GetTaskHandler returns a task. ?logTail=N, up to maxLogTail, adds the
+// last N lines of its output.
+func (a *Api) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+	lines := 0
+	if v := r.URL.Query().Get("logTail"); v != "" {
+		lines, err = strconv.Atoi(v)
+		if err != nil || lines < 1 || lines > maxLogTail {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid logTail parameter %q, must be 1 to %d", v, maxLogTail))
+			return
+		}
+	}
+
+	t, ok := a.Manager.GetTask(id)
+	if !ok {
+		writeAPIError(w, fmt.Errorf("%w: %v", ErrTaskNotFound, id))
+		return
+	}
+	if lines == 0 {
+		writeJSON(w, http.StatusOK, t)
+		return
+	}
+	tail, note := a.Manager.logTail(r.Context(), id, lines, r.Header.Get(RequestIDHeader))
+	writeJSON(w, http.StatusOK, TaskWithLogs{Task: t, LogTail: tail, LogNote: note})
+}
+
+// StopTaskHandler stops a task gracefully, waiting out its
+// DeregisterDelay, unless ?force=true.
+func (a *Api) StopTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	stop := a.Manager.StopTask
+	if force, _ := strconv.ParseBool(r.URL.Query().Get("force")); force {
+		stop = a.Manager.ForceStopTask
+	}
+	t, err := stop(id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	log.Printf("[%s] Stopped task %v\n", t.CorrelationID, t.ID)
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (a *Api) GetTaskDiffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	changes, err := a.Manager.TaskDiff(id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// RestartRequest optionally switches a task to a new image as part of a
+// restart.
+type RestartRequest struct {
+	Image string
+	// RerunInit runs the task's init containers again before the new
+	// container starts. Restarts skip them otherwise.
+	RerunInit bool
+}
+
+func (a *Api) RestartTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	var req RestartRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	t, err := a.Manager.RestartTask(id, req)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// RequeueTaskHandler moves a stuck Pending or Scheduled task to the front
+// of the scheduling queue. Placement happens on the next scheduling pass,
+// so it answers 202 with the requeued task.
+func (a *Api) RequeueTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	t, err := a.Manager.RequeueTask(id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, t)
+}
+
+type TaskPatch struct {
+	Name string
+}
+
+func (a *Api) PatchTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	var p TaskPatch
+	err = json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	t, err := a.Manager.RenameTask(id, p.Name)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+type ResourcePatch struct {
+	CPU    float64
+	Memory int64
+}
+
+func (a *Api) PatchResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	var p ResourcePatch
+	err = json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	t, err := a.Manager.UpdateTaskResources(id, p.CPU, p.Memory)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
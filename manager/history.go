@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// purgeBatch bounds how many tasks a single purge pass removes so the
+// manager lock is never held for long.
+const purgeBatch = 100
+
+// History returns terminal tasks that finished at or after since,
+// optionally restricted to a single state, oldest first.
+func (m *Manager) History(since time.Time, state *task.State) []task.Task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tasks []task.Task
+	for _, versions := range m.TaskDb {
+		t := versions[len(versions)-1]
+		if !t.State.Terminal() || t.FinishTime.Before(since) {
+			continue
+		}
+		if state != nil && t.State != *state {
+			continue
+		}
+		tasks = append(tasks, *t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].FinishTime.Before(tasks[j].FinishTime)
+	})
+	return tasks
+}
+
+// PurgeHistory removes up to purgeBatch terminal tasks that finished more
+// than HistoryTTL ago and returns how many were removed.
+func (m *Manager) PurgeHistory() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.HistoryTTL <= 0 {
+		return 0
+	}
+	cutoff := m.clock().Now().Add(-m.HistoryTTL)
+	var expired []uuid.UUID
+	for _, versions := range m.TaskDb {
+		t := versions[len(versions)-1]
+		if t.State.Terminal() && !t.FinishTime.IsZero() && t.FinishTime.Before(cutoff) {
+			expired = append(expired, t.ID)
+			if len(expired) == purgeBatch {
+				break
+			}
+		}
+	}
+
+	for _, id := range expired {
+		m.forgetTask(id)
+	}
+	return len(expired)
+}
+
+// PurgeHistoryLoop runs PurgeHistory on its own schedule, separate from
+// task processing.
+func (m *Manager) PurgeHistoryLoop(interval time.Duration) {
+	for {
+		if n := m.PurgeHistory(); n > 0 {
+			log.Printf("Purged %d expired tasks from history\n", n)
+		}
+		<-m.clock().After(interval)
+	}
+}
+
+func (m *Manager) forgetTask(id uuid.UUID) {
+	if t := m.getTask(id); t != nil {
+		m.unindexName(id, t.Name)
+	}
+	delete(m.TaskDb, id.String())
+	delete(m.EventDb, id.String())
+	delete(m.backoffs, id)
+	delete(m.registryAuth, id)
+	delete(m.sampledAt, id)
+	delete(m.breaches, id)
+
+	worker, ok := m.TaskWorkerMap[id]
+	if !ok {
+		return
+	}
+	delete(m.TaskWorkerMap, id)
+	ids := m.WorkerTaskMap[worker]
+	for i, tid := range ids {
+		if tid == id {
+			m.WorkerTaskMap[worker] = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// NodePrune is one worker's result of an image prune.
+type NodePrune struct {
+	Node string
+	task.ImagePruneResult
+	Error string `json:",omitempty"`
+}
+
+type PruneReport struct {
+	Nodes          []NodePrune
+	SpaceReclaimed uint64
+}
+
+// PruneImages has every worker prune its images in parallel: dangling
+// images only, or with all every image no container or pending task on
+// that worker uses.
+func (m *Manager) PruneImages(all bool) PruneReport {
+	m.mu.Lock()
+	var nodes []*node.Node
+	for _, n := range m.WorkerNodes {
+		if n.Api != "" && !n.Unreachable {
+			nodes = append(nodes, n)
+		}
+	}
+	m.mu.Unlock()
+
+	results := make([]NodePrune, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *node.Node) {
+			defer wg.Done()
+			results[i].Node = n.Name
+			path := fmt.Sprintf("/images/prune?all=%t", all)
+			err := callWorker(n, http.MethodPost, path, "", nil, &results[i].ImagePruneResult)
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	report := PruneReport{Nodes: results}
+	for _, r := range results {
+		report.SpaceReclaimed += r.SpaceReclaimed
+	}
+	return report
+}
@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"slices"
+	"time"
+
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the latency
+// histograms' buckets.
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Histogram counts observations by bucket. Counts[i] is the number no
+// longer than Buckets[i] seconds and longer than the bucket before; the
+// last count is everything longer than the last bucket.
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	// Sum is the total of the observations in seconds.
+	Sum float64
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Buckets = latencyBuckets
+		h.Counts = make([]uint64, len(latencyBuckets)+1)
+	}
+	s := d.Seconds()
+	i, _ := slices.BinarySearch(h.Buckets, s)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += s
+}
+
+// LatencyStats has a histogram for each phase of task.Timings, across
+// every task the manager has seen.
+type LatencyStats struct {
+	Pull           Histogram
+	Create         Histogram
+	Start          Histogram
+	StartToRunning Histogram
+	Stop           Histogram
+}
+
+// Latency returns the phase latency histograms.
+func (m *Manager) Latency() LatencyStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l := m.latency
+	for _, h := range []*Histogram{&l.Pull, &l.Create, &l.Start, &l.StartToRunning, &l.Stop} {
+		h.Counts = slices.Clone(h.Counts)
+	}
+	return l
+}
+
+// syncTimings copies the timings workers recorded to the manager's
+// tasks, adding phases not seen before to the histograms. The lock must
+// be held.
+func (m *Manager) syncTimings(n *node.Node, tasks []task.Task) {
+	for _, wt := range tasks {
+		t := m.getTask(wt.ID)
+		if t == nil || m.TaskWorkerMap[wt.ID] != n.Name || t.Timings == wt.Timings {
+			continue
+		}
+		m.observeTimings(t.Timings, wt.Timings)
+		t.Timings = wt.Timings
+	}
+}
+
+func (m *Manager) observeTimings(old, cur task.Timings) {
+	// A new start time means a new container, whose phases are all new.
+	started := cur.Started != old.Started || old == task.Timings{}
+	if started {
+		for _, p := range []struct {
+			h *Histogram
+			d time.Duration
+		}{
+			{&m.latency.Pull, cur.Pull},
+			{&m.latency.Create, cur.Create},
+			{&m.latency.Start, cur.Start},
+		} {
+			if p.d > 0 {
+				p.h.observe(p.d)
+			}
+		}
+	}
+	if cur.StartToRunning > 0 && (started || old.StartToRunning == 0) {
+		m.latency.StartToRunning.observe(cur.StartToRunning)
+	}
+	if cur.Stop > 0 && old.Stop == 0 {
+		m.latency.Stop.observe(cur.Stop)
+	}
+}
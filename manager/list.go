@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TaskQuery filters and pages a task listing. A zero Limit returns every
+// matching task after Cursor.
+type TaskQuery struct {
+	App    string
+	State  *task.State
+	Cursor string
+	Limit  int
+	// ScheduledBefore, when set, limits the listing to Pending tasks
+	// with a StartAt before it.
+	ScheduledBefore *time.Time
+}
+
+// ListTasks returns the tasks matching q ordered by creation time then
+// ID, the cursor for the next page or "" on the last one, and how many
+// tasks match in total. New tasks sort after existing ones, so tasks
+// submitted during a cursor walk are never skipped or repeated.
+func (m *Manager) ListTasks(q TaskQuery) ([]task.Task, string, int, error) {
+	var after *task.Task
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		after = &c
+	}
+
+	m.mu.Lock()
+	var tasks []task.Task
+	for _, versions := range m.TaskDb {
+		t := versions[len(versions)-1]
+		if q.App != "" && t.Labels["app"] != q.App {
+			continue
+		}
+		if q.State != nil && t.State != *q.State {
+			continue
+		}
+		if q.ScheduledBefore != nil &&
+			(t.State != task.Pending || t.StartAt.IsZero() || !t.StartAt.Before(*q.ScheduledBefore)) {
+			continue
+		}
+		tasks = append(tasks, *t)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return taskBefore(tasks[i], tasks[j])
+	})
+	total := len(tasks)
+
+	if after != nil {
+		i := sort.Search(len(tasks), func(i int) bool {
+			return taskBefore(*after, tasks[i])
+		})
+		tasks = tasks[i:]
+	}
+
+	next := ""
+	if q.Limit > 0 && len(tasks) > q.Limit {
+		tasks = tasks[:q.Limit]
+		next = encodeCursor(tasks[len(tasks)-1])
+	}
+	return tasks, next, total, nil
+}
+
+// Summarize converts tasks to summaries, filling in the node each one is
+// placed on.
+func (m *Manager) Summarize(tasks []task.Task) []task.TaskSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summaries := make([]task.TaskSummary, 0, len(tasks))
+	for _, t := range tasks {
+		summaries = append(summaries, task.TaskSummary{
+			ID:        t.ID,
+			Name:      t.Name,
+			State:     t.State,
+			Image:     t.Image,
+			Node:      m.TaskWorkerMap[t.ID],
+			StartTime: t.StartTime,
+		})
+	}
+	return summaries
+}
+
+func taskBefore(a, b task.Task) bool {
+	if !a.CreateTime.Equal(b.CreateTime) {
+		return a.CreateTime.Before(b.CreateTime)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+// A cursor is the creation time and ID of the last task on a page.
+func encodeCursor(t task.Task) string {
+	s := fmt.Sprintf("%d:%s", t.CreateTime.UnixNano(), t.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeCursor(cursor string) (task.Task, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	nanos, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return task.Task{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return task.Task{ID: u, CreateTime: time.Unix(0, n).UTC()}, nil
+}
@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+func newListManager() *Manager {
+	return &Manager{
+		TaskDb:        make(map[string][]*task.Task),
+		EventDb:       make(map[string][]*task.TaskEvent),
+		WorkerTaskMap: make(map[string][]uuid.UUID),
+		TaskWorkerMap: make(map[uuid.UUID]string),
+	}
+}
+
+func submit(t *testing.T, m *Manager, name string, created time.Time) uuid.UUID {
+	tk := task.Task{ID: uuid.New(), Name: name, CreateTime: created}
+	if err := m.AddTask(task.TaskEvent{ID: uuid.New(), Task: tk}); err != nil {
+		t.Errorf("AddTask(%s): %v", name, err)
+	}
+	return tk.ID
+}
+
+// TestListTasksConcurrentSubmissions walks the listing a page at a time
+// while other tasks are submitted, some claiming to have been created
+// long ago. Every task that existed before the walk must be listed once,
+// and no task may be listed twice.
+func TestListTasksConcurrentSubmissions(t *testing.T) {
+	m := newListManager()
+	existing := make(map[uuid.UUID]bool)
+	for i := 0; i < 200; i++ {
+		existing[submit(t, m, fmt.Sprintf("existing-%d", i), time.Time{})] = true
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		backdated := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 200; i++ {
+			submit(t, m, fmt.Sprintf("new-%d", i), backdated)
+		}
+	}()
+
+	seen := make(map[uuid.UUID]bool)
+	cursor := ""
+	for {
+		page, next, _, err := m.ListTasks(TaskQuery{Cursor: cursor, Limit: 7})
+		if err != nil {
+			t.Fatalf("ListTasks: %v", err)
+		}
+		for _, tk := range page {
+			if seen[tk.ID] {
+				t.Errorf("task %s listed twice", tk.Name)
+			}
+			seen[tk.ID] = true
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	wg.Wait()
+
+	for id := range existing {
+		if !seen[id] {
+			t.Errorf("task %v was skipped", id)
+		}
+	}
+}
+
+func TestListTasksIgnoresClientCreateTime(t *testing.T) {
+	m := newListManager()
+	first := submit(t, m, "first", time.Time{})
+	second := submit(t, m, "second", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	page, next, total, err := m.ListTasks(TaskQuery{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if total != 2 || len(page) != 1 || page[0].ID != first {
+		t.Fatalf("first page = %v (total %d), want just the first task", page, total)
+	}
+	page, _, _, err = m.ListTasks(TaskQuery{Cursor: next, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != second {
+		t.Fatalf("second page = %v, want the back-dated task", page)
+	}
+}
+
+// BenchmarkListSerialization compares encoding a listing of 5000 tasks as
+// full tasks and as the summaries GET /tasks returns by default.
+func BenchmarkListSerialization(b *testing.B) {
+	m := newListManager()
+	for i := 0; i < 5000; i++ {
+		tk := &task.Task{
+			ID:            uuid.New(),
+			Name:          fmt.Sprintf("web-%d", i),
+			State:         task.Running,
+			Image:         "registry.example.com/web:1.4.2",
+			PortBindings:  map[string]string{"8080/tcp": "8080"},
+			RestartPolicy: "always",
+			Labels:        map[string]string{"app": "web", "team": "payments"},
+			Memory:        256 << 20,
+			CPU:           0.5,
+		}
+		m.TaskDb[tk.ID.String()] = []*task.Task{tk}
+	}
+	tasks, _, _, err := m.ListTasks(TaskQuery{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bc := range []struct {
+		name string
+		list func() any
+	}{
+		{"full", func() any { return tasks }},
+		{"summary", func() any { return m.Summarize(tasks) }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				out, err := json.Marshal(bc.list())
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(out)
+			}
+			b.ReportMetric(float64(size), "bytes")
+		})
+	}
+}
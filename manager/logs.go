@@ -0,0 +1,238 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const (
+	// maxLogTail caps the lines GET /tasks/{id}?logTail= includes, and
+	// maxLogTailBytes their size.
+	maxLogTail      = 500
+	maxLogTailBytes = 1 << 20
+	logTailTimeout  = 5 * time.Second
+	// logPlacementInterval is how often a followed log stream checks
+	// whether its task has moved to another node.
+	logPlacementInterval = 2 * time.Second
+)
+
+// logClient has no timeout since followed logs stream for as long as the
+// task runs; requests are bounded by the client's context instead.
+var logClient = &http.Client{}
+
+// hostingNode returns the node a task is placed on.
+func (m *Manager) hostingNode(id uuid.UUID) (node.Node, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.getTask(id)
+	if t == nil {
+		return node.Node{}, fmt.Errorf("%w: %v", ErrTaskNotFound, id)
+	}
+	n := m.getNode(m.TaskWorkerMap[id])
+	if n == nil || n.Api == "" {
+		return node.Node{}, fmt.Errorf("%w: task %v is %v and not placed on a worker", ErrTaskNotRunning, id, t.State)
+	}
+	return *n, nil
+}
+
+// openLogs starts a log request to the worker hosting a task.
+func openLogs(ctx context.Context, n node.Node, id uuid.UUID, query url.Values, correlationID string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/tasks/%v/logs?%s", n.Api, id, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	if correlationID != "" {
+		req.Header.Set(RequestIDHeader, correlationID)
+	}
+
+	resp, err := logClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error connecting to worker %s: %v", ErrWorkerUnavailable, n.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		e := ErrResponse{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return nil, &WorkerError{Node: n.Name, StatusCode: resp.StatusCode, Code: e.Error.Code, Message: e.Error.Message}
+	}
+	return resp.Body, nil
+}
+
+// watchPlacement calls moved once task id is placed on a node other than
+// on, which is how a followed stream from a worker that died without
+// closing it finds out. It returns when ctx ends.
+func (m *Manager) watchPlacement(ctx context.Context, id uuid.UUID, on string, moved context.CancelFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.clock().After(logPlacementInterval):
+		}
+		if n, err := m.hostingNode(id); err == nil && n.Name != on {
+			moved()
+			return
+		}
+	}
+}
+
+// GetTaskLogsHandler streams a task's output from whichever worker runs
+// it. It accepts ?follow=true, ?tail=N and an RFC 3339 ?since=. When a
+// followed task is rescheduled to another node, even one whose old
+// worker is no longer answering, the stream carries on with the new
+// container's output after a marker line.
+func (a *Api) GetTaskLogsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	q := url.Values{}
+	follow := false
+	if v := r.URL.Query().Get("follow"); v != "" {
+		follow, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid follow parameter %q", v))
+			return
+		}
+		q.Set("follow", strconv.FormatBool(follow))
+	}
+	if v := r.URL.Query().Get("tail"); v != "" {
+		if tail, err := strconv.Atoi(v); err != nil || tail < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid tail parameter %q", v))
+			return
+		}
+		q.Set("tail", v)
+	}
+	if v := r.URL.Query().Get("since"); v != "" {
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid since parameter %q", v))
+			return
+		}
+		q.Set("since", v)
+	}
+
+	correlationID := r.Header.Get(RequestIDHeader)
+	n, err := a.Manager.hostingNode(id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	body, err := openLogs(ctx, n, id, q, correlationID)
+	if err != nil {
+		cancel()
+		writeAPIError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	out := &flushWriter{w: w, rc: http.NewResponseController(w)}
+	for {
+		if follow {
+			go a.Manager.watchPlacement(ctx, id, n.Name, cancel)
+		}
+		_, err = io.Copy(out, body)
+		body.Close()
+		cancel()
+		if r.Context().Err() != nil || !follow {
+			return
+		}
+
+		// The stream ended: either the task finished, or it was placed
+		// somewhere else and its old node may have gone away.
+		next, nerr := a.Manager.hostingNode(id)
+		if nerr != nil || next.Name == n.Name {
+			if err != nil {
+				fmt.Fprintf(out, "--- lost connection to worker %s: %v ---\n", n.Name, err)
+			}
+			return
+		}
+		log.Printf("[%s] Task %v moved from %s to %s while following its logs\n", correlationID, id, n.Name, next.Name)
+		fmt.Fprintf(out, "--- task moved from node %s to %s ---\n", n.Name, next.Name)
+
+		// The new container's output starts fresh, so the original tail
+		// and since no longer apply.
+		n = next
+		ctx, cancel = context.WithCancel(r.Context())
+		body, err = openLogs(ctx, n, id, url.Values{"follow": {"true"}}, correlationID)
+		if err != nil {
+			cancel()
+			fmt.Fprintf(out, "--- %v ---\n", err)
+			return
+		}
+	}
+}
+
+// TaskWithLogs is a task with the end of its output, as
+// GET /tasks/{id}?logTail=N returns it.
+type TaskWithLogs struct {
+	task.Task
+	LogTail []string
+	// LogNote says why LogTail is empty when the output can't be read.
+	LogNote string `json:",omitempty"`
+}
+
+// logTail fetches the last lines of a task's output, stdout and stderr
+// interleaved. Logs are a convenience on top of the task, so when they
+// can't be read the tail is empty and the note says why.
+func (m *Manager) logTail(ctx context.Context, id uuid.UUID, lines int, correlationID string) ([]string, string) {
+	n, err := m.hostingNode(id)
+	if err != nil {
+		return []string{}, "task has not been placed on a worker"
+	}
+	ctx, cancel := context.WithTimeout(ctx, logTailTimeout)
+	defer cancel()
+
+	body, err := openLogs(ctx, n, id, url.Values{"tail": {strconv.Itoa(lines)}}, correlationID)
+	var we *WorkerError
+	switch {
+	case errors.As(err, &we) && we.StatusCode == http.StatusConflict:
+		return []string{}, "task's container has been removed"
+	case errors.As(err, &we) && we.StatusCode == http.StatusNotFound:
+		return []string{}, fmt.Sprintf("worker %s no longer has the task", n.Name)
+	case err != nil:
+		return []string{}, err.Error()
+	}
+	defer body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(body, maxLogTailBytes))
+	if err != nil {
+		return []string{}, fmt.Sprintf("error reading logs from worker %s: %v", n.Name, err)
+	}
+	out := strings.TrimSuffix(string(b), "\n")
+	if out == "" {
+		return []string{}, ""
+	}
+	return strings.Split(out, "\n"), ""
+}
+
+// flushWriter flushes every write so followed logs reach the client as
+// they're produced.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		f.rc.Flush()
+	}
+	return n, err
+}
@@ -1,24 +1,831 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/golang-collections/collections/queue"
 	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/clock"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/scheduler"
 	"github.com/sajalkmr/ordo/task"
 )
 
+var (
+	ErrNoCandidateNodes = errors.New("no candidate nodes")
+	ErrSingletonRunning = errors.New("singleton task already has an active instance")
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrInvalidTask      = errors.New("invalid task")
+	ErrNameConflict     = errors.New("task name already in use")
+	ErrNoCapacity       = errors.New("node does not have enough capacity")
+	ErrNodeNotFound     = errors.New("node not found")
+	ErrTaskNotRunning   = errors.New("task is not running")
+	ErrTaskNotPending   = errors.New("task is not pending")
+)
+
 type Manager struct {
-	Pending       queue.Queue
+	Pending       FairQueue
 	TaskDb        map[string][]*task.Task
 	EventDb       map[string][]*task.TaskEvent
 	Workers       []string
+	WorkerNodes   []*node.Node
 	WorkerTaskMap map[string][]uuid.UUID
 	TaskWorkerMap map[uuid.UUID]string
+	Scheduler     scheduler.Scheduler
+	Events        EventBus
+
+	mu       sync.Mutex
+	backoffs map[uuid.UUID]*backoff
+	paused   bool
+
+	// reconcileMu serializes reconciliation passes so a manual trigger
+	// can't overlap the loop and reschedule the same task twice.
+	reconcileMu sync.Mutex
+
+	// ReconcileInterval is how often ReconcileLoop runs. Zero uses
+	// defaultReconcileInterval.
+	ReconcileInterval time.Duration
+	// RescheduleConcurrency bounds how many tasks from unreachable
+	// workers are started on new workers at once. Zero uses
+	// defaultRescheduleConcurrency.
+	RescheduleConcurrency int
+	// PollConcurrency bounds how many workers Reconcile polls at once,
+	// and PollTimeout how long it waits for each. Zero uses
+	// defaultPollConcurrency and defaultPollTimeout.
+	PollConcurrency int
+	PollTimeout     time.Duration
+
+	// NameTemplate names submitted tasks that have neither a name nor
+	// their own template.
+	NameTemplate string
+
+	// HistoryTTL is how long terminal tasks stay queryable before they
+	// are purged. Zero keeps them forever.
+	HistoryTTL time.Duration
+
+	// ShutdownTimeout is how long Shutdown waits for workers to confirm
+	// tasks stopped. Zero uses defaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+	shutdown        *shutdownState
+	exit            chan struct{}
+
+	// Clock drives backoff, retention and timeouts. Nil uses the wall
+	// clock.
+	Clock clock.Clock
+
+	// DefaultRestartPolicy is given to submitted tasks without a restart
+	// policy of their own.
+	DefaultRestartPolicy string
+
+	// UsageBasedScheduling places recurring tasks by the memory earlier
+	// tasks with the same name actually used, as sampled by
+	// CollectUsage, rather than by what they declare. UsageWindow is how
+	// many samples are kept per name; zero uses defaultUsageWindow.
+	// sampledAt is when each task's latest collected sample was taken.
+	UsageBasedScheduling bool
+	UsageWindow          int
+	usage                map[string][]UsageSample
+	sampledAt            map[uuid.UUID]time.Time
+
+	// AlertWindow is how many consecutive usage samples must exceed a
+	// task's alert threshold before an alert is published. Zero uses
+	// defaultAlertWindow.
+	AlertWindow int
+	breaches    map[uuid.UUID]map[string]int
+
+	// Credentials supplies registry credentials to tasks that don't
+	// have their own, by label selector. registryAuth holds the ones
+	// tasks were submitted with; see keepRegistryAuth.
+	Credentials  CredentialStore
+	registryAuth map[uuid.UUID]task.RegistryAuth
+
+	// CatchUpMissedRuns makes a recurring task that missed scheduled
+	// runs, for example while the manager was down, run once as soon as
+	// possible. Otherwise missed runs are skipped. CronHistory is how
+	// many runs are kept per recurring task; zero uses
+	// defaultCronHistory.
+	CatchUpMissedRuns bool
+	CronHistory       int
+	cronJobs          map[uuid.UUID]*CronJob
+
+	// WebhookQueueSize bounds how many events wait for delivery to each
+	// webhook, and WebhookAttempts how many times a delivery is tried.
+	// Zero uses defaultWebhookQueue and defaultWebhookAttempts.
+	WebhookQueueSize int
+	WebhookAttempts  int
+	webhooks         webhooks
+
+	// MaxConcurrentPulls caps how many image pulls run at once across
+	// the cluster. Zero doesn't limit them. Tokens a worker doesn't give
+	// back expire after PullTokenTTL; zero uses defaultPullTokenTTL.
+	MaxConcurrentPulls int
+	PullTokenTTL       time.Duration
+	pulls              pullTokens
+
+	// quotas cap what groups of tasks may use; see SetQuotas.
+	quotas []quota
+	// latency is how long task phases took, fed by Reconcile.
+	latency LatencyStats
+
+	// MaxTaskAge is how long a task labelled RecycleLabel=true may run
+	// before RecycleTasks stops it, and RecycleReschedule whether a
+	// fresh copy, started and ready first, replaces it. Replicas are
+	// recycled at most once
+	// per RecycleStagger; zero uses defaultRecycleStagger. Zero
+	// MaxTaskAge disables recycling.
+	MaxTaskAge        time.Duration
+	RecycleReschedule bool
+	RecycleStagger    time.Duration
+	recycled          map[string]time.Time
+	recycling         map[string]bool
+
+	// EvictOnOvercommit stops and reschedules tasks on a node whose
+	// capacity shrinks below its allocations, until they fit again.
+	EvictOnOvercommit bool
+
+	// MaxSubmitWait caps how long POST /tasks?wait= holds a request open
+	// for the task to be placed. Zero uses defaultMaxSubmitWait.
+	MaxSubmitWait time.Duration
+	room          chan struct{}
+	// sendQueued is set while a scheduling pass requested by waiters is
+	// yet to start, so they share it.
+	sendQueued bool
+
+	// lastCreate is the CreateTime given to the newest task.
+	lastCreate time.Time
+	// names indexes the tasks in TaskDb by name, so name checks don't
+	// scan every task.
+	names map[string]map[uuid.UUID]bool
+}
+
+// AddTask stores a task and queues it for scheduling. The singleton check
+// and the insert happen under the same lock so concurrent submissions of
+// the same singleton can't both be accepted.
+func (m *Manager) AddTask(te task.TaskEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := te.Task
+	auth := t.RegistryAuth
+	t.RegistryAuth, te.Task.RegistryAuth = nil, nil
+	t.State, te.Task.State = task.Pending, task.Pending
+	t.CreateTime = m.createTime()
+	te.Task.CreateTime = t.CreateTime
+	if t.Name == "" {
+		tmpl := t.NameTemplate
+		if tmpl == "" {
+			tmpl = m.NameTemplate
+		}
+		if tmpl != "" {
+			name, index, err := m.generateName(t, tmpl)
+			if err != nil {
+				return err
+			}
+			t.Name, t.ReplicaIndex = name, index
+			te.Task.Name, te.Task.ReplicaIndex = name, index
+		}
+	}
+	for _, tol := range t.Tolerations {
+		if err := tol.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+		}
+	}
+	if err := task.ValidateAlertThreshold(t.CpuAlertThreshold); err != nil {
+		return fmt.Errorf("%w: cpu %v", ErrInvalidTask, err)
+	}
+	if err := task.ValidateAlertThreshold(t.MemoryAlertThreshold); err != nil {
+		return fmt.Errorf("%w: memory %v", ErrInvalidTask, err)
+	}
+	if t.RestartPolicy == "" {
+		t.RestartPolicy = m.DefaultRestartPolicy
+		te.Task.RestartPolicy = t.RestartPolicy
+	}
+	if err := task.ValidateRestartPolicy(t.RestartPolicy); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	if err := validateSoftDeps(t); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	if t.Hostname != "" {
+		if err := task.ValidateHostname(t.Hostname); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+		}
+	}
+	if t.Domainname != "" {
+		if err := task.ValidateHostname(t.Domainname); err != nil {
+			return fmt.Errorf("%w: domainname: %v", ErrInvalidTask, err)
+		}
+	}
+	if t.TopologySpread != nil {
+		if err := t.TopologySpread.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+		}
+		if t.Labels["app"] == "" {
+			return fmt.Errorf("%w: topology spread needs an app label to tell the replicas apart", ErrInvalidTask)
+		}
+	}
+	if t.NodeName != "" && m.getNode(t.NodeName) == nil {
+		return fmt.Errorf("%w: pinned node %s is unknown", ErrInvalidTask, t.NodeName)
+	}
+	if t.Schedule != "" {
+		if err := m.addCronJob(t); err != nil {
+			return err
+		}
+		m.keepRegistryAuth(t.ID, auth)
+		return nil
+	}
+	if t.Singleton {
+		if other := m.activeSingleton(t); other != nil {
+			return fmt.Errorf("%w: task %s (%v) is %v", ErrSingletonRunning, other.Name, other.ID, other.State)
+		}
+	}
+	if err := m.checkQuotas(t); err != nil {
+		return err
+	}
+
+	m.keepRegistryAuth(t.ID, auth)
+	m.TaskDb[t.ID.String()] = append(m.TaskDb[t.ID.String()], &t)
+	m.indexName(t.ID, t.Name)
+	m.Pending.Enqueue(te)
+	return nil
+}
+
+// AddNode registers a worker node after checking its taints.
+func (m *Manager) AddNode(n *node.Node) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.WorkerNodes = append(m.WorkerNodes, n)
+	m.Workers = append(m.Workers, n.Name)
+	m.wake()
+	return nil
+}
+
+// NodeDraining handles a worker's notice that it is shutting down. The
+// node stops receiving tasks and the listed tasks are queued to be placed
+// elsewhere without waiting for the worker to finish stopping them.
+func (m *Manager) NodeDraining(name string, ids []uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.getNode(name)
+	if n == nil {
+		return ErrNodeNotFound
+	}
+	n.Draining = true
+
+	for _, id := range ids {
+		t := m.getTask(id)
+		if t == nil || t.State.Terminal() || m.TaskWorkerMap[id] != name {
+			continue
+		}
+		m.reschedule(n, t, fmt.Sprintf("rescheduling: worker %s is shutting down", name))
+	}
+	return nil
+}
+
+// reschedule takes a task off its node and queues it to be placed again.
+func (m *Manager) reschedule(n *node.Node, t *task.Task, reason string) {
+	t.RecordRestart(task.RestartEvent{
+		Time:        m.clock().Now().UTC(),
+		Reason:      reason,
+		ExitCode:    t.ExitCode,
+		ContainerID: t.ContainerID,
+	})
+	m.unassign(n, t, reason)
+	m.requeue(t)
+	log.Printf("[%s] Task %v queued for rescheduling: %s\n", t.CorrelationID, t.ID, reason)
+}
+
+// unassign takes a task off its node and returns it to Pending.
+func (m *Manager) unassign(n *node.Node, t *task.Task, reason string) {
+	release(n, t)
+	delete(m.TaskWorkerMap, t.ID)
+	m.WorkerTaskMap[n.Name] = slices.DeleteFunc(m.WorkerTaskMap[n.Name], func(other uuid.UUID) bool {
+		return other == t.ID
+	})
+
+	t.State = task.Pending
+	t.ContainerID = ""
+	t.StatusReason = reason
+	m.addEvent(t)
+}
+
+// RequeueTask puts a Pending or Scheduled task back at the front of the
+// scheduling queue with its backoff cleared, so the next scheduling pass
+// tries it first. A Scheduled task is stopped on its node and taken off
+// it beforehand, so it can't end up running in two places.
+func (m *Manager) RequeueTask(id uuid.UUID) (task.Task, error) {
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil {
+		m.mu.Unlock()
+		return task.Task{}, ErrTaskNotFound
+	}
+	if t.State != task.Pending && t.State != task.Scheduled {
+		m.mu.Unlock()
+		return task.Task{}, fmt.Errorf("%w: task %v is %v", ErrTaskNotPending, id, t.State)
+	}
+	if n := m.getNode(m.TaskWorkerMap[id]); n != nil && n.Api != "" {
+		correlationID := t.CorrelationID
+		m.mu.Unlock()
+		err := callWorker(n, http.MethodDelete, fmt.Sprintf("/tasks/%v?force=true", id), correlationID, nil, nil)
+		var we *WorkerError
+		if err != nil && !(errors.As(err, &we) && we.StatusCode == http.StatusNotFound) {
+			return task.Task{}, err
+		}
+		m.mu.Lock()
+		t = m.getTask(id)
+		if t == nil {
+			m.mu.Unlock()
+			return task.Task{}, ErrTaskNotFound
+		}
+		if t.State != task.Pending && t.State != task.Scheduled {
+			m.mu.Unlock()
+			return task.Task{}, fmt.Errorf("%w: task %v is %v", ErrTaskNotPending, id, t.State)
+		}
+	}
+	defer m.mu.Unlock()
+
+	delete(m.backoffs, id)
+	if n := m.getNode(m.TaskWorkerMap[id]); n != nil {
+		m.unassign(n, t, "requeued")
+	} else {
+		t.StatusReason = ""
+	}
+	m.Pending.Remove(id)
+	m.Pending.PushFront(task.TaskEvent{
+		ID:            uuid.New(),
+		State:         task.Pending,
+		Timestamp:     m.clock().Now(),
+		Task:          *t,
+		CorrelationID: t.CorrelationID,
+	})
+	log.Printf("[%s] Task %v requeued at the front of the queue\n", t.CorrelationID, id)
+	return *t, nil
+}
+
+func (m *Manager) requeue(t *task.Task) {
+	m.Pending.Enqueue(task.TaskEvent{
+		ID:            uuid.New(),
+		State:         task.Pending,
+		Timestamp:     m.clock().Now(),
+		Task:          *t,
+		CorrelationID: t.CorrelationID,
+	})
+}
+
+// UpdateNodeStats feeds a node's reported memory usage into its pressure
+// tracking so the scheduler can avoid nodes whose accounting lags reality.
+func (m *Manager) UpdateNodeStats(name string, memUsedPercent float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.getNode(name)
+	if n == nil {
+		return
+	}
+
+	wasUnderPressure := n.UnderPressure
+	n.UpdatePressure(memUsedPercent, m.clock().Now())
+	if wasUnderPressure != n.UnderPressure {
+		log.Printf("Node %s memory pressure changed: under pressure=%v (%.1f%% used)\n",
+			n.Name, n.UnderPressure, memUsedPercent)
+	}
+	if wasUnderPressure && !n.UnderPressure {
+		m.wake()
+	}
+}
+
+func (m *Manager) ReleaseTask(id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.getTask(id)
+	if t == nil {
+		return
+	}
+	n := m.getNode(m.TaskWorkerMap[id])
+	if n == nil {
+		return
+	}
+	release(n, t)
+	delete(m.TaskWorkerMap, id)
+	m.WorkerTaskMap[n.Name] = slices.DeleteFunc(m.WorkerTaskMap[n.Name], func(other uuid.UUID) bool {
+		return other == id
+	})
+	m.wake()
+}
+
+// StopTask stops a task wherever it is. Tasks that haven't been placed
+// yet are simply marked Completed.
+func (m *Manager) StopTask(id uuid.UUID) (task.Task, error) {
+	return m.stopTask(id, false)
+}
+
+// ForceStopTask stops the task without waiting out its DeregisterDelay.
+func (m *Manager) ForceStopTask(id uuid.UUID) (task.Task, error) {
+	return m.stopTask(id, true)
+}
+
+// stopTask stops the task on its worker, if it has one, and marks it
+// Completed. The lock is released during the worker call. A worker that
+// doesn't know the task, because it was never sent there or has already
+// dropped it, counts as having stopped it.
+func (m *Manager) stopTask(id uuid.UUID, force bool) (task.Task, error) {
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil {
+		m.mu.Unlock()
+		return task.Task{}, ErrTaskNotFound
+	}
+	if t.State.Terminal() {
+		m.mu.Unlock()
+		return *t, nil
+	}
+	if n := m.getNode(m.TaskWorkerMap[id]); n != nil && n.Api != "" {
+		correlationID := t.CorrelationID
+		m.mu.Unlock()
+		path := fmt.Sprintf("/tasks/%v", id)
+		if force {
+			path += "?force=true"
+		}
+		err := callWorker(n, http.MethodDelete, path, correlationID, nil, nil)
+		var we *WorkerError
+		if err != nil && !(errors.As(err, &we) && we.StatusCode == http.StatusNotFound) {
+			return task.Task{}, err
+		}
+		m.mu.Lock()
+		t = m.getTask(id)
+		if t == nil {
+			m.mu.Unlock()
+			return task.Task{}, ErrTaskNotFound
+		}
+		if t.State.Terminal() {
+			m.mu.Unlock()
+			return *t, nil
+		}
+	}
+	defer m.mu.Unlock()
+
+	if n := m.getNode(m.TaskWorkerMap[id]); n != nil {
+		release(n, t)
+		delete(m.TaskWorkerMap, id)
+		m.WorkerTaskMap[n.Name] = slices.DeleteFunc(m.WorkerTaskMap[n.Name], func(other uuid.UUID) bool {
+			return other == id
+		})
+		m.wake()
+	}
+
+	delete(m.backoffs, id)
+	m.Pending.Remove(id)
+	t.State = task.Completed
+	t.FinishTime = m.clock().Now().UTC()
+	m.addEvent(t)
+	return *t, nil
+}
+
+// RestartTask has the hosting worker replace the task's container with a
+// fresh one, switching to req.Image if it isn't empty. The task keeps its
+// ID, labels and placement. The worker pulls the image before stopping the
+// old container, so a failed pull leaves the task running as it was. The
+// lock is released during the worker call since the pull may take a while.
+func (m *Manager) RestartTask(id uuid.UUID, req RestartRequest) (task.Task, error) {
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil {
+		m.mu.Unlock()
+		return task.Task{}, ErrTaskNotFound
+	}
+	n := m.getNode(m.TaskWorkerMap[id])
+	if t.State.Terminal() || n == nil {
+		m.mu.Unlock()
+		return task.Task{}, fmt.Errorf("%w: task %v is %v and not placed on a worker", ErrTaskNotRunning, id, t.State)
+	}
+	correlationID := t.CorrelationID
+	m.mu.Unlock()
+
+	var restarted task.Task
+	err := callWorker(n, http.MethodPost, fmt.Sprintf("/tasks/%v/restart", id), correlationID, req, &restarted)
+	if err != nil {
+		return task.Task{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t = m.getTask(id)
+	if t == nil {
+		return task.Task{}, ErrTaskNotFound
+	}
+	t.ContainerID = restarted.ContainerID
+	t.Image = restarted.Image
+	t.StartTime = restarted.StartTime
+	t.RestartCount = restarted.RestartCount
+	t.State = restarted.State
+	t.StatusReason = restarted.StatusReason
+	m.addEvent(t)
+	return *t, nil
+}
+
+// TaskDiff asks the hosting worker which paths the task's container
+// changed relative to its image.
+func (m *Manager) TaskDiff(id uuid.UUID) ([]task.ContainerChange, error) {
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil {
+		m.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	n := m.getNode(m.TaskWorkerMap[id])
+	if n == nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: task %v is %v and not placed on a worker", ErrTaskNotRunning, id, t.State)
+	}
+	correlationID := t.CorrelationID
+	m.mu.Unlock()
+
+	var changes []task.ContainerChange
+	err := callWorker(n, http.MethodGet, fmt.Sprintf("/tasks/%v/diff", id), correlationID, nil, &changes)
+	return changes, err
 }
 
-func (m *Manager) SelectWorker() {
-	fmt.Println("Select worker")
+func (m *Manager) GetTask(id uuid.UUID) (task.Task, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.getTask(id)
+	if t == nil {
+		return task.Task{}, false
+	}
+	return *t, true
+}
+
+func (m *Manager) GetTasks() []task.Task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks := make([]task.Task, 0, len(m.TaskDb))
+	for _, versions := range m.TaskDb {
+		tasks = append(tasks, *versions[len(versions)-1])
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return taskBefore(tasks[i], tasks[j])
+	})
+	return tasks
+}
+
+// GetTaskSummaries returns summaries of all tasks, or only those labeled
+// with the given app when app is non-empty, in the order ListTasks uses.
+func (m *Manager) GetTaskSummaries(app string) []task.TaskSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks := make([]*task.Task, 0, len(m.TaskDb))
+	for _, versions := range m.TaskDb {
+		t := versions[len(versions)-1]
+		if app != "" && t.Labels["app"] != app {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return taskBefore(*tasks[i], *tasks[j])
+	})
+
+	summaries := make([]task.TaskSummary, 0, len(tasks))
+	for _, t := range tasks {
+		summaries = append(summaries, task.TaskSummary{
+			ID:        t.ID,
+			Name:      t.Name,
+			State:     t.State,
+			Image:     t.Image,
+			Node:      m.TaskWorkerMap[t.ID],
+			StartTime: t.StartTime,
+		})
+	}
+	return summaries
+}
+
+// ZoneDistribution counts the active tasks of an app in each zone.
+func (m *Manager) ZoneDistribution(app string) map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zones := make(map[string]int)
+	for _, n := range m.WorkerNodes {
+		if count := n.Apps[app]; count > 0 {
+			zones[n.Zone] += count
+		}
+	}
+	return zones
+}
+
+// RenameTask changes the human-facing name of a task and its container.
+// The task ID is left untouched. The lock is released during the worker
+// call, so the name is checked again before it's recorded.
+func (m *Manager) RenameTask(id uuid.UUID, name string) (task.Task, error) {
+	if err := task.ValidateName(name); err != nil {
+		return task.Task{}, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil {
+		m.mu.Unlock()
+		return task.Task{}, ErrTaskNotFound
+	}
+	if t.Name == name {
+		m.mu.Unlock()
+		return *t, nil
+	}
+	if other := m.nameInUse(name, id); other != nil {
+		m.mu.Unlock()
+		return task.Task{}, fmt.Errorf("%w: %s is used by task %v", ErrNameConflict, name, other.ID)
+	}
+	n := m.getNode(m.TaskWorkerMap[id])
+	correlationID := t.CorrelationID
+	m.mu.Unlock()
+
+	if n != nil && n.Api != "" {
+		err := callWorker(n, http.MethodPatch, fmt.Sprintf("/tasks/%v", id), correlationID, TaskPatch{Name: name}, nil)
+		if err != nil {
+			return task.Task{}, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t = m.getTask(id)
+	if t == nil {
+		return task.Task{}, ErrTaskNotFound
+	}
+	if other := m.nameInUse(name, id); other != nil {
+		return task.Task{}, fmt.Errorf("%w: %s was taken by task %v during the rename", ErrNameConflict, name, other.ID)
+	}
+	m.unindexName(id, t.Name)
+	m.indexName(id, name)
+	t.Name = name
+	m.addEvent(t)
+	return *t, nil
+}
+
+// UpdateTaskResources changes a task's CPU and memory limits without
+// restarting it. Zero values keep the current limit. The hosting node's
+// accounting is adjusted, and increases it can't accommodate are rejected.
+// The new limits are recorded before the worker is asked to apply them,
+// with the lock released, and put back if it can't.
+func (m *Manager) UpdateTaskResources(id uuid.UUID, cpu float64, memory int64) (task.Task, error) {
+	if cpu < 0 || memory < 0 {
+		return task.Task{}, fmt.Errorf("%w: cpu and memory must not be negative", ErrInvalidTask)
+	}
+
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil {
+		m.mu.Unlock()
+		return task.Task{}, ErrTaskNotFound
+	}
+	if t.State.Terminal() {
+		m.mu.Unlock()
+		return task.Task{}, fmt.Errorf("%w: task %v is %v", ErrTaskNotRunning, id, t.State)
+	}
+	if cpu == 0 {
+		cpu = t.CPU
+	}
+	if memory == 0 {
+		memory = t.Memory
+	}
+	if t.MemoryReservation > memory {
+		m.mu.Unlock()
+		return task.Task{}, fmt.Errorf("%w: memory %d is below the task's reservation %d", ErrInvalidTask, memory, t.MemoryReservation)
+	}
+	resized := *t
+	resized.CPU, resized.Memory = cpu, memory
+	if err := m.checkQuotas(resized); err != nil {
+		m.mu.Unlock()
+		return task.Task{}, err
+	}
+
+	n := m.getNode(m.TaskWorkerMap[id])
+	oldCPU, oldMemory := t.CPU, t.Memory
+	delta := resized.ReservedMemory() - t.ReservedMemory()
+	apply := false
+	if n != nil {
+		if delta > int64(n.Memory-n.MemoryAllocated) {
+			m.mu.Unlock()
+			return task.Task{}, fmt.Errorf("%w: %s has %d bytes of memory free, %d more requested",
+				ErrNoCapacity, n.Name, n.Memory-n.MemoryAllocated, delta)
+		}
+		if n.Cores > 0 && cpu > float64(n.Cores) {
+			m.mu.Unlock()
+			return task.Task{}, fmt.Errorf("%w: %s has %d cores, %.2f requested", ErrNoCapacity, n.Name, n.Cores, cpu)
+		}
+		n.MemoryAllocated += int(delta)
+		apply = n.Api != "" && t.State == task.Running
+	}
+	t.CPU, t.Memory = cpu, memory
+	correlationID := t.CorrelationID
+	m.mu.Unlock()
+
+	var err error
+	if apply {
+		p := ResourcePatch{CPU: cpu, Memory: memory}
+		err = callWorker(n, http.MethodPatch, fmt.Sprintf("/tasks/%v/resources", id), correlationID, p, nil)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t = m.getTask(id)
+	if t == nil {
+		return task.Task{}, ErrTaskNotFound
+	}
+	if err != nil {
+		// Put the old limits back unless the task has since finished,
+		// in which case its node already released the new ones.
+		if !t.State.Terminal() && t.CPU == cpu && t.Memory == memory {
+			t.CPU, t.Memory = oldCPU, oldMemory
+			if n != nil && m.TaskWorkerMap[id] == n.Name {
+				n.MemoryAllocated -= int(delta)
+			}
+		}
+		return task.Task{}, err
+	}
+	if delta < 0 {
+		m.wake()
+	}
+	m.addEvent(t)
+	return *t, nil
+}
+
+func (m *Manager) SelectWorker(t task.Task) (*node.Node, error) {
+	n, _, err := m.selectWorker(t)
+	return n, err
+}
+
+// selectWorker is SelectWorker that also records the decision it made.
+func (m *Manager) selectWorker(t task.Task) (*node.Node, task.PlacementDecision, error) {
+	d := task.PlacementDecision{Time: m.clock().Now().UTC()}
+	if t.NodeName != "" {
+		d.Scheduler = "pinned"
+		n := m.getNode(t.NodeName)
+		if n == nil {
+			d.Reason = fmt.Sprintf("pinned node %s is unknown", t.NodeName)
+			return nil, d, fmt.Errorf("%w: pinned node %s", ErrNodeNotFound, t.NodeName)
+		}
+		d.Nodes = m.nodeDecisions(t, []*node.Node{n}, nil)
+		if reason := scheduler.Unfit(t, n); reason != "" {
+			d.Reason = fmt.Sprintf("pinned node %s can't take it", n.Name)
+			return nil, d, fmt.Errorf("%w: pinned node %s: %s", ErrNoCapacity, n.Name, reason)
+		}
+		d.Chosen, d.Reason = n.Name, "pinned to this node"
+		return n, d, nil
+	}
+
+	d.Scheduler = scheduler.NameOf(m.Scheduler)
+	candidates := m.Scheduler.SelectCandidateNodes(t, m.WorkerNodes)
+	if len(candidates) == 0 {
+		d.Nodes = m.nodeDecisions(t, m.WorkerNodes, nil)
+		d.Reason = "no node can take it"
+		if reason := scheduler.SpreadUnfit(t, m.WorkerNodes); reason != "" {
+			d.Reason = reason
+			return nil, d, fmt.Errorf("%w: %s", ErrNoCandidateNodes, reason)
+		}
+		return nil, d, ErrNoCandidateNodes
+	}
+	if c, ok := m.Scheduler.(*scheduler.ChainScheduler); ok {
+		d.Scheduler = c.Chosen()
+	}
+	scores := m.Scheduler.Score(t, candidates)
+	d.Nodes = m.nodeDecisions(t, m.WorkerNodes, scores)
+	n := m.Scheduler.Pick(scores, candidates)
+	if n == nil {
+		d.Reason = fmt.Sprintf("the %s scheduler picked none of %d candidates", d.Scheduler, len(candidates))
+		return nil, d, ErrNoCandidateNodes
+	}
+	d.Chosen, d.Reason = n.Name, decidingFactor(d.Scheduler, n, scores, len(candidates))
+	return n, d, nil
+}
+
+// scheduledBy names the scheduler behind the last SelectWorker for t.
+func (m *Manager) scheduledBy(t *task.Task) string {
+	if t.NodeName != "" {
+		return "pinned"
+	}
+	if c, ok := m.Scheduler.(*scheduler.ChainScheduler); ok {
+		return c.Chosen()
+	}
+	return scheduler.NameOf(m.Scheduler)
 }
 
 func (m *Manager) UpdateTasks() {
@@ -26,5 +833,243 @@ func (m *Manager) UpdateTasks() {
 }
 
 func (m *Manager) SendWork() {
-	fmt.Println("Send wrork to worker")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paused {
+		return
+	}
+
+	// Take the whole backlog in fair order first so tasks put back below
+	// wait for the next pass instead of being retried in this one.
+	var batch []task.TaskEvent
+	for te, ok := m.Pending.Dequeue(); ok; te, ok = m.Pending.Dequeue() {
+		batch = append(batch, te)
+	}
+
+	for _, te := range batch {
+		t := m.getTask(te.Task.ID)
+		if t == nil {
+			t = &te.Task
+		}
+		if t.State != task.Pending {
+			// Stopped or placed some other way since it was queued.
+			continue
+		}
+		if m.place(t) == nil {
+			m.Pending.Enqueue(te)
+		}
+	}
+}
+
+// place assigns the Pending task t to a node and marks it Scheduled,
+// returning the node. It returns nil, leaving t Pending, if t isn't due
+// yet or no node can take it. SendWork and placeNow both place through
+// it so every task is placed by the same rules. The lock must be held.
+func (m *Manager) place(t *task.Task) *node.Node {
+	if m.clock().Now().Before(t.StartAt) {
+		t.StatusReason = fmt.Sprintf("waiting to start at %s", t.StartAt.UTC().Format(time.RFC3339))
+		return nil
+	}
+	if waiting := m.waitingOnSoftDeps(t); len(waiting) > 0 {
+		t.StatusReason = fmt.Sprintf("waiting for soft dependencies %v", waiting)
+		return nil
+	}
+
+	b := m.backoffs[t.ID]
+	if b != nil && m.clock().Now().Before(b.next) {
+		return nil
+	}
+
+	if m.UsageBasedScheduling {
+		m.applyObservedUsage(t)
+	}
+	n, decision, err := m.selectWorker(*t)
+	t.Placement = &decision
+	if err != nil {
+		b = m.deferTask(t.ID)
+		t.State = task.Pending
+		t.StatusReason = fmt.Sprintf("unschedulable: %v (attempt %d, next retry in %v)",
+			err, b.attempts, b.next.Sub(m.clock().Now()).Round(time.Second))
+		log.Printf("[%s] Task %v is unschedulable: %v\n", t.CorrelationID, t.ID, err)
+		m.addEvent(t)
+		return nil
+	}
+
+	allocate(n, t)
+	m.WorkerTaskMap[n.Name] = append(m.WorkerTaskMap[n.Name], t.ID)
+	m.TaskWorkerMap[t.ID] = n.Name
+
+	t.ScheduledBy = m.scheduledBy(t)
+	t.State = task.Scheduled
+	t.StatusReason = ""
+	if b != nil {
+		delete(m.backoffs, t.ID)
+		t.StatusReason = fmt.Sprintf("placed on %s after %d failed attempts", n.Name, b.attempts)
+		log.Printf("[%s] Task %v placed on %s after %d failed attempts\n", t.CorrelationID, t.ID, n.Name, b.attempts)
+	}
+	m.addEvent(t)
+	return n
+}
+
+func allocate(n *node.Node, t *task.Task) {
+	n.MemoryAllocated += int(t.ReservedMemory())
+	n.DiskAllocated += int(t.Disk)
+	n.TaskCount++
+	for _, dm := range t.Devices {
+		if slices.Contains(n.Devices, dm.PathOnHost) {
+			if n.DevicesInUse == nil {
+				n.DevicesInUse = make(map[string]bool)
+			}
+			n.DevicesInUse[dm.PathOnHost] = true
+		}
+	}
+	if app := t.Labels["app"]; app != "" {
+		if n.Apps == nil {
+			n.Apps = make(map[string]int)
+		}
+		n.Apps[app]++
+	}
+}
+
+func release(n *node.Node, t *task.Task) {
+	// Allocations are never negative, even if a task is released with
+	// more than it was allocated, such as after its requirements grew.
+	n.MemoryAllocated = max(n.MemoryAllocated-int(t.ReservedMemory()), 0)
+	n.DiskAllocated = max(n.DiskAllocated-int(t.Disk), 0)
+	n.TaskCount--
+	for _, dm := range t.Devices {
+		delete(n.DevicesInUse, dm.PathOnHost)
+	}
+	if app := t.Labels["app"]; app != "" && n.Apps[app] > 0 {
+		n.Apps[app]--
+	}
+}
+
+func (m *Manager) addEvent(t *task.Task) {
+	te := &task.TaskEvent{
+		ID:            uuid.New(),
+		State:         t.State,
+		Timestamp:     m.clock().Now(),
+		Task:          *t,
+		CorrelationID: t.CorrelationID,
+	}
+	m.EventDb[t.ID.String()] = append(m.EventDb[t.ID.String()], te)
+	m.Events.Publish(*te)
+}
+
+// EventsSince returns the recorded events newer than since, oldest first.
+func (m *Manager) EventsSince(since time.Time) []task.TaskEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []task.TaskEvent
+	for _, history := range m.EventDb {
+		for _, te := range history {
+			if te.Timestamp.After(since) {
+				events = append(events, *te)
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events
+}
+
+// nameInUse returns the active task other than except named name. The
+// lock must be held.
+func (m *Manager) nameInUse(name string, except uuid.UUID) *task.Task {
+	for id := range m.names[name] {
+		other := m.getTask(id)
+		if other != nil && other.ID != except && other.Name == name && !other.State.Terminal() {
+			return other
+		}
+	}
+	return nil
+}
+
+// indexName records that task id is called name. The lock must be held.
+func (m *Manager) indexName(id uuid.UUID, name string) {
+	if m.names == nil {
+		m.names = make(map[string]map[uuid.UUID]bool)
+	}
+	if m.names[name] == nil {
+		m.names[name] = make(map[uuid.UUID]bool)
+	}
+	m.names[name][id] = true
+}
+
+// unindexName forgets that task id is called name. The lock must be held.
+func (m *Manager) unindexName(id uuid.UUID, name string) {
+	delete(m.names[name], id)
+	if len(m.names[name]) == 0 {
+		delete(m.names, name)
+	}
+}
+
+// generateName expands tmpl with the lowest index that gives a name no
+// active task uses, returning the index too.
+func (m *Manager) generateName(t task.Task, tmpl string) (string, int, error) {
+	for i := 0; i <= len(m.TaskDb); i++ {
+		name, err := task.ExpandNameTemplate(tmpl, t, i)
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+		}
+		other := m.nameInUse(name, t.ID)
+		if other == nil {
+			return name, i, nil
+		}
+		if !strings.Contains(tmpl, "{index}") {
+			return "", 0, fmt.Errorf("%w: %s is used by task %v", ErrNameConflict, name, other.ID)
+		}
+	}
+	return "", 0, fmt.Errorf("%w: no free index for template %q", ErrNameConflict, tmpl)
+}
+
+func (m *Manager) activeSingleton(t task.Task) *task.Task {
+	for id := range m.names[t.Name] {
+		other := m.getTask(id)
+		if other == nil || !other.Singleton || other.Name != t.Name || other.ID == t.ID {
+			continue
+		}
+		switch other.State {
+		case task.Pending, task.Scheduled, task.Starting, task.Running:
+			return other
+		}
+	}
+	return nil
+}
+
+func (m *Manager) getTask(id uuid.UUID) *task.Task {
+	tasks := m.TaskDb[id.String()]
+	if len(tasks) == 0 {
+		return nil
+	}
+	return tasks[len(tasks)-1]
+}
+
+// createTime returns the CreateTime for a task being added now. Each is
+// later than the one before, so listings ordered by it page new tasks
+// after existing ones. The lock must be held.
+func (m *Manager) createTime() time.Time {
+	now := m.clock().Now().UTC()
+	if !now.After(m.lastCreate) {
+		now = m.lastCreate.Add(time.Nanosecond)
+	}
+	m.lastCreate = now
+	return now
+}
+
+func (m *Manager) clock() clock.Clock {
+	return clock.OrReal(m.Clock)
+}
+
+func (m *Manager) getNode(name string) *node.Node {
+	for _, n := range m.WorkerNodes {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
 }
@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// DrainNotice is sent by a worker that is shutting down, listing the
+// tasks it is about to stop.
+type DrainNotice struct {
+	Tasks []uuid.UUID
+}
+
+func (a *Api) DrainNodeHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var notice DrainNotice
+	err := json.NewDecoder(r.Body).Decode(&notice)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	err = a.Manager.NodeDraining(name, notice.Tasks)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("%w: %s", err, name))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// NodeCapacity is a node's memory and disk capacity as it reports it.
+type NodeCapacity struct {
+	Memory int
+	Disk   int
+}
+
+// UpdateNodeCapacityHandler records a node's new capacity, for example
+// after it came back with less memory.
+func (a *Api) UpdateNodeCapacityHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var c NodeCapacity
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+	if c.Memory < 0 || c.Disk < 0 {
+		writeError(w, http.StatusBadRequest, "Memory and disk capacity must not be negative")
+		return
+	}
+
+	update, err := a.Manager.UpdateNodeCapacity(name, c.Memory, c.Disk)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("%w: %s", err, name))
+		return
+	}
+	writeJSON(w, http.StatusOK, update)
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultUsageWindow = 20
+
+// UsageSample is one observation of what a task actually used.
+type UsageSample struct {
+	CPU    float64
+	Memory int64
+}
+
+// taskUsage is the part of a worker's GET /tasks/usage response the
+// manager keeps. Workers sample steady tasks less often than they're
+// polled, so the same sample, with the same SampledAt, can come back
+// several times.
+type taskUsage struct {
+	ID        uuid.UUID
+	Name      string
+	SampledAt time.Time
+	UsageSample
+}
+
+// RecordUsage adds a sample to the rolling window kept for tasks with
+// this name, dropping the oldest once the window is full.
+func (m *Manager) RecordUsage(name string, s UsageSample) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recordUsage(name, s)
+}
+
+func (m *Manager) recordUsage(name string, s UsageSample) {
+	if name == "" {
+		return
+	}
+	if m.usage == nil {
+		m.usage = make(map[string][]UsageSample)
+	}
+	window := m.UsageWindow
+	if window <= 0 {
+		window = defaultUsageWindow
+	}
+	samples := append(m.usage[name], s)
+	if len(samples) > window {
+		samples = samples[len(samples)-window:]
+	}
+	m.usage[name] = samples
+}
+
+// ObservedUsage returns the mean CPU and peak memory seen across the
+// window of samples for a task name.
+func (m *Manager) ObservedUsage(name string) (UsageSample, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.observedUsage(name)
+}
+
+func (m *Manager) observedUsage(name string) (UsageSample, bool) {
+	samples := m.usage[name]
+	if len(samples) == 0 {
+		return UsageSample{}, false
+	}
+	var observed UsageSample
+	for _, s := range samples {
+		observed.CPU += s.CPU
+		observed.Memory = max(observed.Memory, s.Memory)
+	}
+	observed.CPU /= float64(len(samples))
+	return observed, true
+}
+
+// applyObservedUsage has the scheduler reserve the memory a recurring task
+// has actually been using instead of what it declared, capped at its
+// limit. Tasks with no history keep their declared reservation. The
+// container's own limits are left alone.
+func (m *Manager) applyObservedUsage(t *task.Task) {
+	observed, ok := m.observedUsage(t.Name)
+	if !ok || observed.Memory <= 0 {
+		return
+	}
+	reserve := observed.Memory
+	if t.Memory > 0 && reserve > t.Memory {
+		reserve = t.Memory
+	}
+	if reserve != t.ReservedMemory() {
+		log.Printf("[%s] Reserving %d bytes for task %v from observed usage (declared %d)\n",
+			t.CorrelationID, reserve, t.ID, t.ReservedMemory())
+	}
+	t.ObservedMemory = reserve
+}
+
+// CollectUsage samples the usage of every running task from the workers.
+func (m *Manager) CollectUsage() {
+	m.mu.Lock()
+	var nodes []*node.Node
+	for _, n := range m.WorkerNodes {
+		if n.Api != "" && !n.Unreachable {
+			nodes = append(nodes, n)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, n := range nodes {
+		var usage []taskUsage
+		if err := callWorker(n, http.MethodGet, "/tasks/usage", "", nil, &usage); err != nil {
+			log.Printf("Error collecting task usage from %s: %v\n", n.Name, err)
+			continue
+		}
+		m.mu.Lock()
+		for _, u := range usage {
+			if !m.newSample(u) {
+				continue
+			}
+			m.recordUsage(u.Name, u.UsageSample)
+			m.checkAlerts(u)
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	m.pruneBreaches()
+	m.mu.Unlock()
+}
+
+// newSample reports whether u was taken after the last sample collected
+// for its task, and remembers it if so. The lock must be held.
+func (m *Manager) newSample(u taskUsage) bool {
+	if u.SampledAt.IsZero() {
+		return true
+	}
+	if !u.SampledAt.After(m.sampledAt[u.ID]) {
+		return false
+	}
+	if m.sampledAt == nil {
+		m.sampledAt = make(map[uuid.UUID]time.Time)
+	}
+	m.sampledAt[u.ID] = u.SampledAt
+	return true
+}
+
+// CollectUsageLoop runs CollectUsage every interval.
+func (m *Manager) CollectUsageLoop(interval time.Duration) {
+	for {
+		m.CollectUsage()
+		<-m.clock().After(interval)
+	}
+}
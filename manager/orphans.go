@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// ErrOrphanNotFound is returned when asked to adopt a container that
+// isn't an orphan on any worker.
+var ErrOrphanNotFound = errors.New("orphaned container not found")
+
+// Orphan is a container ordo created that no task in the manager refers
+// to, for example after the manager lost its task store.
+type Orphan struct {
+	Node string
+	task.ManagedContainer
+}
+
+// Orphans asks every reachable worker for the containers carrying ordo's
+// label and returns those no known task has as its container. Since the
+// manager only learns container IDs when it reconciles, a worker's own
+// tasks also count: a container one of them runs is known if the manager
+// has that task. Workers that can't be asked are logged and skipped.
+func (m *Manager) Orphans() []Orphan {
+	m.mu.Lock()
+	tasks := make(map[uuid.UUID]bool, len(m.TaskDb))
+	known := make(map[string]bool)
+	for _, versions := range m.TaskDb {
+		for _, t := range versions {
+			tasks[t.ID] = true
+			if t.ContainerID != "" {
+				known[t.ContainerID] = true
+			}
+		}
+	}
+	var nodes []node.Node
+	for _, n := range m.WorkerNodes {
+		if n.Api != "" && !n.Unreachable {
+			nodes = append(nodes, *n)
+		}
+	}
+	m.mu.Unlock()
+
+	found := make([][]Orphan, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n node.Node) {
+			defer wg.Done()
+			var containers []task.ManagedContainer
+			if err := callWorker(&n, http.MethodGet, "/containers", "", nil, &containers); err != nil {
+				log.Printf("Error listing containers on %s: %v\n", n.Name, err)
+				return
+			}
+			var workerTasks []task.Task
+			if err := callWorker(&n, http.MethodGet, "/tasks", "", nil, &workerTasks); err != nil {
+				log.Printf("Error listing tasks on %s: %v\n", n.Name, err)
+				return
+			}
+			tracked := make(map[string]bool)
+			for _, wt := range workerTasks {
+				if wt.ContainerID != "" && tasks[wt.ID] {
+					tracked[wt.ContainerID] = true
+				}
+			}
+			for _, c := range containers {
+				if !known[c.ID] && !tracked[c.ID] {
+					found[i] = append(found[i], Orphan{Node: n.Name, ManagedContainer: c})
+				}
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	orphans := []Orphan{}
+	for _, o := range found {
+		orphans = append(orphans, o...)
+	}
+	return orphans
+}
+
+// AdoptContainer has the worker holding an orphaned container start
+// tracking it as a task, and records that task in the manager. The
+// container may be given by its full ID or a unique prefix of at least
+// 12 characters.
+func (m *Manager) AdoptContainer(containerID string) (task.Task, error) {
+	var orphan *Orphan
+	for _, o := range m.Orphans() {
+		if o.ID == containerID || (len(containerID) >= 12 && strings.HasPrefix(o.ID, containerID)) {
+			o := o
+			orphan = &o
+			break
+		}
+	}
+	if orphan == nil {
+		return task.Task{}, fmt.Errorf("%w: %s", ErrOrphanNotFound, containerID)
+	}
+
+	m.mu.Lock()
+	n := m.getNode(orphan.Node)
+	m.mu.Unlock()
+	if n == nil {
+		return task.Task{}, fmt.Errorf("%w: %s", ErrNodeNotFound, orphan.Node)
+	}
+
+	var t task.Task
+	err := callWorker(n, http.MethodPost, fmt.Sprintf("/containers/%s/adopt", orphan.ID), "", nil, &t)
+	if err != nil {
+		return task.Task{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing := m.getTask(t.ID); existing != nil {
+		return *existing, nil
+	}
+	t.CreateTime = m.createTime()
+	m.TaskDb[t.ID.String()] = append(m.TaskDb[t.ID.String()], &t)
+	m.indexName(t.ID, t.Name)
+	if !t.State.Terminal() {
+		allocate(n, &t)
+		m.WorkerTaskMap[n.Name] = append(m.WorkerTaskMap[n.Name], t.ID)
+		m.TaskWorkerMap[t.ID] = n.Name
+	}
+	m.addEvent(&t)
+	log.Printf("Adopted container %s on %s as task %v\n", orphan.ID, n.Name, t.ID)
+	return t, nil
+}
+
+// syncContainers records the containers workers run the manager's tasks
+// in, which the manager doesn't learn when it places them. The lock must
+// be held.
+func (m *Manager) syncContainers(n *node.Node, tasks []task.Task) {
+	for _, wt := range tasks {
+		t := m.getTask(wt.ID)
+		if t == nil || m.TaskWorkerMap[wt.ID] != n.Name || wt.ContainerID == "" {
+			continue
+		}
+		t.ContainerID = wt.ContainerID
+	}
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/scheduler"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// ErrNoPlacement is returned for tasks the scheduler hasn't considered.
+var ErrNoPlacement = errors.New("no placement decision")
+
+// nodeDecisions says how each node fared in placing t. Nodes with a score
+// were candidates; the rest get the constraint that ruled them out.
+func (m *Manager) nodeDecisions(t task.Task, nodes []*node.Node, scores map[string]float64) []task.NodeDecision {
+	decisions := make([]task.NodeDecision, len(nodes))
+	for i, n := range nodes {
+		d := task.NodeDecision{Node: n.Name}
+		if score, ok := scores[n.Name]; ok {
+			d.Candidate, d.Score = true, score
+		} else if reason := scheduler.Unfit(t, n); reason != "" {
+			d.Eliminated = reason
+		} else if t.TopologySpread != nil {
+			d.Eliminated = fmt.Sprintf("placing it here would exceed the max skew of %d over %s",
+				t.TopologySpread.MaxSkew, t.TopologySpread.TopologyKey)
+		} else {
+			d.Eliminated = "not selected by the scheduler"
+		}
+		decisions[i] = d
+	}
+	return decisions
+}
+
+// decidingFactor explains why the scheduler picked n. The weighted random
+// scheduler scores better nodes higher and draws one at random; the
+// others pick the lowest score.
+func decidingFactor(sched string, n *node.Node, scores map[string]float64, candidates int) string {
+	if candidates == 1 {
+		return "only node that can take it"
+	}
+	if sched == "weightedrandom" {
+		if scoredBest(n, scores, func(a, b float64) bool { return a > b }) {
+			return fmt.Sprintf("drawn at random weighted by free memory, with the highest weight of %d candidates", candidates)
+		}
+		return fmt.Sprintf("drawn at random weighted by free memory from %d candidates", candidates)
+	}
+	if scoredBest(n, scores, func(a, b float64) bool { return a < b }) {
+		switch sched {
+		case "roundrobin":
+			return fmt.Sprintf("next in turn of %d candidates", candidates)
+		case "spread":
+			return fmt.Sprintf("best spread and packing (lowest score) of %d candidates", candidates)
+		}
+		return fmt.Sprintf("lowest score of %d candidates", candidates)
+	}
+	return fmt.Sprintf("picked by the %s scheduler from %d candidates", sched, candidates)
+}
+
+// scoredBest reports whether n's score is better than every other node's.
+func scoredBest(n *node.Node, scores map[string]float64, better func(a, b float64) bool) bool {
+	for name, score := range scores {
+		if name != n.Name && !better(scores[n.Name], score) {
+			return false
+		}
+	}
+	return true
+}
+
+// Placement returns the last placement decision for a task.
+func (m *Manager) Placement(id uuid.UUID) (task.PlacementDecision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.getTask(id)
+	if t == nil {
+		return task.PlacementDecision{}, fmt.Errorf("%w: %v", ErrTaskNotFound, id)
+	}
+	if t.Placement == nil {
+		return task.PlacementDecision{}, fmt.Errorf("%w: task %v hasn't been through scheduling yet", ErrNoPlacement, id)
+	}
+	return *t.Placement, nil
+}
+
+// GetPlacementHandler explains where a task was placed and why, or why it
+// can't be placed.
+func (a *Api) GetPlacementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	d, err := a.Manager.Placement(id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
@@ -0,0 +1,213 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPullTokenTTL = 10 * time.Minute
+	pullTokenRecheck    = 1 * time.Second
+)
+
+var ErrPullTokenNotFound = errors.New("pull token not found")
+
+// PullLease is a pull token held by a worker.
+type PullLease struct {
+	Token   string
+	Node    string
+	Image   string
+	Granted time.Time
+	Expires time.Time
+}
+
+// PullTokenStats reports how the cluster-wide pull limit is being used.
+// A Limit of zero means pulls aren't limited.
+type PullTokenStats struct {
+	Limit   int
+	InUse   int
+	Waiting int
+	Granted uint64
+	Expired uint64
+	Leases  []PullLease
+}
+
+// pullTokens is the semaphore behind MaxConcurrentPulls. Workers take a
+// token before pulling an image and give it back afterwards; tokens of
+// workers that never return them expire after PullTokenTTL.
+type pullTokens struct {
+	mu      sync.Mutex
+	held    map[string]PullLease
+	waiting int
+	granted uint64
+	expired uint64
+	// freed is closed and replaced whenever a token may have become
+	// available.
+	freed chan struct{}
+}
+
+// broadcast wakes every waiter. The lock must be held.
+func (p *pullTokens) broadcast() {
+	if p.freed != nil {
+		close(p.freed)
+	}
+	p.freed = make(chan struct{})
+}
+
+// expire drops leases past their expiry. The lock must be held.
+func (p *pullTokens) expire(now time.Time) {
+	for token, l := range p.held {
+		if now.After(l.Expires) {
+			log.Printf("Pull token %s of %s for %s expired\n", token, l.Node, l.Image)
+			delete(p.held, token)
+			p.expired++
+		}
+	}
+}
+
+// AcquirePullToken waits until fewer than MaxConcurrentPulls pulls are
+// running and returns a token for node to pull image with. It returns ""
+// when pulls aren't limited, or ctx's error if ctx ends first.
+func (m *Manager) AcquirePullToken(ctx context.Context, node, image string) (string, error) {
+	p := &m.pulls
+	waiting := false
+	defer func() {
+		if waiting {
+			p.mu.Lock()
+			p.waiting--
+			p.mu.Unlock()
+		}
+	}()
+
+	for {
+		m.mu.Lock()
+		limit, ttl := m.MaxConcurrentPulls, m.PullTokenTTL
+		m.mu.Unlock()
+		if limit <= 0 {
+			return "", nil
+		}
+		if ttl <= 0 {
+			ttl = defaultPullTokenTTL
+		}
+
+		now := m.clock().Now()
+		p.mu.Lock()
+		p.expire(now)
+		if len(p.held) < limit {
+			if p.held == nil {
+				p.held = make(map[string]PullLease)
+			}
+			token := uuid.NewString()
+			p.held[token] = PullLease{Token: token, Node: node, Image: image, Granted: now, Expires: now.Add(ttl)}
+			p.granted++
+			p.mu.Unlock()
+			return token, nil
+		}
+		if !waiting {
+			waiting = true
+			p.waiting++
+		}
+		if p.freed == nil {
+			p.freed = make(chan struct{})
+		}
+		freed := p.freed
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-freed:
+		case <-m.clock().After(pullTokenRecheck):
+		}
+	}
+}
+
+// ReleasePullToken returns a token so a waiting pull can start.
+func (m *Manager) ReleasePullToken(token string) error {
+	p := &m.pulls
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.held[token]; !ok {
+		return fmt.Errorf("%w: %s", ErrPullTokenNotFound, token)
+	}
+	delete(p.held, token)
+	p.broadcast()
+	return nil
+}
+
+// PullTokenStats reports the tokens in use and the pulls waiting for one.
+func (m *Manager) PullTokenStats() PullTokenStats {
+	m.mu.Lock()
+	limit := m.MaxConcurrentPulls
+	m.mu.Unlock()
+
+	p := &m.pulls
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expire(m.clock().Now())
+	s := PullTokenStats{
+		Limit:   limit,
+		InUse:   len(p.held),
+		Waiting: p.waiting,
+		Granted: p.granted,
+		Expired: p.expired,
+		Leases:  []PullLease{},
+	}
+	for _, l := range p.held {
+		s.Leases = append(s.Leases, l)
+	}
+	sort.Slice(s.Leases, func(i, j int) bool { return s.Leases[i].Granted.Before(s.Leases[j].Granted) })
+	return s
+}
+
+// PullTokenRequest is the body of POST /pulls/tokens.
+type PullTokenRequest struct {
+	Node  string
+	Image string
+}
+
+// PullToken is the response to POST /pulls/tokens. An empty Token means
+// pulls aren't limited and nothing needs releasing.
+type PullToken struct {
+	Token string
+}
+
+// AcquirePullTokenHandler answers once the worker may pull. It holds the
+// request open while the cluster is at its pull limit; a worker that
+// gives up just closes the connection.
+func (a *Api) AcquirePullTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req PullTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+	token, err := a.Manager.AcquirePullToken(r.Context(), req.Node, req.Image)
+	if err != nil {
+		log.Printf("[%s] %s stopped waiting for a pull token for %s: %v\n", r.Header.Get(RequestIDHeader), req.Node, req.Image, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, PullToken{Token: token})
+}
+
+func (a *Api) ReleasePullTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.Manager.ReleasePullToken(r.PathValue("token")); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) GetPullTokensHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.PullTokenStats())
+}
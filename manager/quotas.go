@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota caps what the active tasks whose labels match Selector, such as
+// "team=payments", may use across the cluster. A zero limit doesn't cap
+// that dimension. A task counts against every quota it matches, and must
+// set its own CPU and memory limits if a quota it matches caps them.
+type Quota struct {
+	Selector  string
+	MaxCpu    float64
+	MaxMemory int64
+	MaxTasks  int
+}
+
+// QuotaUsage is a quota with what its group currently uses.
+type QuotaUsage struct {
+	Quota
+	Cpu    float64
+	Memory int64
+	Tasks  int
+}
+
+type quota struct {
+	Quota
+	selector map[string]string
+}
+
+func (q Quota) Validate() error {
+	if _, err := parseSelector(q.Selector); err != nil {
+		return err
+	}
+	if q.MaxCpu < 0 || q.MaxMemory < 0 || q.MaxTasks < 0 {
+		return fmt.Errorf("quota %q limits must not be negative", q.Selector)
+	}
+	return nil
+}
+
+// SetQuotas replaces the quotas. Groups already over a new quota keep
+// their tasks; only further submissions are refused.
+func (m *Manager) SetQuotas(qs []Quota) error {
+	parsed, err := parseQuotas(qs)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas = parsed
+	return nil
+}
+
+func parseQuotas(qs []Quota) ([]quota, error) {
+	parsed := make([]quota, 0, len(qs))
+	for _, q := range qs {
+		if err := q.Validate(); err != nil {
+			return nil, err
+		}
+		sel, _ := parseSelector(q.Selector)
+		parsed = append(parsed, quota{Quota: q, selector: sel})
+	}
+	return parsed, nil
+}
+
+// Quotas lists the quotas with their groups' current usage.
+func (m *Manager) Quotas() []QuotaUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := make([]QuotaUsage, 0, len(m.quotas))
+	for _, q := range m.quotas {
+		usage = append(usage, m.quotaUsage(q, uuid.Nil))
+	}
+	return usage
+}
+
+// quotaUsage adds up the active tasks in q's group, leaving out except.
+// The lock must be held.
+func (m *Manager) quotaUsage(q quota, except uuid.UUID) QuotaUsage {
+	u := QuotaUsage{Quota: q.Quota}
+	for _, tasks := range m.TaskDb {
+		t := tasks[len(tasks)-1]
+		if t.ID == except || t.State.Terminal() || !matchesSelector(q.selector, t.Labels) {
+			continue
+		}
+		u.Cpu += t.CPU
+		u.Memory += t.Memory
+		u.Tasks++
+	}
+	return u
+}
+
+// checkQuotas returns ErrQuotaExceeded if admitting t, or t's new
+// resources when it's already admitted, would take any quota it matches
+// over a limit. The lock must be held, and kept until t is stored, so
+// concurrent submissions can't both fit into the same headroom.
+func (m *Manager) checkQuotas(t task.Task) error {
+	for _, q := range m.quotas {
+		if !matchesSelector(q.selector, t.Labels) {
+			continue
+		}
+		u := m.quotaUsage(q, t.ID)
+		switch {
+		case q.MaxCpu > 0 && t.CPU <= 0:
+			return fmt.Errorf("%w: %s caps cpu, so tasks must set a cpu limit", ErrQuotaExceeded, q.Selector)
+		case q.MaxMemory > 0 && t.Memory <= 0:
+			return fmt.Errorf("%w: %s caps memory, so tasks must set a memory limit", ErrQuotaExceeded, q.Selector)
+		case q.MaxTasks > 0 && u.Tasks+1 > q.MaxTasks:
+			return fmt.Errorf("%w: %s allows %d tasks and %d are active", ErrQuotaExceeded, q.Selector, q.MaxTasks, u.Tasks)
+		case q.MaxCpu > 0 && u.Cpu+t.CPU > q.MaxCpu:
+			return fmt.Errorf("%w: %s allows %.2f cpu, %.2f in use and %.2f requested", ErrQuotaExceeded, q.Selector, q.MaxCpu, u.Cpu, t.CPU)
+		case q.MaxMemory > 0 && u.Memory+t.Memory > q.MaxMemory:
+			return fmt.Errorf("%w: %s allows %d bytes of memory, %d in use and %d requested", ErrQuotaExceeded, q.Selector, q.MaxMemory, u.Memory, t.Memory)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const (
+	defaultReconcileInterval     = 30 * time.Second
+	defaultRescheduleConcurrency = 8
+	defaultPollConcurrency       = 16
+	defaultPollTimeout           = 5 * time.Second
+)
+
+// ReconcileReport summarizes what a reconciliation pass changed.
+type ReconcileReport struct {
+	Rescheduled []uuid.UUID
+	Unreachable []string
+	Recovered   []string
+	Cleaned     []uuid.UUID
+}
+
+// Reconcile compares the manager's view of the cluster with what the
+// workers report. Tasks on unreachable workers are rescheduled, and
+// containers the manager no longer expects on a worker are stopped.
+// Passes are serialized, so a manual trigger waits for a running pass.
+// Workers are polled in parallel, and their reachability is applied in
+// one step once every poll has finished. Rescheduled tasks are placed
+// after that, so none lands on a node this pass is about to find
+// unreachable.
+func (m *Manager) Reconcile() ReconcileReport {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	m.mu.Lock()
+	nodes := make([]*node.Node, 0, len(m.WorkerNodes))
+	for _, n := range m.WorkerNodes {
+		if n.Api != "" {
+			nodes = append(nodes, n)
+		}
+	}
+	m.mu.Unlock()
+
+	polls := m.pollWorkers(nodes)
+
+	var report ReconcileReport
+	m.mu.Lock()
+	for _, p := range polls {
+		if p.err != nil {
+			m.nodeUnreachable(p.node, p.err, &report)
+		} else {
+			m.nodeReachable(p.node, &report)
+			m.syncTimings(p.node, p.tasks)
+			m.syncRestarts(p.node, p.tasks)
+			m.syncContainers(p.node, p.tasks)
+			report.Rescheduled = append(report.Rescheduled, m.retryPullTimeouts(p.node, p.tasks)...)
+			report.Rescheduled = append(report.Rescheduled, m.retryOOMKilled(p.node, p.tasks)...)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, p := range polls {
+		if p.err != nil {
+			continue
+		}
+		n := p.node
+		for _, wt := range p.tasks {
+			if wt.State.Terminal() || m.expectedOn(n, wt.ID) {
+				continue
+			}
+			err := callWorker(n, http.MethodDelete, fmt.Sprintf("/tasks/%v", wt.ID), wt.CorrelationID, nil, nil)
+			if err != nil {
+				log.Printf("[%s] Error cleaning up task %v on %s: %v\n", wt.CorrelationID, wt.ID, n.Name, err)
+				continue
+			}
+			report.Cleaned = append(report.Cleaned, wt.ID)
+		}
+	}
+
+	m.placeRescheduled(report.Rescheduled)
+
+	if len(report.Rescheduled)+len(report.Unreachable)+len(report.Recovered)+len(report.Cleaned) > 0 {
+		log.Printf("Reconciled: %d rescheduled, %d nodes unreachable, %d recovered, %d cleaned\n",
+			len(report.Rescheduled), len(report.Unreachable), len(report.Recovered), len(report.Cleaned))
+	}
+	return report
+}
+
+// ReconcileLoop runs Reconcile every ReconcileInterval.
+func (m *Manager) ReconcileLoop() {
+	for {
+		m.Reconcile()
+
+		m.mu.Lock()
+		interval := m.ReconcileInterval
+		m.mu.Unlock()
+		if interval <= 0 {
+			interval = defaultReconcileInterval
+		}
+		<-m.clock().After(interval)
+	}
+}
+
+// workerPoll is the result of asking one worker for its tasks.
+type workerPoll struct {
+	node  *node.Node
+	tasks []task.Task
+	err   error
+}
+
+// pollWorkers asks every node for its tasks, with up to PollConcurrency
+// requests in flight and each bounded by PollTimeout, so a slow worker
+// only delays its own result. Results are in the order of nodes.
+func (m *Manager) pollWorkers(nodes []*node.Node) []workerPoll {
+	limit := m.PollConcurrency
+	if limit <= 0 {
+		limit = defaultPollConcurrency
+	}
+	timeout := m.PollTimeout
+	if timeout <= 0 {
+		timeout = defaultPollTimeout
+	}
+
+	polls := make([]workerPoll, len(nodes))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, n *node.Node) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			polls[i].node = n
+			polls[i].err = callWorkerContext(ctx, n, http.MethodGet, "/tasks", "", nil, &polls[i].tasks)
+		}(i, n)
+	}
+	wg.Wait()
+	return polls
+}
+
+func (m *Manager) nodeUnreachable(n *node.Node, err error, report *ReconcileReport) {
+	if !n.Unreachable {
+		log.Printf("Node %s is unreachable: %v\n", n.Name, err)
+		n.Unreachable = true
+		report.Unreachable = append(report.Unreachable, n.Name)
+	}
+	for _, id := range append([]uuid.UUID(nil), m.WorkerTaskMap[n.Name]...) {
+		t := m.getTask(id)
+		if t == nil || t.State.Terminal() {
+			continue
+		}
+		m.unassign(n, t, fmt.Sprintf("rescheduling: worker %s is unreachable", n.Name))
+		report.Rescheduled = append(report.Rescheduled, id)
+	}
+}
+
+func (m *Manager) nodeReachable(n *node.Node, report *ReconcileReport) {
+	if n.Unreachable {
+		log.Printf("Node %s is reachable again\n", n.Name)
+		n.Unreachable = false
+		report.Recovered = append(report.Recovered, n.Name)
+		m.wake()
+	}
+}
+
+// expectedOn reports whether the manager has the task active on n.
+func (m *Manager) expectedOn(n *node.Node, id uuid.UUID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.getTask(id)
+	return t != nil && !t.State.Terminal() && m.TaskWorkerMap[id] == n.Name
+}
+
+// placeRescheduled places tasks taken off unreachable workers and starts
+// them on their new workers without waiting for SendWork, with up to
+// RescheduleConcurrency tasks in flight. Placement and allocation happen
+// under mu, so concurrent placements see each other's allocations and
+// can't oversubscribe a node; only the worker calls run in parallel.
+// Tasks that can't be placed or started are queued for SendWork.
+func (m *Manager) placeRescheduled(ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	concurrency := m.RescheduleConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultRescheduleConcurrency
+	}
+
+	start := m.clock().Now()
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id uuid.UUID) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			m.placeNow(id)
+		}(id)
+	}
+	wg.Wait()
+	log.Printf("Placed %d rescheduled tasks in %v\n", len(ids), m.clock().Now().Sub(start))
+}
+
+func (m *Manager) placeNow(id uuid.UUID) {
+	m.mu.Lock()
+	t := m.getTask(id)
+	if t == nil || t.State != task.Pending {
+		m.mu.Unlock()
+		return
+	}
+	var n *node.Node
+	if !m.paused {
+		n = m.place(t)
+	}
+	if n == nil {
+		m.requeue(t)
+		m.mu.Unlock()
+		return
+	}
+	te := task.TaskEvent{
+		ID:            uuid.New(),
+		State:         task.Scheduled,
+		Timestamp:     m.clock().Now(),
+		Task:          m.withCredentials(*t),
+		CorrelationID: t.CorrelationID,
+	}
+	m.mu.Unlock()
+
+	if n.Api == "" {
+		return
+	}
+	err := callWorker(n, http.MethodPost, "/tasks", te.CorrelationID, te, nil)
+	if err == nil {
+		log.Printf("[%s] Rescheduled task %v onto %s\n", te.CorrelationID, id, n.Name)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t := m.getTask(id); t != nil && t.State == task.Scheduled && m.TaskWorkerMap[id] == n.Name {
+		if pullTimedOut(err) && t.NodeName == "" {
+			m.avoidNode(t, n.Name)
+		}
+		m.reschedule(n, t, fmt.Sprintf("rescheduling: starting on %s failed: %v", n.Name, err))
+	}
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/scheduler"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// BenchmarkPlaceRescheduled measures how long 200 tasks orphaned by a
+// dead worker take to be placed and started on four others, whose
+// workers take 5ms to accept each task, one at a time ("before") and
+// with the default concurrency ("after").
+func BenchmarkPlaceRescheduled(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer worker.Close()
+
+	for _, bc := range []struct {
+		name        string
+		concurrency int
+	}{
+		{"before", 1},
+		{"after", defaultRescheduleConcurrency},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				m, ids := orphanedTasks(worker.URL, 200)
+				m.RescheduleConcurrency = bc.concurrency
+				b.StartTimer()
+
+				m.placeRescheduled(ids)
+
+				b.StopTimer()
+				for _, id := range ids {
+					if t := m.getTask(id); t.State != task.Scheduled {
+						b.Fatalf("task %v is %v, want Scheduled", id, t.State)
+					}
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+// orphanedTasks returns a manager with four workers at api and count
+// Pending tasks that were taken off an unreachable one.
+func orphanedTasks(api string, count int) (*Manager, []uuid.UUID) {
+	m := &Manager{
+		TaskDb:        make(map[string][]*task.Task),
+		EventDb:       make(map[string][]*task.TaskEvent),
+		WorkerTaskMap: make(map[string][]uuid.UUID),
+		TaskWorkerMap: make(map[uuid.UUID]string),
+		Scheduler:     &scheduler.RoundRobin{Name: "roundrobin"},
+	}
+	for i := 0; i < 4; i++ {
+		m.WorkerNodes = append(m.WorkerNodes, &node.Node{
+			Name:   fmt.Sprintf("worker-%d", i),
+			Api:    api,
+			Cores:  64,
+			Memory: 1 << 40,
+			Disk:   1 << 40,
+		})
+	}
+	ids := make([]uuid.UUID, count)
+	for i := range ids {
+		t := &task.Task{ID: uuid.New(), Name: fmt.Sprintf("task-%d", i), State: task.Pending, Memory: 1 << 20}
+		m.TaskDb[t.ID.String()] = []*task.Task{t}
+		ids[i] = t.ID
+	}
+	return m, ids
+}
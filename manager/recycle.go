@@ -0,0 +1,295 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// RecycleLabel opts a task into recycling: with the label set to "true",
+// the task is stopped once it has run for MaxTaskAge.
+const RecycleLabel = "ordo.recycle"
+
+const (
+	defaultRecycleStagger  = 1 * time.Minute
+	defaultRecycleInterval = 30 * time.Second
+	// recycleReadyTimeout bounds how long a replacement has to become
+	// ready before the task it replaces is stopped.
+	recycleReadyTimeout = 5 * time.Minute
+	recycleReadyPoll    = 2 * time.Second
+)
+
+// RecycleTasks stops opted-in tasks that have run for longer than
+// MaxTaskAge and, with RecycleReschedule, replaces each with a fresh copy.
+// The copy is started first and the old task is only stopped once the
+// copy is ready, so an app with a single replica stays up; singletons,
+// which can't have two instances, are stopped first instead. Replicas are
+// recycled one at a time: tasks sharing an app label, or a name without
+// one, are recycled at most once per RecycleStagger, counted from when
+// the last one finished. It returns the recycled tasks.
+func (m *Manager) RecycleTasks() []uuid.UUID {
+	m.mu.Lock()
+	maxAge, reschedule := m.MaxTaskAge, m.RecycleReschedule
+	stagger := m.RecycleStagger
+	if stagger <= 0 {
+		stagger = defaultRecycleStagger
+	}
+	if maxAge <= 0 {
+		m.mu.Unlock()
+		return nil
+	}
+
+	now := m.clock().Now()
+	oldest := make(map[string]*task.Task)
+	since := make(map[uuid.UUID]time.Time)
+	for _, tasks := range m.TaskDb {
+		t := tasks[len(tasks)-1]
+		if t.Labels[RecycleLabel] != "true" || t.State.Terminal() || t.State == task.Pending {
+			continue
+		}
+		s := m.runningSince(t)
+		if s.IsZero() || now.Sub(s) < maxAge {
+			continue
+		}
+		g := recycleGroup(t)
+		if m.recycling[g] || now.Sub(m.recycled[g]) < stagger {
+			continue
+		}
+		since[t.ID] = s
+		if o := oldest[g]; o == nil || s.Before(since[o.ID]) {
+			oldest[g] = t
+		}
+	}
+
+	var due []recycleJob
+	for g, t := range oldest {
+		if m.recycling == nil {
+			m.recycling = make(map[string]bool)
+		}
+		m.recycling[g] = true
+		due = append(due, recycleJob{
+			task:  *t,
+			group: g,
+			reason: fmt.Sprintf("recycled after running for %v, over the max task age of %v",
+				now.Sub(since[t.ID]).Round(time.Second), maxAge),
+		})
+	}
+	m.mu.Unlock()
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids []uuid.UUID
+	)
+	for _, r := range due {
+		wg.Add(1)
+		go func(r recycleJob) {
+			defer wg.Done()
+			if m.recycle(r, reschedule) {
+				mu.Lock()
+				ids = append(ids, r.task.ID)
+				mu.Unlock()
+			}
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			delete(m.recycling, r.group)
+			if m.recycled == nil {
+				m.recycled = make(map[string]time.Time)
+			}
+			m.recycled[r.group] = m.clock().Now()
+		}(r)
+	}
+	wg.Wait()
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	return ids
+}
+
+// recycleJob is a task RecycleTasks is about to replace.
+type recycleJob struct {
+	task   task.Task
+	group  string
+	reason string
+}
+
+// recycle replaces and stops one task, and reports whether it was
+// stopped. A replacement that isn't ready within recycleReadyTimeout is
+// stopped again and the old task is left running.
+func (m *Manager) recycle(r recycleJob, reschedule bool) bool {
+	t := r.task
+	log.Printf("[%s] Recycling task %v: %s\n", t.CorrelationID, t.ID, r.reason)
+
+	replace := reschedule && !t.Singleton
+	if replace {
+		id, err := m.startReplacement(t)
+		if err != nil {
+			log.Printf("[%s] Error replacing recycled task %v, leaving it running: %v\n", t.CorrelationID, t.ID, err)
+			return false
+		}
+		log.Printf("[%s] Replacement %v for recycled task %v is ready\n", t.CorrelationID, id, t.ID)
+	}
+
+	m.mu.Lock()
+	if stored := m.getTask(t.ID); stored != nil {
+		stored.StatusReason = r.reason
+	}
+	m.mu.Unlock()
+	if _, err := m.StopTask(t.ID); err != nil {
+		log.Printf("[%s] Error recycling task %v: %v\n", t.CorrelationID, t.ID, err)
+		m.mu.Lock()
+		if stored := m.getTask(t.ID); stored != nil && stored.StatusReason == r.reason {
+			stored.StatusReason = ""
+		}
+		m.mu.Unlock()
+		return false
+	}
+
+	if reschedule && !replace {
+		rt := replacementOf(t)
+		if err := m.AddTask(task.TaskEvent{ID: uuid.New(), State: task.Pending, Task: rt, CorrelationID: rt.CorrelationID}); err != nil {
+			log.Printf("[%s] Error rescheduling recycled task %v: %v\n", t.CorrelationID, t.ID, err)
+			return true
+		}
+		log.Printf("[%s] Replaced recycled task %v with %v\n", t.CorrelationID, t.ID, rt.ID)
+	}
+	return true
+}
+
+// startReplacement submits a fresh copy of t and waits for it to be
+// ready. If it isn't, the copy is stopped.
+func (m *Manager) startReplacement(t task.Task) (uuid.UUID, error) {
+	rt := replacementOf(t)
+	err := m.AddTask(task.TaskEvent{ID: uuid.New(), State: task.Pending, Task: rt, CorrelationID: rt.CorrelationID})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recycleReadyTimeout)
+	defer cancel()
+	if err := m.waitReady(ctx, rt.ID); err != nil {
+		if _, stopErr := m.StopTask(rt.ID); stopErr != nil {
+			log.Printf("[%s] Error stopping replacement %v: %v\n", t.CorrelationID, rt.ID, stopErr)
+		}
+		return uuid.Nil, fmt.Errorf("replacement %v: %w", rt.ID, err)
+	}
+	return rt.ID, nil
+}
+
+// waitReady places a submitted task and waits for its worker to report
+// it Running, which a task with a readiness probe only is once the probe
+// passes.
+func (m *Manager) waitReady(ctx context.Context, id uuid.UUID) error {
+	for {
+		m.mu.Lock()
+		t := m.getTask(id)
+		if t == nil {
+			m.mu.Unlock()
+			return ErrTaskNotFound
+		}
+		state, reason, correlationID := t.State, t.StatusReason, t.CorrelationID
+		n := m.getNode(m.TaskWorkerMap[id])
+		m.mu.Unlock()
+
+		switch {
+		case state.Terminal():
+			return fmt.Errorf("task %v is %v: %s", id, state, reason)
+		case state == task.Pending:
+			m.placeNow(id)
+			if placed, _ := m.GetTask(id); placed.State != task.Pending {
+				continue
+			}
+		case n != nil:
+			ready, err := m.readyOn(ctx, n, id, correlationID)
+			if ready || err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if reason == "" {
+				reason = state.String()
+			}
+			return fmt.Errorf("task %v wasn't ready in time: %s", id, reason)
+		case <-m.clock().After(recycleReadyPoll):
+		}
+	}
+}
+
+// readyOn reports whether n runs the task and it's ready, or why it never
+// will be.
+func (m *Manager) readyOn(ctx context.Context, n *node.Node, id uuid.UUID, correlationID string) (bool, error) {
+	var tasks []task.Task
+	if err := callWorkerContext(ctx, n, http.MethodGet, "/tasks", correlationID, nil, &tasks); err != nil {
+		log.Printf("[%s] Error checking whether task %v is ready on %s: %v\n", correlationID, id, n.Name, err)
+		return false, nil
+	}
+	for _, wt := range tasks {
+		if wt.ID != id {
+			continue
+		}
+		if wt.State.Terminal() {
+			return false, fmt.Errorf("task %v is %v on %s: %s", id, wt.State, n.Name, wt.StatusReason)
+		}
+		return wt.State == task.Running, nil
+	}
+	return false, nil
+}
+
+// replacementOf is a fresh copy of t to be scheduled in its place.
+func replacementOf(t task.Task) task.Task {
+	r := t
+	r.ID = uuid.New()
+	r.State = task.Pending
+	r.ContainerID = ""
+	r.StatusReason = ""
+	r.CreateTime, r.StartTime, r.FinishTime = time.Time{}, time.Time{}, time.Time{}
+	r.ExitCode, r.RestartCount = 0, 0
+	r.Timings = task.Timings{}
+	r.RestartHistory, r.InitSteps, r.Placement = nil, nil, nil
+	return r
+}
+
+// RecycleLoop runs RecycleTasks every interval, or every
+// defaultRecycleInterval if it's zero.
+func (m *Manager) RecycleLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultRecycleInterval
+	}
+	for {
+		m.RecycleTasks()
+		<-m.clock().After(interval)
+	}
+}
+
+// runningSince is when the task's current container was placed or last
+// restarted. The lock must be held.
+func (m *Manager) runningSince(t *task.Task) time.Time {
+	since := t.StartTime
+	events := m.EventDb[t.ID.String()]
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].State == task.Scheduled {
+			if events[i].Timestamp.After(since) {
+				since = events[i].Timestamp
+			}
+			break
+		}
+	}
+	return since
+}
+
+// recycleGroup is the set of replicas a task is staggered with.
+func recycleGroup(t *task.Task) string {
+	if app := t.Labels["app"]; app != "" {
+		return "app=" + app
+	}
+	return "name=" + t.Name
+}
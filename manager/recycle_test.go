@@ -0,0 +1,75 @@
+package manager
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// TestRecycleStartsReplacementFirst recycles the only replica of an app
+// and checks its replacement was running before it was stopped.
+func TestRecycleStartsReplacementFirst(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var mu sync.Mutex
+	var calls []string
+	running := make(map[uuid.UUID]task.Task)
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/tasks":
+			var te task.TaskEvent
+			json.NewDecoder(r.Body).Decode(&te)
+			te.Task.State = task.Running
+			running[te.Task.ID] = te.Task
+			calls = append(calls, "start "+te.Task.ID.String())
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/tasks":
+			var tasks []task.Task
+			for _, t := range running {
+				tasks = append(tasks, t)
+			}
+			json.NewEncoder(w).Encode(tasks)
+		case r.Method == http.MethodDelete:
+			calls = append(calls, "stop "+strings.TrimPrefix(r.URL.Path, "/tasks/"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer worker.Close()
+
+	m, _ := orphanedTasks(worker.URL, 0)
+	m.MaxTaskAge = time.Hour
+	m.RecycleReschedule = true
+	old := &task.Task{
+		ID:        uuid.New(),
+		Name:      "web",
+		State:     task.Scheduled,
+		Labels:    map[string]string{"app": "web", RecycleLabel: "true"},
+		StartTime: time.Now().Add(-2 * time.Hour),
+	}
+	m.TaskDb[old.ID.String()] = []*task.Task{old}
+	m.TaskWorkerMap[old.ID] = "worker-0"
+	m.WorkerTaskMap["worker-0"] = []uuid.UUID{old.ID}
+
+	if ids := m.RecycleTasks(); len(ids) != 1 || ids[0] != old.ID {
+		t.Fatalf("RecycleTasks() = %v, want [%v]", ids, old.ID)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 || !strings.HasPrefix(calls[0], "start ") || calls[1] != "stop "+old.ID.String() {
+		t.Fatalf("worker calls = %v, want the replacement started before %v is stopped", calls, old.ID)
+	}
+}
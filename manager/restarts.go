@@ -0,0 +1,21 @@
+package manager
+
+import (
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// syncRestarts copies the restart counts and history workers recorded to
+// the manager's tasks. A worker's history starts from the one the manager
+// sent with the task, so it's the more complete of the two. The lock must
+// be held.
+func (m *Manager) syncRestarts(n *node.Node, tasks []task.Task) {
+	for _, wt := range tasks {
+		t := m.getTask(wt.ID)
+		if t == nil || m.TaskWorkerMap[wt.ID] != n.Name {
+			continue
+		}
+		t.RestartCount = wt.RestartCount
+		t.RestartHistory = wt.RestartHistory
+	}
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// codePullTimeout is the code a worker answers with when pulling an image
+// timed out.
+const codePullTimeout = "PULL_TIMEOUT"
+
+const (
+	// maxOOMRetries is how many times an OOM-killed task is placed again
+	// with more memory before it's left failed.
+	maxOOMRetries  = 3
+	oomRetryReason = "rescheduling: OOM-killed"
+)
+
+// retryPullTimeouts reschedules the tasks whose image pull timed out on
+// n, keeping them off n since another node may have the image cached. It
+// returns the rescheduled tasks. The lock must be held.
+func (m *Manager) retryPullTimeouts(n *node.Node, tasks []task.Task) []uuid.UUID {
+	var retried []uuid.UUID
+	for _, wt := range tasks {
+		if wt.State != task.Failed || !task.PullTimedOut(wt.StatusReason) {
+			continue
+		}
+		t := m.getTask(wt.ID)
+		if t == nil || t.State.Terminal() || m.TaskWorkerMap[wt.ID] != n.Name || t.NodeName != "" {
+			continue
+		}
+		m.avoidNode(t, n.Name)
+		m.reschedule(n, t, fmt.Sprintf("rescheduling: pulling %s on %s timed out", t.Image, n.Name))
+		retried = append(retried, t.ID)
+	}
+	return retried
+}
+
+// retryOOMKilled reschedules the tasks OOM-killed on n with double the
+// memory, both as their limit and as what the scheduler reserves, so
+// they're placed on a node with room for it. It returns the rescheduled
+// tasks. The lock must be held.
+func (m *Manager) retryOOMKilled(n *node.Node, tasks []task.Task) []uuid.UUID {
+	var retried []uuid.UUID
+	for _, wt := range tasks {
+		if wt.State != task.Failed || !strings.HasPrefix(wt.StatusReason, task.OOMKilledReason) {
+			continue
+		}
+		t := m.getTask(wt.ID)
+		if t == nil || t.State.Terminal() || m.TaskWorkerMap[wt.ID] != n.Name || oomRetries(t) >= maxOOMRetries {
+			continue
+		}
+
+		// The old reservation is released with the task before the new
+		// one is set, so the node's accounting stays balanced.
+		need := 2 * t.ReservedMemory()
+		if need == 0 {
+			if observed, ok := m.observedUsage(t.Name); ok {
+				need = 2 * observed.Memory
+			}
+		}
+		m.reschedule(n, t, fmt.Sprintf("%s on %s, retrying with %d bytes of memory", oomRetryReason, n.Name, need))
+		t.MinMemory = need
+		if t.Memory > 0 {
+			t.Memory *= 2
+		}
+		retried = append(retried, t.ID)
+	}
+	return retried
+}
+
+// oomRetries counts the times t has been rescheduled after an OOM kill.
+func oomRetries(t *task.Task) int {
+	retries := 0
+	for _, e := range t.RestartHistory {
+		if strings.HasPrefix(e.Reason, oomRetryReason) {
+			retries++
+		}
+	}
+	return retries
+}
+
+// pullTimedOut reports whether a worker refused a task because pulling
+// its image timed out.
+func pullTimedOut(err error) bool {
+	var we *WorkerError
+	return errors.As(err, &we) && we.Code == codePullTimeout
+}
+
+// avoidNode keeps t off the named node. Once every node is to be avoided
+// the list starts over, so the task can still be placed somewhere. The
+// lock must be held.
+func (m *Manager) avoidNode(t *task.Task, name string) {
+	if slices.Contains(t.AvoidNodes, name) {
+		return
+	}
+	if len(t.AvoidNodes)+1 >= len(m.WorkerNodes) {
+		t.AvoidNodes = nil
+	}
+	t.AvoidNodes = append(t.AvoidNodes, name)
+}
@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/ordopb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TaskService exposes the manager over gRPC. It calls the same Manager
+// methods as the REST handlers.
+type TaskService struct {
+	ordopb.UnimplementedTaskServiceServer
+	Manager *Manager
+}
+
+func (s *TaskService) SubmitTask(ctx context.Context, req *ordopb.TaskEvent) (*ordopb.Task, error) {
+	te, err := taskEventFromProto(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if te.Task.CorrelationID == "" {
+		te.Task.CorrelationID = uuid.NewString()
+	}
+	te.CorrelationID = te.Task.CorrelationID
+
+	err = s.Manager.AddTask(te)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	te.Task.RegistryAuth = nil
+	return taskToProto(te.Task), nil
+}
+
+func (s *TaskService) GetTask(ctx context.Context, req *ordopb.GetTaskRequest) (*ordopb.Task, error) {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid task ID %q", req.Id)
+	}
+	t, ok := s.Manager.GetTask(id)
+	if !ok {
+		return nil, rpcError(ErrTaskNotFound)
+	}
+	return taskToProto(t), nil
+}
+
+func (s *TaskService) ListTasks(ctx context.Context, req *ordopb.ListTasksRequest) (*ordopb.ListTasksResponse, error) {
+	resp := &ordopb.ListTasksResponse{}
+	if req.Full {
+		for _, t := range s.Manager.GetTasks() {
+			resp.Tasks = append(resp.Tasks, taskToProto(t))
+		}
+		return resp, nil
+	}
+	for _, ts := range s.Manager.GetTaskSummaries(req.App) {
+		resp.Summaries = append(resp.Summaries, summaryToProto(ts))
+	}
+	return resp, nil
+}
+
+func (s *TaskService) StopTask(ctx context.Context, req *ordopb.StopTaskRequest) (*ordopb.Task, error) {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid task ID %q", req.Id)
+	}
+	t, err := s.Manager.StopTask(id)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return taskToProto(t), nil
+}
+
+// StreamEvents sends the events recorded after req.Since, then follows the
+// event bus until the caller goes away or the manager shuts down. Like the
+// webhooks, a caller that falls behind misses events rather than holding
+// up the bus.
+func (s *TaskService) StreamEvents(req *ordopb.StreamEventsRequest, stream ordopb.TaskService_StreamEventsServer) error {
+	events, unsubscribe := s.Manager.Events.Subscribe(64)
+	defer unsubscribe()
+
+	sent := make(map[uuid.UUID]bool)
+	for _, te := range s.Manager.EventsSince(timeFromProto(req.Since)) {
+		if err := stream.Send(taskEventToProto(te)); err != nil {
+			return err
+		}
+		sent[te.ID] = true
+	}
+
+	done := s.Manager.Done()
+	for {
+		select {
+		case te, ok := <-events:
+			if !ok {
+				return nil
+			}
+			// Events published while the backlog was read are in both.
+			if sent[te.ID] {
+				continue
+			}
+			if err := stream.Send(taskEventToProto(te)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-done:
+			return status.Error(codes.Unavailable, "manager is shutting down")
+		}
+	}
+}
+
+// rpcError gives err the gRPC code closest to the status the REST API
+// would answer it with.
+func rpcError(err error) error {
+	code := codes.Internal
+	var we *WorkerError
+	if errors.As(err, &we) {
+		code = codes.Unavailable
+	}
+	for _, c := range errorCodes {
+		if !errors.Is(err, c.err) {
+			continue
+		}
+		switch c.status {
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusBadRequest:
+			code = codes.InvalidArgument
+		case http.StatusConflict:
+			code = codes.FailedPrecondition
+		case http.StatusForbidden:
+			code = codes.ResourceExhausted
+		case http.StatusServiceUnavailable, http.StatusBadGateway:
+			code = codes.Unavailable
+		}
+		break
+	}
+	return status.Error(code, err.Error())
+}
+
+// ServeRPC serves TaskService over gRPC on addr until the manager shuts
+// down.
+func ServeRPC(addr string, m *Manager) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	ordopb.RegisterTaskServiceServer(server, &TaskService{Manager: m})
+	go func() {
+		<-m.Done()
+		server.GracefulStop()
+	}()
+	return server.Serve(l)
+}
@@ -0,0 +1,324 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/ordopb"
+	"github.com/sajalkmr/ordo/task"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The conversions below map the gRPC messages onto the task package's
+// types, so the gRPC service can hand them to the same Manager methods as
+// the REST handlers.
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func durationToProto(d time.Duration) *durationpb.Duration {
+	if d == 0 {
+		return nil
+	}
+	return durationpb.New(d)
+}
+
+func parseIDs(ids []string) ([]uuid.UUID, error) {
+	var out []uuid.UUID
+	for _, s := range ids {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid task ID %q", s)
+		}
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+func probeToProto(p *task.Probe) *ordopb.Probe {
+	if p == nil {
+		return nil
+	}
+	pp := &ordopb.Probe{
+		InitialDelay:     durationToProto(p.InitialDelay),
+		Period:           durationToProto(p.Period),
+		Timeout:          durationToProto(p.Timeout),
+		FailureThreshold: int32(p.FailureThreshold),
+	}
+	if p.HTTPGet != nil {
+		pp.HttpGet = &ordopb.HTTPGetAction{Path: p.HTTPGet.Path, Port: int32(p.HTTPGet.Port)}
+	}
+	if p.TCPSocket != nil {
+		pp.TcpSocket = &ordopb.TCPSocketAction{Port: int32(p.TCPSocket.Port)}
+	}
+	if p.Exec != nil {
+		pp.Exec = &ordopb.ExecAction{Command: p.Exec.Command}
+	}
+	return pp
+}
+
+func probeFromProto(pp *ordopb.Probe) *task.Probe {
+	if pp == nil {
+		return nil
+	}
+	p := &task.Probe{
+		InitialDelay:     pp.InitialDelay.AsDuration(),
+		Period:           pp.Period.AsDuration(),
+		Timeout:          pp.Timeout.AsDuration(),
+		FailureThreshold: int(pp.FailureThreshold),
+	}
+	if pp.HttpGet != nil {
+		p.HTTPGet = &task.HTTPGetAction{Path: pp.HttpGet.Path, Port: int(pp.HttpGet.Port)}
+	}
+	if pp.TcpSocket != nil {
+		p.TCPSocket = &task.TCPSocketAction{Port: int(pp.TcpSocket.Port)}
+	}
+	if pp.Exec != nil {
+		p.Exec = &task.ExecAction{Command: pp.Exec.Command}
+	}
+	return p
+}
+
+func configToProto(c task.Config) *ordopb.Config {
+	return &ordopb.Config{
+		Name:   c.Name,
+		Cmd:    c.Cmd,
+		Image:  c.Image,
+		Cpu:    c.Cpu,
+		Memory: c.Memory,
+		Disk:   c.Disk,
+		Env:    c.Env,
+		Labels: c.Labels,
+	}
+}
+
+func configFromProto(pc *ordopb.Config) task.Config {
+	return task.Config{
+		Name:   pc.Name,
+		Cmd:    pc.Cmd,
+		Image:  pc.Image,
+		Cpu:    pc.Cpu,
+		Memory: pc.Memory,
+		Disk:   pc.Disk,
+		Env:    pc.Env,
+		Labels: pc.Labels,
+	}
+}
+
+func taskToProto(t task.Task) *ordopb.Task {
+	pt := &ordopb.Task{
+		Id:                   t.ID.String(),
+		ContainerId:          t.ContainerID,
+		Name:                 t.Name,
+		State:                ordopb.State(t.State),
+		Image:                t.Image,
+		Cpu:                  t.CPU,
+		Memory:               t.Memory,
+		Disk:                 t.Disk,
+		PortBindings:         t.PortBindings,
+		RestartPolicy:        t.RestartPolicy,
+		StartTime:            timeToProto(t.StartTime),
+		FinishTime:           timeToProto(t.FinishTime),
+		StatusReason:         t.StatusReason,
+		ExitCode:             int32(t.ExitCode),
+		RestartCount:         int32(t.RestartCount),
+		Singleton:            t.Singleton,
+		ShmSize:              t.ShmSize,
+		Platform:             t.Platform,
+		Labels:               t.Labels,
+		LivenessProbe:        probeToProto(t.LivenessProbe),
+		ReadinessProbe:       probeToProto(t.ReadinessProbe),
+		SecurityOpt:          t.SecurityOpt,
+		NodeName:             t.NodeName,
+		RemoveOnStop:         t.RemoveOnStop,
+		MemoryReservation:    t.MemoryReservation,
+		NameTemplate:         t.NameTemplate,
+		CreateTime:           timeToProto(t.CreateTime),
+		Annotations:          t.Annotations,
+		RemoveImageOnStop:    t.RemoveImageOnStop,
+		Sysctls:              t.Sysctls,
+		PullTimeout:          durationToProto(t.PullTimeout),
+		ReplicaIndex:         int32(t.ReplicaIndex),
+		InjectMetadataEnv:    t.InjectMetadataEnv,
+		CpuAlertThreshold:    t.CpuAlertThreshold,
+		MemoryAlertThreshold: t.MemoryAlertThreshold,
+		DeregisterDelay:      durationToProto(t.DeregisterDelay),
+		MemorySwap:           t.MemorySwap,
+		LogToFile:            t.LogToFile,
+		LogFile:              t.LogFile,
+		StartAt:              timeToProto(t.StartAt),
+		Schedule:             t.Schedule,
+		ConcurrencyPolicy:    t.ConcurrencyPolicy,
+		AttachStdin:          t.AttachStdin,
+		ScheduledBy:          t.ScheduledBy,
+		UsernsMode:           t.UsernsMode,
+		CallbackUrl:          t.CallbackURL,
+		SoftDependsTimeout:   durationToProto(t.SoftDependsTimeout),
+		CgroupParent:         t.CgroupParent,
+		Priority:             int32(t.Priority),
+		AvoidNodes:           t.AvoidNodes,
+		CorrelationId:        t.CorrelationID,
+		Hostname:             t.Hostname,
+		Domainname:           t.Domainname,
+	}
+	for p := range t.ExposedPorts {
+		pt.ExposedPorts = append(pt.ExposedPorts, string(p))
+	}
+	for _, m := range t.Mounts {
+		pt.Mounts = append(pt.Mounts, &ordopb.Mount{Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly})
+	}
+	for _, c := range t.InitContainers {
+		pt.InitContainers = append(pt.InitContainers, configToProto(c))
+	}
+	for _, id := range t.SoftDependsOn {
+		pt.SoftDependsOn = append(pt.SoftDependsOn, id.String())
+	}
+	return pt
+}
+
+func taskFromProto(pt *ordopb.Task) (task.Task, error) {
+	t := task.Task{
+		ContainerID:          pt.ContainerId,
+		Name:                 pt.Name,
+		State:                task.State(pt.State),
+		Image:                pt.Image,
+		CPU:                  pt.Cpu,
+		Memory:               pt.Memory,
+		Disk:                 pt.Disk,
+		PortBindings:         pt.PortBindings,
+		RestartPolicy:        pt.RestartPolicy,
+		StartTime:            timeFromProto(pt.StartTime),
+		FinishTime:           timeFromProto(pt.FinishTime),
+		StatusReason:         pt.StatusReason,
+		ExitCode:             int(pt.ExitCode),
+		RestartCount:         int(pt.RestartCount),
+		Singleton:            pt.Singleton,
+		ShmSize:              pt.ShmSize,
+		Platform:             pt.Platform,
+		Labels:               pt.Labels,
+		LivenessProbe:        probeFromProto(pt.LivenessProbe),
+		ReadinessProbe:       probeFromProto(pt.ReadinessProbe),
+		SecurityOpt:          pt.SecurityOpt,
+		NodeName:             pt.NodeName,
+		RemoveOnStop:         pt.RemoveOnStop,
+		MemoryReservation:    pt.MemoryReservation,
+		NameTemplate:         pt.NameTemplate,
+		CreateTime:           timeFromProto(pt.CreateTime),
+		Annotations:          pt.Annotations,
+		RemoveImageOnStop:    pt.RemoveImageOnStop,
+		Sysctls:              pt.Sysctls,
+		PullTimeout:          pt.PullTimeout.AsDuration(),
+		ReplicaIndex:         int(pt.ReplicaIndex),
+		InjectMetadataEnv:    pt.InjectMetadataEnv,
+		CpuAlertThreshold:    pt.CpuAlertThreshold,
+		MemoryAlertThreshold: pt.MemoryAlertThreshold,
+		DeregisterDelay:      pt.DeregisterDelay.AsDuration(),
+		MemorySwap:           pt.MemorySwap,
+		LogToFile:            pt.LogToFile,
+		LogFile:              pt.LogFile,
+		StartAt:              timeFromProto(pt.StartAt),
+		Schedule:             pt.Schedule,
+		ConcurrencyPolicy:    pt.ConcurrencyPolicy,
+		AttachStdin:          pt.AttachStdin,
+		ScheduledBy:          pt.ScheduledBy,
+		UsernsMode:           pt.UsernsMode,
+		CallbackURL:          pt.CallbackUrl,
+		SoftDependsTimeout:   pt.SoftDependsTimeout.AsDuration(),
+		CgroupParent:         pt.CgroupParent,
+		Priority:             int(pt.Priority),
+		AvoidNodes:           pt.AvoidNodes,
+		CorrelationID:        pt.CorrelationId,
+		Hostname:             pt.Hostname,
+		Domainname:           pt.Domainname,
+	}
+	if pt.Id != "" {
+		id, err := uuid.Parse(pt.Id)
+		if err != nil {
+			return task.Task{}, fmt.Errorf("invalid task ID %q", pt.Id)
+		}
+		t.ID = id
+	}
+	if len(pt.ExposedPorts) > 0 {
+		t.ExposedPorts = make(nat.PortSet)
+		for _, p := range pt.ExposedPorts {
+			t.ExposedPorts[nat.Port(p)] = struct{}{}
+		}
+	}
+	for _, m := range pt.Mounts {
+		t.Mounts = append(t.Mounts, task.Mount{Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly})
+	}
+	for _, c := range pt.InitContainers {
+		t.InitContainers = append(t.InitContainers, configFromProto(c))
+	}
+	deps, err := parseIDs(pt.SoftDependsOn)
+	if err != nil {
+		return task.Task{}, err
+	}
+	t.SoftDependsOn = deps
+	if a := pt.RegistryAuth; a != nil {
+		t.RegistryAuth = &task.RegistryAuth{Username: a.Username, Password: a.Password, ServerAddress: a.ServerAddress}
+	}
+	return t, nil
+}
+
+func summaryToProto(ts task.TaskSummary) *ordopb.TaskSummary {
+	return &ordopb.TaskSummary{
+		Id:        ts.ID.String(),
+		Name:      ts.Name,
+		State:     ordopb.State(ts.State),
+		Image:     ts.Image,
+		Node:      ts.Node,
+		StartTime: timeToProto(ts.StartTime),
+	}
+}
+
+func taskEventToProto(te task.TaskEvent) *ordopb.TaskEvent {
+	pe := &ordopb.TaskEvent{
+		Id:            te.ID.String(),
+		State:         ordopb.State(te.State),
+		Timestamp:     timeToProto(te.Timestamp),
+		Task:          taskToProto(te.Task),
+		CorrelationId: te.CorrelationID,
+	}
+	if te.Alert != nil {
+		pe.Alert = &ordopb.Alert{Resource: te.Alert.Resource, Value: te.Alert.Value, Threshold: te.Alert.Threshold}
+	}
+	return pe
+}
+
+func taskEventFromProto(pe *ordopb.TaskEvent) (task.TaskEvent, error) {
+	te := task.TaskEvent{
+		State:         task.State(pe.State),
+		Timestamp:     timeFromProto(pe.Timestamp),
+		CorrelationID: pe.CorrelationId,
+	}
+	if pe.Id != "" {
+		id, err := uuid.Parse(pe.Id)
+		if err != nil {
+			return task.TaskEvent{}, fmt.Errorf("invalid event ID %q", pe.Id)
+		}
+		te.ID = id
+	}
+	if pe.Task != nil {
+		t, err := taskFromProto(pe.Task)
+		if err != nil {
+			return task.TaskEvent{}, err
+		}
+		te.Task = t
+	}
+	return te, nil
+}
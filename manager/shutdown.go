@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const (
+	defaultShutdownTimeout = 60 * time.Second
+	shutdownPollInterval   = time.Second
+)
+
+// ShutdownReport lists what happened to each active task during a
+// cluster shutdown. Unconfirmed tasks were asked to stop but their
+// worker hadn't reported them stopped when the timeout ran out.
+type ShutdownReport struct {
+	Stopped     []uuid.UUID
+	Unconfirmed []uuid.UUID
+	Failed      map[string]string `json:",omitempty"`
+}
+
+type shutdownState struct {
+	done   chan struct{}
+	report ShutdownReport
+}
+
+// Shutdown stops every active task in the cluster, waits for the workers
+// to confirm the containers are gone and then closes Done so the process
+// can exit. Scheduling is paused first so nothing new is placed while
+// tasks wind down. Calling it again while a shutdown is in progress waits
+// for that one and returns its report.
+func (m *Manager) Shutdown(correlationID string) ShutdownReport {
+	m.mu.Lock()
+	if m.shutdown != nil {
+		s := m.shutdown
+		m.mu.Unlock()
+		<-s.done
+		return s.report
+	}
+	s := &shutdownState{done: make(chan struct{})}
+	m.shutdown = s
+	m.paused = true
+
+	var ids []uuid.UUID
+	for _, versions := range m.TaskDb {
+		t := versions[len(versions)-1]
+		if !t.State.Terminal() {
+			ids = append(ids, t.ID)
+		}
+	}
+	m.mu.Unlock()
+
+	log.Printf("[%s] Shutting down: stopping %d tasks\n", correlationID, len(ids))
+	report := ShutdownReport{Failed: make(map[string]string)}
+	waiting := make(map[string][]uuid.UUID)
+	for i, id := range ids {
+		m.mu.Lock()
+		nodeName := m.TaskWorkerMap[id]
+		m.mu.Unlock()
+
+		t, err := m.StopTask(id)
+		if err != nil {
+			log.Printf("[%s] Error stopping task %v (%d/%d): %v\n", correlationID, id, i+1, len(ids), err)
+			report.Failed[id.String()] = err.Error()
+			continue
+		}
+		log.Printf("[%s] Stopping task %s (%v) (%d/%d)\n", correlationID, t.Name, id, i+1, len(ids))
+		if nodeName != "" {
+			waiting[nodeName] = append(waiting[nodeName], id)
+		} else {
+			report.Stopped = append(report.Stopped, id)
+		}
+	}
+
+	deadline := m.clock().Now().Add(m.shutdownTimeout())
+	for nodeName, pending := range waiting {
+		m.mu.Lock()
+		n := m.getNode(nodeName)
+		m.mu.Unlock()
+
+		stopped, unconfirmed := m.awaitStopped(n, pending, deadline, correlationID)
+		report.Stopped = append(report.Stopped, stopped...)
+		report.Unconfirmed = append(report.Unconfirmed, unconfirmed...)
+	}
+
+	log.Printf("[%s] Shutdown complete: %d stopped, %d unconfirmed, %d failed\n",
+		correlationID, len(report.Stopped), len(report.Unconfirmed), len(report.Failed))
+	s.report = report
+	close(s.done)
+
+	m.mu.Lock()
+	close(m.exitChan())
+	m.mu.Unlock()
+	return report
+}
+
+// awaitStopped polls a worker until none of ids is active on it or the
+// deadline passes.
+func (m *Manager) awaitStopped(n *node.Node, ids []uuid.UUID, deadline time.Time, correlationID string) ([]uuid.UUID, []uuid.UUID) {
+	if n == nil || n.Api == "" {
+		return ids, nil
+	}
+
+	for {
+		var tasks []task.Task
+		err := callWorker(n, http.MethodGet, "/tasks", correlationID, nil, &tasks)
+		if err == nil {
+			active := make(map[uuid.UUID]bool)
+			for _, t := range tasks {
+				if !t.State.Terminal() {
+					active[t.ID] = true
+				}
+			}
+
+			var stopped, pending []uuid.UUID
+			for _, id := range ids {
+				if active[id] {
+					pending = append(pending, id)
+				} else {
+					stopped = append(stopped, id)
+				}
+			}
+			if len(pending) == 0 || m.clock().Now().After(deadline) {
+				log.Printf("[%s] Worker %s stopped %d of %d tasks\n", correlationID, n.Name, len(stopped), len(ids))
+				return stopped, pending
+			}
+		} else if m.clock().Now().After(deadline) {
+			log.Printf("[%s] Error confirming tasks stopped on %s: %v\n", correlationID, n.Name, err)
+			return nil, ids
+		}
+		<-m.clock().After(shutdownPollInterval)
+	}
+}
+
+func (m *Manager) shutdownTimeout() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ShutdownTimeout > 0 {
+		return m.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
+}
+
+// Done is closed once Shutdown has stopped every task, telling the API
+// server and the process to exit.
+func (m *Manager) Done() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.exitChan()
+}
+
+func (m *Manager) exitChan() chan struct{} {
+	if m.exit == nil {
+		m.exit = make(chan struct{})
+	}
+	return m.exit
+}
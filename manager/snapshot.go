@@ -0,0 +1,224 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// SnapshotVersion is the version of the snapshot format. It changes when
+// a snapshot written by one version can't be imported by another.
+const SnapshotVersion = 2
+
+var (
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+	ErrManagerNotEmpty = errors.New("manager already has state")
+)
+
+// Snapshot is the manager's state for backup and restore. The registry
+// passwords in the credential store are left out unless the export asks
+// for them; a snapshot that has them must be kept as safe as they are.
+type Snapshot struct {
+	Version   int
+	CreatedAt time.Time
+	// SecretsRedacted is set when the credential store's passwords were
+	// left out. Importing such a snapshot keeps the importing manager's
+	// own credential store.
+	SecretsRedacted bool
+	// Tasks holds every version of every task, oldest first, and Events
+	// their events.
+	Tasks  map[string][]task.Task
+	Events map[string][]task.TaskEvent
+	Nodes  []node.Node
+	// Assignments maps tasks to the node they're placed on.
+	Assignments map[uuid.UUID]string
+	// CronJobs are the recurring tasks with their schedule state.
+	CronJobs    []SnapshotCronJob
+	Credentials []Credential
+	Quotas      []Quota
+}
+
+// SnapshotCronJob is a recurring task's template and where its schedule
+// stands, so an imported manager carries on from the same runs.
+type SnapshotCronJob struct {
+	Template task.Task
+	Disabled bool
+	LastRun  time.Time
+	NextRun  time.Time
+	Runs     []CronRun
+}
+
+// Export takes a snapshot of the manager's state, with the credential
+// store's passwords only if secrets is set.
+func (m *Manager) Export(secrets bool) Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := Snapshot{
+		Version:     SnapshotVersion,
+		CreatedAt:   m.clock().Now().UTC(),
+		Tasks:       make(map[string][]task.Task, len(m.TaskDb)),
+		Events:      make(map[string][]task.TaskEvent, len(m.EventDb)),
+		Assignments: make(map[uuid.UUID]string, len(m.TaskWorkerMap)),
+		Credentials: m.Credentials.Entries(),
+	}
+	if !secrets {
+		s.SecretsRedacted = true
+		for i := range s.Credentials {
+			s.Credentials[i].Auth.Password = ""
+		}
+	}
+	for id, versions := range m.TaskDb {
+		for _, t := range versions {
+			s.Tasks[id] = append(s.Tasks[id], *t)
+		}
+	}
+	for id, events := range m.EventDb {
+		for _, te := range events {
+			s.Events[id] = append(s.Events[id], *te)
+		}
+	}
+	for _, n := range m.WorkerNodes {
+		s.Nodes = append(s.Nodes, *n)
+	}
+	for id, name := range m.TaskWorkerMap {
+		s.Assignments[id] = name
+	}
+	for _, j := range m.cronJobs {
+		s.CronJobs = append(s.CronJobs, SnapshotCronJob{
+			Template: j.template,
+			Disabled: j.Disabled,
+			LastRun:  j.LastRun,
+			NextRun:  j.NextRun,
+			Runs:     append([]CronRun(nil), j.Runs...),
+		})
+	}
+	for _, q := range m.quotas {
+		s.Quotas = append(s.Quotas, q.Quota)
+	}
+	return s
+}
+
+// Import restores a snapshot into a manager that has no tasks, nodes or
+// recurring tasks yet. Either all of it is loaded or, if any part is
+// invalid, none of it. Pending tasks are queued again, and a
+// reconciliation pass then checks the placed tasks against what the
+// workers are actually running.
+func (m *Manager) Import(s Snapshot) error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("%w: version %d, this manager reads version %d", ErrInvalidSnapshot, s.Version, SnapshotVersion)
+	}
+	quotas, err := parseQuotas(s.Quotas)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	creds, err := parseCredentials(s.Credentials)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+
+	nodes := make([]*node.Node, len(s.Nodes))
+	names := make([]string, len(s.Nodes))
+	for i := range s.Nodes {
+		n := s.Nodes[i]
+		if err := n.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if slices.Contains(names[:i], n.Name) {
+			return fmt.Errorf("%w: node %s appears more than once", ErrInvalidSnapshot, n.Name)
+		}
+		nodes[i], names[i] = &n, n.Name
+	}
+
+	taskDb := make(map[string][]*task.Task, len(s.Tasks))
+	for id, versions := range s.Tasks {
+		if len(versions) == 0 {
+			return fmt.Errorf("%w: task %s has no versions", ErrInvalidSnapshot, id)
+		}
+		for i := range versions {
+			t := versions[i]
+			if t.ID.String() != id {
+				return fmt.Errorf("%w: task %v filed under %s", ErrInvalidSnapshot, t.ID, id)
+			}
+			taskDb[id] = append(taskDb[id], &t)
+		}
+	}
+	eventDb := make(map[string][]*task.TaskEvent, len(s.Events))
+	for id, events := range s.Events {
+		for i := range events {
+			te := events[i]
+			eventDb[id] = append(eventDb[id], &te)
+		}
+	}
+
+	taskWorkerMap := make(map[uuid.UUID]string, len(s.Assignments))
+	workerTaskMap := make(map[string][]uuid.UUID)
+	for id, name := range s.Assignments {
+		if _, ok := taskDb[id.String()]; !ok {
+			return fmt.Errorf("%w: task %v is assigned but missing", ErrInvalidSnapshot, id)
+		}
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("%w: task %v is assigned to unknown node %s", ErrInvalidSnapshot, id, name)
+		}
+		taskWorkerMap[id] = name
+		workerTaskMap[name] = append(workerTaskMap[name], id)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.TaskDb) > 0 || len(m.WorkerNodes) > 0 || len(m.cronJobs) > 0 {
+		return ErrManagerNotEmpty
+	}
+	cronJobs := make(map[uuid.UUID]*CronJob, len(s.CronJobs))
+	for _, sj := range s.CronJobs {
+		t := sj.Template
+		job, err := m.newCronJob(t)
+		if err != nil {
+			return fmt.Errorf("%w: recurring task %v: %v", ErrInvalidSnapshot, t.ID, err)
+		}
+		// Keep the saved next run so runs missed while the manager was
+		// down are handled as CatchUpMissedRuns says.
+		job.Disabled, job.LastRun, job.Runs = sj.Disabled, sj.LastRun, sj.Runs
+		if !sj.NextRun.IsZero() {
+			job.NextRun = sj.NextRun
+		}
+		cronJobs[t.ID] = job
+	}
+
+	m.TaskDb, m.EventDb = taskDb, eventDb
+	m.names = nil
+	for _, versions := range taskDb {
+		t := versions[len(versions)-1]
+		m.indexName(t.ID, t.Name)
+	}
+	m.WorkerNodes, m.Workers = nodes, names
+	m.TaskWorkerMap, m.WorkerTaskMap = taskWorkerMap, workerTaskMap
+	m.cronJobs = cronJobs
+	m.quotas = quotas
+	if s.SecretsRedacted {
+		log.Printf("Snapshot has no registry passwords, keeping the current credential store\n")
+	} else {
+		m.Credentials.mu.Lock()
+		m.Credentials.entries = creds
+		m.Credentials.mu.Unlock()
+	}
+
+	for _, versions := range taskDb {
+		if t := versions[len(versions)-1]; t.State == task.Pending {
+			m.requeue(t)
+		}
+	}
+	log.Printf("Imported snapshot from %v: %d tasks, %d nodes, %d recurring tasks\n",
+		s.CreatedAt, len(taskDb), len(nodes), len(cronJobs))
+
+	go m.Reconcile()
+	m.wake()
+	return nil
+}
@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultSoftDependsTimeout = 5 * time.Minute
+
+// waitingOnSoftDeps returns the soft dependencies t is still waiting
+// for. It stops waiting once SoftDependsTimeout has passed since the
+// task was created. The lock must be held.
+func (m *Manager) waitingOnSoftDeps(t *task.Task) []uuid.UUID {
+	if len(t.SoftDependsOn) == 0 {
+		return nil
+	}
+	timeout := t.SoftDependsTimeout
+	if timeout <= 0 {
+		timeout = defaultSoftDependsTimeout
+	}
+	if !m.clock().Now().Before(t.CreateTime.Add(timeout)) {
+		return nil
+	}
+
+	var waiting []uuid.UUID
+	for _, id := range t.SoftDependsOn {
+		if !m.softDepReady(id) {
+			waiting = append(waiting, id)
+		}
+	}
+	return waiting
+}
+
+// softDepReady reports whether a soft dependency no longer holds anything
+// up: it's running, or it will never run, including when it doesn't
+// exist. The lock must be held.
+func (m *Manager) softDepReady(id uuid.UUID) bool {
+	dep := m.getTask(id)
+	switch {
+	case dep == nil, dep.State.Terminal(), dep.State == task.Running:
+		return true
+	}
+	// Workers report StartToRunning once the container is up and ready.
+	return dep.Timings.StartToRunning > 0
+}
+
+func validateSoftDeps(t task.Task) error {
+	if t.SoftDependsTimeout < 0 {
+		return fmt.Errorf("soft dependency timeout must not be negative, got %v", t.SoftDependsTimeout)
+	}
+	for _, id := range t.SoftDependsOn {
+		if id == t.ID {
+			return fmt.Errorf("task %v can't depend on itself", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// placedTask adds a Scheduled task to m and places it on worker-0.
+func placedTask(m *Manager) *task.Task {
+	t := &task.Task{ID: uuid.New(), Name: "web", State: task.Scheduled, Memory: 1 << 20}
+	m.TaskDb[t.ID.String()] = []*task.Task{t}
+	m.TaskWorkerMap[t.ID] = "worker-0"
+	m.WorkerTaskMap["worker-0"] = []uuid.UUID{t.ID}
+	m.WorkerNodes[0].MemoryAllocated = int(t.Memory)
+	m.WorkerNodes[0].TaskCount = 1
+	return t
+}
+
+func checkStopped(t *testing.T, m *Manager, id uuid.UUID) {
+	t.Helper()
+	got, _ := m.GetTask(id)
+	if got.State != task.Completed {
+		t.Errorf("task is %v, want Completed", got.State)
+	}
+	if _, ok := m.TaskWorkerMap[id]; ok {
+		t.Errorf("task is still in TaskWorkerMap")
+	}
+	if ids := m.WorkerTaskMap["worker-0"]; len(ids) != 0 {
+		t.Errorf("WorkerTaskMap[worker-0] = %v, want it empty", ids)
+	}
+	if n := m.WorkerNodes[0]; n.MemoryAllocated != 0 || n.TaskCount != 0 {
+		t.Errorf("worker-0 has %d bytes and %d tasks allocated, want none", n.MemoryAllocated, n.TaskCount)
+	}
+}
+
+// TestStopTaskReleasesLock checks the manager can be read while the
+// worker handles the stop.
+func TestStopTaskReleasesLock(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var m *Manager
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read := make(chan struct{})
+		go func() {
+			m.GetTasks()
+			close(read)
+		}()
+		select {
+		case <-read:
+		case <-time.After(2 * time.Second):
+			t.Error("the manager lock was held during the worker call")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer worker.Close()
+
+	m, _ = orphanedTasks(worker.URL, 0)
+	tk := placedTask(m)
+	if _, err := m.StopTask(tk.ID); err != nil {
+		t.Fatalf("StopTask: %v", err)
+	}
+	checkStopped(t, m, tk.ID)
+}
+
+// TestStopTaskUnknownToWorker stops a task its worker never received,
+// which must still end up Completed and off the node.
+func TestStopTaskUnknownToWorker(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "task not found", http.StatusNotFound)
+	}))
+	defer worker.Close()
+
+	m, _ := orphanedTasks(worker.URL, 0)
+	tk := placedTask(m)
+	if _, err := m.StopTask(tk.ID); err != nil {
+		t.Fatalf("StopTask: %v", err)
+	}
+	checkStopped(t, m, tk.ID)
+}
+
+// TestStopTaskWorkerError checks a failed stop leaves the task running
+// where it was.
+func TestStopTaskWorkerError(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer worker.Close()
+
+	m, _ := orphanedTasks(worker.URL, 0)
+	tk := placedTask(m)
+	if _, err := m.StopTask(tk.ID); err == nil {
+		t.Fatal("StopTask succeeded, want the worker's error")
+	}
+	if got, _ := m.GetTask(tk.ID); got.State != task.Scheduled {
+		t.Errorf("task is %v, want Scheduled", got.State)
+	}
+	if m.TaskWorkerMap[tk.ID] != "worker-0" {
+		t.Errorf("task was taken off worker-0")
+	}
+}
+
+// TestForceStopTask checks only a forced stop skips the worker's
+// deregistration delay, and that both take the task off its node even
+// when the worker no longer has it.
+func TestForceStopTask(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, tc := range []struct {
+		name   string
+		stop   func(*Manager, uuid.UUID) (task.Task, error)
+		force  string
+		status int
+	}{
+		{"graceful", (*Manager).StopTask, "", http.StatusNoContent},
+		{"forced", (*Manager).ForceStopTask, "true", http.StatusNoContent},
+		{"graceful unknown", (*Manager).StopTask, "", http.StatusNotFound},
+		{"forced unknown", (*Manager).ForceStopTask, "true", http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var force string
+			worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				force = r.URL.Query().Get("force")
+				w.WriteHeader(tc.status)
+			}))
+			defer worker.Close()
+
+			m, _ := orphanedTasks(worker.URL, 0)
+			tk := placedTask(m)
+			if _, err := tc.stop(m, tk.ID); err != nil {
+				t.Fatalf("stop: %v", err)
+			}
+			if force != tc.force {
+				t.Errorf("worker got force=%q, want %q", force, tc.force)
+			}
+			checkStopped(t, m, tk.ID)
+		})
+	}
+}
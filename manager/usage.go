@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+type UsageReport struct {
+	Tasks int
+	task.ResourceUsage
+}
+
+func (m *Manager) TaskUsage(id uuid.UUID) (task.ResourceUsage, error) {
+	t, ok := m.GetTask(id)
+	if !ok {
+		return task.ResourceUsage{}, ErrTaskNotFound
+	}
+	return task.Usage(t, time.Time{}, m.clock().Now().UTC()), nil
+}
+
+// TaskStats fetches a running task's current usage from the worker
+// hosting it. The worker's answer is passed on as it is.
+func (m *Manager) TaskStats(id uuid.UUID, interfaces bool, correlationID string) (json.RawMessage, error) {
+	n, err := m.hostingNode(id)
+	if err != nil {
+		return nil, err
+	}
+	var stats json.RawMessage
+	path := fmt.Sprintf("/tasks/%v/stats?interfaces=%t", id, interfaces)
+	if err := callWorker(&n, http.MethodGet, path, correlationID, nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ClusterUsage sums the usage since the given time of every task, or of
+// an app's tasks when app is non-empty.
+func (m *Manager) ClusterUsage(app string, since time.Time) UsageReport {
+	now := m.clock().Now().UTC()
+	var report UsageReport
+	for _, t := range m.GetTasks() {
+		if app != "" && t.Labels["app"] != app {
+			continue
+		}
+		u := task.Usage(t, since, now)
+		if u.Seconds == 0 {
+			continue
+		}
+		report.Tasks++
+		report.Add(u)
+	}
+	return report
+}
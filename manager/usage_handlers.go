@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (a *Api) GetTaskUsageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	u, err := a.Manager.TaskUsage(id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// GetTaskStatsHandler returns a running task's resource and network
+// usage from its worker. ?interfaces=true breaks the network traffic
+// down by interface.
+func (a *Api) GetTaskStatsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+	interfaces := false
+	if v := r.URL.Query().Get("interfaces"); v != "" {
+		interfaces, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid interfaces parameter %q", v))
+			return
+		}
+	}
+
+	stats, err := a.Manager.TaskStats(id, interfaces, r.Header.Get(RequestIDHeader))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// GetClusterStatsHandler reports cluster-wide capacity, allocation and
+// real usage with a per-node breakdown.
+func (a *Api) GetClusterStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.ClusterStats())
+}
+
+// GetLatencyHandler returns histograms of how long pulling, creating,
+// starting and stopping task containers took.
+func (a *Api) GetLatencyHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Manager.Latency())
+}
+
+// GetUsageHandler reports resource-seconds across the cluster, optionally
+// for one ?app= and from an RFC 3339 ?since=.
+func (a *Api) GetUsageHandler(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid since parameter %q", v))
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, a.Manager.ClusterUsage(r.URL.Query().Get("app"), since))
+}
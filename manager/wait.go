@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultMaxSubmitWait = 2 * time.Minute
+
+// Placement is where a task was scheduled.
+type Placement struct {
+	Node string
+	Task task.Task
+}
+
+// submitWait caps a requested wait at MaxSubmitWait.
+func (m *Manager) submitWait(wait time.Duration) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limit := m.MaxSubmitWait
+	if limit <= 0 {
+		limit = defaultMaxSubmitWait
+	}
+	return min(wait, limit)
+}
+
+// WaitScheduled waits for a submitted task to be placed on a node. It
+// asks for a scheduling pass straight away and again whenever capacity
+// changes. If ctx ends first the task stays queued, and the error says
+// why it couldn't be placed.
+func (m *Manager) WaitScheduled(ctx context.Context, id uuid.UUID) (Placement, error) {
+	events, unsubscribe := m.Events.Subscribe(16)
+	defer unsubscribe()
+
+	m.requestSendWork()
+	for {
+		m.mu.Lock()
+		t := m.getTask(id)
+		if t == nil {
+			m.mu.Unlock()
+			return Placement{}, ErrTaskNotFound
+		}
+		if t.State != task.Pending {
+			p := Placement{Node: m.TaskWorkerMap[id], Task: *t}
+			m.mu.Unlock()
+			return p, nil
+		}
+		reason := t.StatusReason
+		room := m.roomSignal()
+		var retry <-chan time.Time
+		if b := m.backoffs[id]; b != nil {
+			retry = m.clock().After(b.next.Sub(m.clock().Now()))
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			if reason == "" {
+				reason = "still queued"
+			}
+			return Placement{}, fmt.Errorf("%w: task %v wasn't scheduled in time and stays queued: %s", ErrNoCandidateNodes, id, reason)
+		case <-room:
+			m.requestSendWork()
+		case <-retry:
+			m.requestSendWork()
+		case <-events:
+		}
+	}
+}
+
+// requestSendWork runs SendWork in the background. Waiters woken by the
+// same capacity change all ask at once; they share a single pass rather
+// than each running their own. A request made once the pass has started
+// gets another one, since the pass may have missed what prompted it.
+func (m *Manager) requestSendWork() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sendQueued {
+		return
+	}
+	m.sendQueued = true
+	go func() {
+		m.mu.Lock()
+		m.sendQueued = false
+		m.mu.Unlock()
+		m.SendWork()
+	}()
+}
@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// warmImageTimeout bounds each worker's pull of a warmed image.
+const warmImageTimeout = 15 * time.Minute
+
+// warmClient has no timeout of its own since pulls of large images take
+// longer than workerClient allows; requests are bounded by
+// warmImageTimeout instead.
+var warmClient = &http.Client{}
+
+// WarmImageRequest asks workers to pull an image ahead of the tasks that
+// will use it. Nodes limits it to the named workers, otherwise every
+// reachable worker pulls. Labels pick registry credentials from the
+// credential store the way a task's labels would, unless RegistryAuth is
+// given.
+type WarmImageRequest struct {
+	Image        string
+	Platform     string
+	Nodes        []string
+	Labels       map[string]string
+	RegistryAuth *task.RegistryAuth
+}
+
+// NodeWarm is one worker's result of warming an image.
+type NodeWarm struct {
+	Node string
+	// Source is the mirror or registry the image came from.
+	Source   string `json:",omitempty"`
+	Duration time.Duration
+	Error    string `json:",omitempty"`
+}
+
+type WarmReport struct {
+	Image  string
+	Nodes  []NodeWarm
+	Failed int
+}
+
+// WarmImage has the requested workers pull an image in parallel. The
+// pulls take pull tokens like any other, so they stay within
+// MaxConcurrentPulls.
+func (m *Manager) WarmImage(req WarmImageRequest, correlationID string) (WarmReport, error) {
+	if req.Image == "" {
+		return WarmReport{}, fmt.Errorf("%w: no image given", ErrInvalidTask)
+	}
+
+	m.mu.Lock()
+	var nodes []*node.Node
+	if len(req.Nodes) == 0 {
+		for _, n := range m.WorkerNodes {
+			if n.Api != "" && !n.Unreachable {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	for _, name := range req.Nodes {
+		n := m.getNode(name)
+		if n == nil {
+			m.mu.Unlock()
+			return WarmReport{}, fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+		}
+		nodes = append(nodes, n)
+	}
+	t := m.withCredentials(task.Task{
+		Image:        req.Image,
+		Platform:     req.Platform,
+		Labels:       req.Labels,
+		RegistryAuth: req.RegistryAuth,
+	})
+	m.mu.Unlock()
+
+	results := make([]NodeWarm, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *node.Node) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), warmImageTimeout)
+			defer cancel()
+
+			var pull struct {
+				Source   string
+				Duration time.Duration
+			}
+			results[i].Node = n.Name
+			err := callWorkerWith(ctx, warmClient, n, http.MethodPost, "/images/pull", correlationID, t, &pull)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Source, results[i].Duration = pull.Source, pull.Duration
+		}(i, n)
+	}
+	wg.Wait()
+
+	report := WarmReport{Image: req.Image, Nodes: results}
+	for _, r := range results {
+		if r.Error != "" {
+			report.Failed++
+		}
+	}
+	log.Printf("[%s] Warmed image %s on %d nodes, %d failed\n", correlationID, req.Image, len(results), report.Failed)
+	return report, nil
+}
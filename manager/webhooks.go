@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of a webhook's body,
+// keyed with its secret, as "sha256=<hex>".
+const WebhookSignatureHeader = "X-Ordo-Signature"
+
+const (
+	defaultWebhookQueue    = 100
+	defaultWebhookAttempts = 5
+	webhookBackoffBase     = 1 * time.Second
+	webhookBackoffMax      = 1 * time.Minute
+)
+
+var (
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Webhook POSTs task events to URL. States and Labels filter the events
+// it receives: an event must be in one of States, when there are any,
+// and its task must carry every one of Labels. Deliveries are signed
+// with Secret when it's set; it's never returned by the API.
+type Webhook struct {
+	ID     string
+	URL    string
+	States []string          `json:",omitempty"`
+	Labels map[string]string `json:",omitempty"`
+	Secret string            `json:",omitempty"`
+}
+
+// webhooks holds the registered webhooks, each with its own bounded
+// delivery queue so a slow receiver only delays itself.
+type webhooks struct {
+	mu   sync.Mutex
+	subs map[string]*webhookSub
+}
+
+type webhookSub struct {
+	hook   Webhook
+	states []task.State
+	queue  chan task.TaskEvent
+}
+
+func (s *webhookSub) matches(te task.TaskEvent) bool {
+	if len(s.states) > 0 && !containsState(s.states, te.State) {
+		return false
+	}
+	for k, v := range s.hook.Labels {
+		if l, ok := te.Task.Labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+func containsState(states []task.State, s task.State) bool {
+	for _, st := range states {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AddWebhook registers a webhook and starts delivering matching events
+// to it. It returns the webhook with its generated ID.
+func (m *Manager) AddWebhook(wh Webhook) (Webhook, error) {
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, fmt.Errorf("%w: URL %q must be an absolute http or https URL", ErrInvalidWebhook, wh.URL)
+	}
+	var states []task.State
+	for _, name := range wh.States {
+		s, err := task.ParseState(name)
+		if err != nil {
+			return Webhook{}, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+		states = append(states, s)
+	}
+
+	wh.ID = uuid.NewString()
+	size := m.WebhookQueueSize
+	if size <= 0 {
+		size = defaultWebhookQueue
+	}
+	sub := &webhookSub{hook: wh, states: states, queue: make(chan task.TaskEvent, size)}
+
+	m.webhooks.mu.Lock()
+	if m.webhooks.subs == nil {
+		m.webhooks.subs = make(map[string]*webhookSub)
+	}
+	m.webhooks.subs[wh.ID] = sub
+	m.webhooks.mu.Unlock()
+
+	go m.deliverWebhooks(sub)
+	log.Printf("Added webhook %s for %s\n", wh.ID, wh.URL)
+	return redactWebhook(wh), nil
+}
+
+// RemoveWebhook unregisters a webhook. Events already queued for it are
+// still delivered.
+func (m *Manager) RemoveWebhook(id string) error {
+	m.webhooks.mu.Lock()
+	defer m.webhooks.mu.Unlock()
+
+	sub, ok := m.webhooks.subs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+	delete(m.webhooks.subs, id)
+	close(sub.queue)
+	log.Printf("Removed webhook %s\n", id)
+	return nil
+}
+
+// Webhooks lists the registered webhooks, without their secrets.
+func (m *Manager) Webhooks() []Webhook {
+	m.webhooks.mu.Lock()
+	defer m.webhooks.mu.Unlock()
+
+	hooks := []Webhook{}
+	for _, s := range m.webhooks.subs {
+		hooks = append(hooks, redactWebhook(s.hook))
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		return hooks[i].URL < hooks[j].URL || (hooks[i].URL == hooks[j].URL && hooks[i].ID < hooks[j].ID)
+	})
+	return hooks
+}
+
+func redactWebhook(wh Webhook) Webhook {
+	wh.Secret = ""
+	return wh
+}
+
+// WebhookLoop feeds events from the event bus to the webhooks until the
+// manager shuts down. It only ever queues events: a webhook whose queue
+// is full drops the event rather than holding up the others.
+func (m *Manager) WebhookLoop() {
+	size := m.WebhookQueueSize
+	if size <= 0 {
+		size = defaultWebhookQueue
+	}
+	events, unsubscribe := m.Events.Subscribe(size)
+	defer unsubscribe()
+
+	done := m.Done()
+	for {
+		select {
+		case te := <-events:
+			m.dispatchWebhooks(te)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (m *Manager) dispatchWebhooks(te task.TaskEvent) {
+	m.webhooks.mu.Lock()
+	defer m.webhooks.mu.Unlock()
+
+	for id, s := range m.webhooks.subs {
+		if !s.matches(te) {
+			continue
+		}
+		select {
+		case s.queue <- te:
+		default:
+			log.Printf("[%s] Webhook %s queue is full, dropping event for task %v\n", te.CorrelationID, id, te.Task.ID)
+		}
+	}
+}
+
+func (m *Manager) deliverWebhooks(s *webhookSub) {
+	for te := range s.queue {
+		m.deliverWebhook(s.hook, te)
+	}
+}
+
+// deliverWebhook POSTs one event, retrying with exponential backoff up
+// to WebhookAttempts times.
+func (m *Manager) deliverWebhook(wh Webhook, te task.TaskEvent) {
+	body, err := json.Marshal(te)
+	if err != nil {
+		log.Printf("[%s] Error marshalling event for webhook %s: %v\n", te.CorrelationID, wh.ID, err)
+		return
+	}
+
+	attempts := m.WebhookAttempts
+	if attempts <= 0 {
+		attempts = defaultWebhookAttempts
+	}
+	delay := webhookBackoffBase
+	for i := 1; ; i++ {
+		err = postWebhook(wh, body, te.CorrelationID)
+		if err == nil {
+			return
+		}
+		if i >= attempts {
+			log.Printf("[%s] Giving up on webhook %s for task %v after %d attempts: %v\n", te.CorrelationID, wh.ID, te.Task.ID, i, err)
+			return
+		}
+		log.Printf("[%s] Error delivering to webhook %s, retrying in %v: %v\n", te.CorrelationID, wh.ID, delay, err)
+		<-m.clock().After(delay)
+		delay *= 2
+		if delay > webhookBackoffMax {
+			delay = webhookBackoffMax
+		}
+	}
+}
+
+func postWebhook(wh Webhook, body []byte, correlationID string) error {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if correlationID != "" {
+		req.Header.Set(RequestIDHeader, correlationID)
+	}
+	if wh.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(wh.Secret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", wh.URL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sajalkmr/ordo/node"
+)
+
+var workerClient = &http.Client{Timeout: 10 * time.Second}
+
+// WorkerError is returned when a worker answers a request with an error
+// status.
+type WorkerError struct {
+	Node       string
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// ErrWorkerUnavailable is returned when a worker can't be reached at all.
+var ErrWorkerUnavailable = errors.New("worker unavailable")
+
+func (e *WorkerError) Error() string {
+	return fmt.Sprintf("worker %s returned %d: %s", e.Node, e.StatusCode, e.Message)
+}
+
+// callWorker sends a JSON request to a worker's API and decodes a
+// successful response into out when it's non-nil. The correlation ID is
+// forwarded so the worker's logs can be tied back to the request.
+func callWorker(n *node.Node, method, path, correlationID string, in, out any) error {
+	return callWorkerContext(context.Background(), n, method, path, correlationID, in, out)
+}
+
+// callWorkerContext is callWorker with a context bounding the request.
+func callWorkerContext(ctx context.Context, n *node.Node, method, path, correlationID string, in, out any) error {
+	return callWorkerWith(ctx, workerClient, n, method, path, correlationID, in, out)
+}
+
+// callWorkerWith is callWorkerContext with a client of the caller's
+// choosing, for requests that outlast workerClient's timeout.
+func callWorkerWith(ctx context.Context, client *http.Client, n *node.Node, method, path, correlationID string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, n.Api+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if correlationID != "" {
+		req.Header.Set(RequestIDHeader, correlationID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: error connecting to worker %s: %v", ErrWorkerUnavailable, n.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		e := ErrResponse{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return &WorkerError{Node: n.Name, StatusCode: resp.StatusCode, Code: e.Error.Code, Message: e.Error.Message}
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
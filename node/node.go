@@ -1,13 +1,63 @@
 package node
 
+import "time"
+
+const (
+	memoryPressureThreshold = 90.0
+	memoryPressureWindow    = 30 * time.Second
+)
+
 type Node struct {
 	Name            string
 	Ip              string
+	Api             string
 	Cores           int
 	Memory          int
 	MemoryAllocated int
 	Disk            int
 	DiskAllocated   int
 	Role            string
+	Platform        string
+	Zone            string
 	TaskCount       int
+	MaxTasks        int
+	UnderPressure   bool
+	// Draining is set once the worker starts shutting down. No new tasks
+	// are placed on it.
+	Draining bool
+	// Overcommitted is set while the node's allocations exceed its
+	// capacity, which can shrink after it's registered. No new tasks are
+	// placed on it.
+	Overcommitted bool
+	// Unreachable is set while the manager can't reach the worker's API.
+	Unreachable bool
+	// Apps counts the active tasks on the node by their "app" label.
+	Apps map[string]int
+	// Devices lists host device paths that are handed out to one task
+	// at a time. Devices not listed here aren't tracked.
+	Devices      []string
+	DevicesInUse map[string]bool
+	// Taints keep tasks off the node unless they tolerate them.
+	Taints []Taint
+	// Labels describe where the node sits, such as its rack, for
+	// topology spreading.
+	Labels map[string]string
+
+	pressureSince time.Time
+}
+
+// UpdatePressure records the node's latest memory usage. The node is
+// considered under pressure once usage has stayed above the threshold for
+// the whole pressure window, and stops being so as soon as it drops below.
+func (n *Node) UpdatePressure(memUsedPercent float64, now time.Time) {
+	if memUsedPercent < memoryPressureThreshold {
+		n.pressureSince = time.Time{}
+		n.UnderPressure = false
+		return
+	}
+
+	if n.pressureSince.IsZero() {
+		n.pressureSince = now
+	}
+	n.UnderPressure = now.Sub(n.pressureSince) > memoryPressureWindow
 }
@@ -0,0 +1,39 @@
+package node
+
+import "fmt"
+
+// Taint effects. NoSchedule keeps tasks without a matching toleration
+// off the node; PreferNoSchedule only makes the scheduler avoid it.
+const (
+	TaintNoSchedule       = "NoSchedule"
+	TaintPreferNoSchedule = "PreferNoSchedule"
+)
+
+// Taint reserves a node for tasks that tolerate it, e.g. gpu=true with
+// effect NoSchedule.
+type Taint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+func (t Taint) Validate() error {
+	if t.Key == "" {
+		return fmt.Errorf("taint key is required")
+	}
+	if t.Effect != TaintNoSchedule && t.Effect != TaintPreferNoSchedule {
+		return fmt.Errorf("taint %s has unknown effect %q, must be %s or %s",
+			t.Key, t.Effect, TaintNoSchedule, TaintPreferNoSchedule)
+	}
+	return nil
+}
+
+// Validate checks the node's configuration.
+func (n *Node) Validate() error {
+	for _, t := range n.Taints {
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("node %s: %w", n.Name, err)
+		}
+	}
+	return nil
+}
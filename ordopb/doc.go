@@ -0,0 +1,5 @@
+// Package ordopb is the manager's gRPC contract, generated from
+// ordo.proto.
+package ordopb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ordo.proto
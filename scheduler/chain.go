@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// ChainScheduler tries Schedulers in order and places the task with the
+// first one that finds a node, so a strict strategy can fall back to a
+// looser one instead of leaving the task unschedulable.
+type ChainScheduler struct {
+	Name       string
+	Schedulers []Scheduler
+
+	// The placement found by the last SelectCandidateNodes, handed out
+	// by Score and Pick.
+	chosen Scheduler
+	scores map[string]float64
+	pick   *node.Node
+}
+
+// NewChainScheduler returns a chain of schedulers named after its
+// members, e.g. "spread,roundrobin".
+func NewChainScheduler(schedulers ...Scheduler) *ChainScheduler {
+	names := make([]string, len(schedulers))
+	for i, s := range schedulers {
+		names[i] = NameOf(s)
+	}
+	return &ChainScheduler{Name: strings.Join(names, ","), Schedulers: schedulers}
+}
+
+// SelectCandidateNodes runs each scheduler in turn and returns the
+// candidates of the first whose pick succeeds.
+func (c *ChainScheduler) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	c.chosen, c.scores, c.pick = nil, nil, nil
+	for _, s := range c.Schedulers {
+		candidates := s.SelectCandidateNodes(t, nodes)
+		if len(candidates) == 0 {
+			continue
+		}
+		scores := s.Score(t, candidates)
+		if n := s.Pick(scores, candidates); n != nil {
+			c.chosen, c.scores, c.pick = s, scores, n
+			return candidates
+		}
+	}
+	return nil
+}
+
+func (c *ChainScheduler) Score(t task.Task, nodes []*node.Node) map[string]float64 {
+	return c.scores
+}
+
+func (c *ChainScheduler) Pick(scores map[string]float64, candidates []*node.Node) *node.Node {
+	return c.pick
+}
+
+// Chosen returns the name of the scheduler that made the last
+// placement, or "" if none did.
+func (c *ChainScheduler) Chosen() string {
+	if c.chosen == nil {
+		return ""
+	}
+	return NameOf(c.chosen)
+}
+
+// NameOf returns the name s was created with.
+func NameOf(s Scheduler) string {
+	switch v := s.(type) {
+	case *RoundRobin:
+		return v.Name
+	case *SpreadScheduler:
+		return v.Name
+	case *WeightedRandomScheduler:
+		return v.Name
+	case *ChainScheduler:
+		return v.Name
+	}
+	return fmt.Sprintf("%T", s)
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+type RoundRobin struct {
+	Name       string
+	LastWorker int
+}
+
+func (r *RoundRobin) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	return feasibleNodes(t, nodes)
+}
+
+func (r *RoundRobin) Score(t task.Task, nodes []*node.Node) map[string]float64 {
+	scores := make(map[string]float64)
+	var newWorker int
+	if r.LastWorker+1 < len(nodes) {
+		newWorker = r.LastWorker + 1
+		r.LastWorker++
+	} else {
+		newWorker = 0
+		r.LastWorker = 0
+	}
+
+	for idx, n := range nodes {
+		if idx == newWorker {
+			scores[n.Name] = 0.1
+		} else {
+			scores[n.Name] = 1.0
+		}
+		scores[n.Name] += taintPenalty(t, n)
+	}
+	return scores
+}
+
+func (r *RoundRobin) Pick(scores map[string]float64, candidates []*node.Node) *node.Node {
+	return lowestScore(scores, candidates)
+}
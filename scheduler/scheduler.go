@@ -1,7 +1,83 @@
 package scheduler
 
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
 type Scheduler interface {
-	SelectCandidateNodes()
-	Score()
-	Pick()
+	SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node
+	Score(t task.Task, nodes []*node.Node) map[string]float64
+	Pick(scores map[string]float64, candidates []*node.Node) *node.Node
+}
+
+// feasibleNodes returns the nodes that can take the task right now
+// without breaking its topology spread.
+func feasibleNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	var candidates []*node.Node
+	for _, n := range nodes {
+		if Unfit(t, n) != "" {
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	return withinSkew(t, nodes, candidates)
+}
+
+// Unfit says why n can't take t, or returns "" if it can.
+func Unfit(t task.Task, n *node.Node) string {
+	switch {
+	case n.Unreachable:
+		return "node is unreachable"
+	case n.Draining:
+		return "node is draining"
+	case n.Overcommitted:
+		return "node's allocations exceed its capacity"
+	case n.UnderPressure:
+		return "node is under memory pressure"
+	case slices.Contains(t.AvoidNodes, n.Name):
+		return "task failed to start on this node before"
+	case atCapacity(n):
+		return fmt.Sprintf("node is at its limit of %d tasks", n.MaxTasks)
+	case !fits(t, n):
+		return "node lacks the platform, devices or resources the task needs"
+	}
+	if taint := untolerated(t, n, node.TaintNoSchedule); taint != nil {
+		return fmt.Sprintf("node has taint %s=%s:%s the task doesn't tolerate", taint.Key, taint.Value, taint.Effect)
+	}
+	return ""
+}
+
+func fits(t task.Task, n *node.Node) bool {
+	if !task.PlatformMatches(t.Platform, n.Platform) {
+		return false
+	}
+	for _, dm := range t.Devices {
+		if slices.Contains(n.Devices, dm.PathOnHost) && n.DevicesInUse[dm.PathOnHost] {
+			return false
+		}
+	}
+	return int64(n.Memory-n.MemoryAllocated) >= t.ReservedMemory() &&
+		int64(n.Disk-n.DiskAllocated) >= t.Disk
+}
+
+func atCapacity(n *node.Node) bool {
+	return n.MaxTasks > 0 && n.TaskCount >= n.MaxTasks
+}
+
+// lowestScore picks the candidate with the lowest score, preferring
+// earlier candidates on ties.
+func lowestScore(scores map[string]float64, candidates []*node.Node) *node.Node {
+	var bestNode *node.Node
+	var lowest float64
+	for idx, n := range candidates {
+		if idx == 0 || scores[n.Name] < lowest {
+			bestNode = n
+			lowest = scores[n.Name]
+		}
+	}
+	return bestNode
 }
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// SpreadScheduler places replicas sharing an "app" label in the zone that has the
+// fewest of them, and packs them onto the fullest node within that zone.
+// With a single zone, or tasks without an app label, only the packing
+// part applies. Replicas are counted on every node, not just the
+// candidates, so a zone that's full still counts towards the balance.
+type SpreadScheduler struct {
+	Name string
+	// zones holds the replica counts per zone of the task last passed to
+	// SelectCandidateNodes, for the Score call that follows it.
+	zones map[string]int
+}
+
+func (s *SpreadScheduler) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	s.zones = zoneCounts(t, nodes)
+	return feasibleNodes(t, nodes)
+}
+
+func (s *SpreadScheduler) Score(t task.Task, nodes []*node.Node) map[string]float64 {
+	app := t.Labels["app"]
+	zones := s.zones
+	s.zones = nil
+	if zones == nil {
+		zones = zoneCounts(t, nodes)
+	}
+
+	scores := make(map[string]float64)
+	for _, n := range nodes {
+		var free float64
+		if n.Memory > 0 {
+			free = float64(n.Memory-n.MemoryAllocated) / float64(n.Memory)
+		}
+		scores[n.Name] = free + taintPenalty(t, n)
+		if app != "" && len(zones) > 1 {
+			scores[n.Name] += float64(zones[n.Zone])
+		}
+	}
+	return scores
+}
+
+// zoneCounts counts t's replicas in each zone of nodes.
+func zoneCounts(t task.Task, nodes []*node.Node) map[string]int {
+	app := t.Labels["app"]
+	zones := make(map[string]int)
+	for _, n := range nodes {
+		zones[n.Zone] += n.Apps[app]
+	}
+	return zones
+}
+
+func (s *SpreadScheduler) Pick(scores map[string]float64, candidates []*node.Node) *node.Node {
+	return lowestScore(scores, candidates)
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// preferNoSchedulePenalty is added to a node's score for each
+// PreferNoSchedule taint the task doesn't tolerate. It outweighs the
+// other scoring terms so such nodes are only picked as a last resort.
+const preferNoSchedulePenalty = 1000.0
+
+// untolerated returns the first taint with the given effect that t has
+// no toleration for.
+func untolerated(t task.Task, n *node.Node, effect string) *node.Taint {
+	for i, taint := range n.Taints {
+		if taint.Effect != effect || tolerated(t, taint) {
+			continue
+		}
+		return &n.Taints[i]
+	}
+	return nil
+}
+
+func tolerated(t task.Task, taint node.Taint) bool {
+	for _, tol := range t.Tolerations {
+		if tol.Tolerates(taint.Key, taint.Value, taint.Effect) {
+			return true
+		}
+	}
+	return false
+}
+
+func taintPenalty(t task.Task, n *node.Node) float64 {
+	var penalty float64
+	for _, taint := range n.Taints {
+		if taint.Effect == node.TaintPreferNoSchedule && !tolerated(t, taint) {
+			penalty += preferNoSchedulePenalty
+		}
+	}
+	return penalty
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// TopologyDomain is the domain n belongs to for key, and false if n
+// isn't in any.
+func TopologyDomain(n *node.Node, key string) (string, bool) {
+	if v, ok := n.Labels[key]; ok {
+		return v, true
+	}
+	switch key {
+	case "zone":
+		return n.Zone, n.Zone != ""
+	case "host":
+		return n.Name, true
+	}
+	return "", false
+}
+
+// domainCounts counts t's replicas in each topology domain of the nodes
+// that can take tasks at all.
+func domainCounts(t task.Task, nodes []*node.Node) map[string]int {
+	app := t.Labels["app"]
+	counts := make(map[string]int)
+	for _, n := range nodes {
+		if n.Unreachable || n.Draining {
+			continue
+		}
+		if d, ok := TopologyDomain(n, t.TopologySpread.TopologyKey); ok {
+			counts[d] += n.Apps[app]
+		}
+	}
+	return counts
+}
+
+// withinSkew keeps the candidates where placing t leaves its topology
+// spread within MaxSkew.
+func withinSkew(t task.Task, nodes, candidates []*node.Node) []*node.Node {
+	if t.TopologySpread == nil || t.Labels["app"] == "" {
+		return candidates
+	}
+	counts := domainCounts(t, nodes)
+	least := minCount(counts)
+
+	var within []*node.Node
+	for _, n := range candidates {
+		d, ok := TopologyDomain(n, t.TopologySpread.TopologyKey)
+		if ok && counts[d]+1-least <= t.TopologySpread.MaxSkew {
+			within = append(within, n)
+		}
+	}
+	return within
+}
+
+func minCount(counts map[string]int) int {
+	least := -1
+	for _, c := range counts {
+		if least < 0 || c < least {
+			least = c
+		}
+	}
+	return max(least, 0)
+}
+
+// SpreadUnfit explains why t's topology spread rules out every node that
+// could otherwise take it, or returns "" if the spread isn't the reason
+// it can't be placed.
+func SpreadUnfit(t task.Task, nodes []*node.Node) string {
+	if t.TopologySpread == nil || t.Labels["app"] == "" {
+		return ""
+	}
+	var fit []*node.Node
+	for _, n := range nodes {
+		if Unfit(t, n) == "" {
+			fit = append(fit, n)
+		}
+	}
+	if len(fit) == 0 {
+		return ""
+	}
+
+	key := t.TopologySpread.TopologyKey
+	counts := domainCounts(t, nodes)
+	if len(counts) == 0 {
+		return fmt.Sprintf("no node has topology key %s", key)
+	}
+	var domains []string
+	for d, c := range counts {
+		domains = append(domains, fmt.Sprintf("%s=%d", d, c))
+	}
+	slices.Sort(domains)
+	return fmt.Sprintf("placing another %s replica on any node that fits would exceed the max skew of %d over %s (%s)",
+		t.Labels["app"], t.TopologySpread.MaxSkew, key, strings.Join(domains, ", "))
+}
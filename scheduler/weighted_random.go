@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"math/rand"
+
+	"github.com/sajalkmr/ordo/clock"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// WeightedRandomScheduler picks a node at random, weighting each by the
+// memory it has free. Managers running side by side then spread their
+// placements instead of all converging on the same best node.
+type WeightedRandomScheduler struct {
+	Name string
+	// Clock seeds the random source when none was given, so a fake clock
+	// makes placements repeatable too. Nil uses the wall clock.
+	Clock clock.Clock
+	rand  *rand.Rand
+}
+
+// NewWeightedRandomScheduler returns a scheduler drawing from src, or from
+// a source seeded with the time of its first placement if src is nil.
+// Pass a fixed source for repeatable placements.
+func NewWeightedRandomScheduler(name string, src rand.Source) *WeightedRandomScheduler {
+	s := &WeightedRandomScheduler{Name: name}
+	if src != nil {
+		s.rand = rand.New(src)
+	}
+	return s
+}
+
+func (s *WeightedRandomScheduler) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	return feasibleNodes(t, nodes)
+}
+
+// Score returns each node's weight, which unlike the other schedulers is
+// higher for better nodes. Untolerated PreferNoSchedule taints shrink the
+// weight so tainted nodes are still chosen, just rarely.
+func (s *WeightedRandomScheduler) Score(t task.Task, nodes []*node.Node) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, n := range nodes {
+		free := float64(n.Memory - n.MemoryAllocated)
+		if free < 0 {
+			free = 0
+		}
+		scores[n.Name] = free / (1 + taintPenalty(t, n))
+	}
+	return scores
+}
+
+func (s *WeightedRandomScheduler) Pick(scores map[string]float64, candidates []*node.Node) *node.Node {
+	if len(candidates) == 0 {
+		return nil
+	}
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewSource(clock.OrReal(s.Clock).Now().UnixNano()))
+	}
+
+	var total float64
+	for _, n := range candidates {
+		total += scores[n.Name]
+	}
+	// With no free capacity to weigh by, every candidate is equally good.
+	if total <= 0 {
+		return candidates[s.rand.Intn(len(candidates))]
+	}
+
+	r := s.rand.Float64() * total
+	for _, n := range candidates {
+		r -= scores[n.Name]
+		if r < 0 {
+			return n
+		}
+	}
+	return candidates[len(candidates)-1]
+}
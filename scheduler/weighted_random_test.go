@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sajalkmr/ordo/clock"
+	"github.com/sajalkmr/ordo/node"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// weightedNodes returns nodes of 4GiB each with the given GiB allocated.
+func weightedNodes(allocated ...int) []*node.Node {
+	var nodes []*node.Node
+	for i, a := range allocated {
+		nodes = append(nodes, &node.Node{
+			Name:            fmt.Sprintf("node-%d", i),
+			Cores:           4,
+			Memory:          4 << 30,
+			MemoryAllocated: a << 30,
+			Disk:            1 << 40,
+		})
+	}
+	return nodes
+}
+
+// picks places t n times and returns the nodes chosen, in order.
+func picks(s *WeightedRandomScheduler, t task.Task, nodes []*node.Node, n int) []string {
+	var chosen []string
+	for i := 0; i < n; i++ {
+		candidates := s.SelectCandidateNodes(t, nodes)
+		chosen = append(chosen, s.Pick(s.Score(t, candidates), candidates).Name)
+	}
+	return chosen
+}
+
+func TestWeightedRandomRepeatableWithSource(t *testing.T) {
+	nodes := weightedNodes(0, 1, 2, 3)
+	tk := task.Task{Memory: 1 << 20}
+
+	a := picks(NewWeightedRandomScheduler("weightedrandom", rand.NewSource(42)), tk, nodes, 50)
+	b := picks(NewWeightedRandomScheduler("weightedrandom", rand.NewSource(42)), tk, nodes, 50)
+	if fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("same source gave different placements:\n%v\n%v", a, b)
+	}
+}
+
+func TestWeightedRandomRepeatableWithClock(t *testing.T) {
+	nodes := weightedNodes(0, 1, 2, 3)
+	tk := task.Task{Memory: 1 << 20}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newScheduler := func() *WeightedRandomScheduler {
+		s := NewWeightedRandomScheduler("weightedrandom", nil)
+		s.Clock = clock.NewFakeClock(start)
+		return s
+	}
+	a := picks(newScheduler(), tk, nodes, 50)
+	b := picks(newScheduler(), tk, nodes, 50)
+	if fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("same fake clock gave different placements:\n%v\n%v", a, b)
+	}
+}
+
+func TestWeightedRandomProportionalToFreeMemory(t *testing.T) {
+	// 3GiB free against 1GiB free.
+	nodes := weightedNodes(1, 3)
+	tk := task.Task{Memory: 1 << 20}
+	s := NewWeightedRandomScheduler("weightedrandom", rand.NewSource(1))
+
+	const draws = 10000
+	counts := make(map[string]int)
+	for _, name := range picks(s, tk, nodes, draws) {
+		counts[name]++
+	}
+	if got := float64(counts["node-0"]) / draws; got < 0.73 || got > 0.77 {
+		t.Errorf("node-0 was picked %.3f of the time, want about 0.75 (%v)", got, counts)
+	}
+}
+
+func TestWeightedRandomSkipsNodesThatDontFit(t *testing.T) {
+	// node-1 has 512MiB free, too little for the task.
+	nodes := weightedNodes(0, 0)
+	nodes[1].MemoryAllocated = nodes[1].Memory - 512<<20
+	tk := task.Task{Memory: 1 << 30}
+	s := NewWeightedRandomScheduler("weightedrandom", rand.NewSource(7))
+
+	for _, name := range picks(s, tk, nodes, 100) {
+		if name != "node-0" {
+			t.Fatalf("picked %s, which can't fit the task", name)
+		}
+	}
+}
+
+func TestWeightedRandomWithNoFreeMemory(t *testing.T) {
+	nodes := weightedNodes(4, 4)
+	s := NewWeightedRandomScheduler("weightedrandom", rand.NewSource(3))
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[s.Pick(s.Score(task.Task{}, nodes), nodes).Name]++
+	}
+	if counts["node-0"] == 0 || counts["node-1"] == 0 {
+		t.Errorf("with no free memory anywhere, picks should be uniform, got %v", counts)
+	}
+}
@@ -0,0 +1,26 @@
+package task
+
+import "fmt"
+
+const (
+	AlertCPU    = "cpu"
+	AlertMemory = "memory"
+)
+
+// Alert reports a task using more than its alert threshold of a resource
+// for a sustained period. Value and Threshold are percentages of the
+// task's limit.
+type Alert struct {
+	Resource  string
+	Value     float64
+	Threshold float64
+}
+
+// ValidateAlertThreshold checks a percent-of-limit threshold. Zero turns
+// the alert off.
+func ValidateAlertThreshold(pct float64) error {
+	if pct < 0 || pct > 100 {
+		return fmt.Errorf("alert threshold must be between 0 and 100 percent, got %v", pct)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package task
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// DefaultDetachKeys is the key sequence that detaches from an attached
+// container without stopping it, unless Config.DetachKeys says otherwise.
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+// Attach connects to the running container's streams until the container
+// exits, the detach keys are typed or ctx is cancelled. stdin is only
+// forwarded if the task was started with AttachStdin; when it reaches
+// EOF the container's stdin is closed.
+func (d *Docker) Attach(ctx context.Context, id string, stdin io.Reader, stdout, stderr io.Writer) error {
+	detachKeys := d.Config.DetachKeys
+	if detachKeys == "" {
+		detachKeys = DefaultDetachKeys
+	}
+	withStdin := d.Config.AttachStdin && stdin != nil
+
+	attach, err := d.Client.ContainerAttach(ctx, id, types.ContainerAttachOptions{
+		Stream:     true,
+		Stdin:      withStdin,
+		Stdout:     true,
+		Stderr:     true,
+		DetachKeys: detachKeys,
+	})
+	if err != nil {
+		return err
+	}
+	defer attach.Close()
+	stop := context.AfterFunc(ctx, attach.Close)
+	defer stop()
+
+	if withStdin {
+		go func() {
+			io.Copy(attach.Conn, stdin)
+			attach.CloseWrite()
+		}()
+	}
+
+	_, err = stdcopy.StdCopy(stdout, stderr, attach.Reader)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
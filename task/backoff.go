@@ -0,0 +1,76 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRestartBackoffInitial = 10 * time.Second
+	defaultRestartBackoffMax     = 5 * time.Minute
+	defaultRestartMaxAttempts    = 5
+)
+
+// RestartBackoff makes the worker restart a failed on-failure container
+// itself, waiting Initial, then twice as long after each further crash up
+// to Max. After MaxAttempts consecutive crashes the task is Failed. Zero
+// fields use the defaults: 10s, 5m and 5 attempts.
+type RestartBackoff struct {
+	Initial     time.Duration
+	Max         time.Duration
+	MaxAttempts int
+}
+
+func (b *RestartBackoff) Validate() error {
+	if b.Initial < 0 || b.Max < 0 || b.MaxAttempts < 0 {
+		return fmt.Errorf("restart backoff values must not be negative")
+	}
+	if b.Max > 0 && b.Initial > b.Max {
+		return fmt.Errorf("restart backoff initial delay %v exceeds max %v", b.Initial, b.Max)
+	}
+	return nil
+}
+
+// Delay is how long to wait before restart attempt n, counting from 1.
+func (b *RestartBackoff) Delay(n int) time.Duration {
+	initial, max := b.Initial, b.Max
+	if initial == 0 {
+		initial = defaultRestartBackoffInitial
+	}
+	if max == 0 {
+		max = defaultRestartBackoffMax
+	}
+	delay := initial << (n - 1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+	return delay
+}
+
+func (b *RestartBackoff) Attempts() int {
+	if b.MaxAttempts > 0 {
+		return b.MaxAttempts
+	}
+	return defaultRestartMaxAttempts
+}
+
+// WorkerRestarts reports whether the worker, rather than Docker, restarts
+// the task's container when it fails.
+func (t Task) WorkerRestarts() bool {
+	return t.RestartBackoff != nil && isOnFailure(t.RestartPolicy)
+}
+
+// dockerRestartPolicy is the policy handed to Docker. It's "no" when the
+// worker applies a RestartBackoff itself, since Docker restarts
+// immediately with no way to configure a delay.
+func (c *Config) dockerRestartPolicy() string {
+	if c.RestartBackoff != nil && isOnFailure(c.RestartPolicy) {
+		return "no"
+	}
+	return c.RestartPolicy
+}
+
+func isOnFailure(policy string) bool {
+	return policy == "on-failure" || strings.HasPrefix(policy, "on-failure:")
+}
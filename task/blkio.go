@@ -0,0 +1,65 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+)
+
+type ThrottleDevice struct {
+	Path string
+	// Rate is in bytes per second.
+	Rate uint64
+}
+
+func (td ThrottleDevice) Validate() error {
+	fi, err := os.Stat(td.Path)
+	if err != nil {
+		return fmt.Errorf("throttle device %s: %w", td.Path, err)
+	}
+	if fi.Mode()&os.ModeDevice == 0 || fi.Mode()&os.ModeCharDevice != 0 {
+		return fmt.Errorf("throttle device %s is not a block device", td.Path)
+	}
+	return nil
+}
+
+func (c *Config) validateBlkio() error {
+	if c.BlkioWeight != 0 && (c.BlkioWeight < 10 || c.BlkioWeight > 1000) {
+		return fmt.Errorf("blkio weight must be between 10 and 1000, got %d", c.BlkioWeight)
+	}
+	for _, td := range append(c.DeviceReadBps, c.DeviceWriteBps...) {
+		if err := td.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) applyBlkio(r *container.Resources) {
+	r.BlkioWeight = c.BlkioWeight
+	for _, td := range c.DeviceReadBps {
+		r.BlkioDeviceReadBps = append(r.BlkioDeviceReadBps, &blkiodev.ThrottleDevice{Path: td.Path, Rate: td.Rate})
+	}
+	for _, td := range c.DeviceWriteBps {
+		r.BlkioDeviceWriteBps = append(r.BlkioDeviceWriteBps, &blkiodev.ThrottleDevice{Path: td.Path, Rate: td.Rate})
+	}
+}
+
+// blkioSummary describes the block IO limits applied to the container,
+// or returns an empty string when there are none.
+func (c *Config) blkioSummary() string {
+	var limits []string
+	if c.BlkioWeight != 0 {
+		limits = append(limits, fmt.Sprintf("blkio-weight=%d", c.BlkioWeight))
+	}
+	for _, td := range c.DeviceReadBps {
+		limits = append(limits, fmt.Sprintf("read-bps=%s:%d", td.Path, td.Rate))
+	}
+	for _, td := range c.DeviceWriteBps {
+		limits = append(limits, fmt.Sprintf("write-bps=%s:%d", td.Path, td.Rate))
+	}
+	return strings.Join(limits, " ")
+}
@@ -0,0 +1,199 @@
+package task
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// BuildSpec builds a task's image on the worker instead of pulling it.
+type BuildSpec struct {
+	// Context is the directory on the worker sent to the daemon as the
+	// build context. Like a mount source, it's resolved against the
+	// worker's workspace and must be inside it.
+	Context string
+	// Dockerfile is relative to Context and defaults to "Dockerfile".
+	Dockerfile string
+	Args       map[string]string
+}
+
+func (b *BuildSpec) Validate() error {
+	if b.Context == "" {
+		return errors.New("build context is required")
+	}
+	if filepath.IsAbs(b.Dockerfile) || strings.HasPrefix(filepath.Clean(b.Dockerfile), "..") {
+		return fmt.Errorf("dockerfile %s must be inside the build context", b.Dockerfile)
+	}
+	return nil
+}
+
+// validateBuild rejects an image that names a registry or digest
+// alongside a build: the built image is only tagged locally, so such a
+// reference would silently shadow the one in the registry.
+func (c *Config) validateBuild() error {
+	if c.Build == nil {
+		return nil
+	}
+	if err := c.Build.Validate(); err != nil {
+		return err
+	}
+	if c.Image == "" {
+		return nil
+	}
+	if host, _ := splitImage(c.Image); host != defaultRegistry || strings.HasPrefix(c.Image, defaultRegistry+"/") {
+		return fmt.Errorf("image %s names a registry; a built image is only tagged locally", c.Image)
+	}
+	if strings.Contains(c.Image, "@") {
+		return fmt.Errorf("image %s pins a digest and can't be built", c.Image)
+	}
+	return nil
+}
+
+// buildTag is the tag a built image gets: the task's image if it has
+// one, otherwise a local name derived from the task.
+func (c *Config) buildTag() string {
+	if c.Image != "" {
+		return c.Image
+	}
+	name := strings.ToLower(c.hostname())
+	if name == "" {
+		return "ordo-build:latest"
+	}
+	return "ordo-build/" + name + ":latest"
+}
+
+// Build builds the task's image from Config.Build and tags it, streaming
+// the daemon's output like a pull. Config.Image is set to the tag, and
+// Run uses the image without pulling it.
+func (d *Docker) Build() DockerResult {
+	ctx := context.Background()
+	b := d.Config.Build
+	tag := d.Config.buildTag()
+
+	dir, err := resolveInBase(d.Config.MountBase, b.Context, "build context", ErrBuildContextOutsideBase)
+	if err != nil {
+		log.Printf("Error resolving build context %s: %v\n", b.Context, err)
+		return DockerResult{Action: "build", Error: err}
+	}
+	buildCtx, err := tarContext(dir, b.Dockerfile)
+	if err != nil {
+		log.Printf("Error reading build context %s: %v\n", b.Context, err)
+		return DockerResult{Action: "build", Error: err}
+	}
+	defer buildCtx.Close()
+
+	args := make(map[string]*string, len(b.Args))
+	for k, v := range b.Args {
+		v := v
+		args[k] = &v
+	}
+
+	resp, err := d.Client.ImageBuild(ctx, buildCtx, types.ImageBuildOptions{
+		Tags:       []string{tag},
+		Dockerfile: b.Dockerfile,
+		BuildArgs:  args,
+		Platform:   d.Config.Platform,
+		Remove:     true,
+	})
+	if err != nil {
+		log.Printf("Error building image %s: %v\n", tag, err)
+		return DockerResult{Action: "build", Error: err}
+	}
+	defer resp.Body.Close()
+
+	// Like pulls, build failures are reported inside the progress stream.
+	err = jsonmessage.DisplayJSONMessagesStream(resp.Body, os.Stdout, os.Stdout.Fd(), false, nil)
+	if err != nil {
+		log.Printf("Error building image %s: %v\n", tag, err)
+		return DockerResult{Action: "build", Error: err}
+	}
+
+	d.Config.Image = tag
+	d.pulled = true
+	return DockerResult{Action: "build", Result: tag}
+}
+
+// tarContext streams dir as a tar archive with paths relative to dir,
+// leaving out whatever dir/.dockerignore excludes. The Dockerfile and the
+// .dockerignore itself are always sent, since the daemon needs them.
+func tarContext(dir, dockerfile string) (io.ReadCloser, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("build context %s is not a directory", dir)
+	}
+	ignore, err := readDockerignore(dir)
+	if err != nil {
+		return nil, err
+	}
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	dockerfile = filepath.ToSlash(filepath.Clean(dockerfile))
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil || rel == "." {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if rel != dockerfile && rel != ".dockerignore" && ignore.excluded(rel) {
+				// A negated pattern may bring back something inside an
+				// excluded directory, so only skip it when there are none.
+				if fi.IsDir() && !ignore.negations {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			link := ""
+			if fi.Mode()&os.ModeSymlink != 0 {
+				link, err = os.Readlink(path)
+				if err != nil {
+					return err
+				}
+			}
+			hdr, err := tar.FileInfoHeader(fi, link)
+			if err != nil {
+				return err
+			}
+			hdr.Name = rel
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
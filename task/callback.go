@@ -0,0 +1,14 @@
+package task
+
+import (
+	"fmt"
+	"net/url"
+)
+
+func validateCallbackURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("callback URL %q must be an absolute http or https URL", s)
+	}
+	return nil
+}
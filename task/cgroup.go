@@ -0,0 +1,39 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// cgroupParentHelp explains the two forms Docker accepts, which depend on
+// the daemon's cgroup driver rather than on anything ordo can see.
+const cgroupParentHelp = "with the systemd cgroup driver, the default on cgroup v2 hosts, " +
+	"it must be a slice such as ordo.slice or ordo-tasks.slice (nested under ordo.slice); " +
+	"with the cgroupfs driver it's a path such as /ordo/tasks"
+
+var (
+	sliceName      = regexp.MustCompile(`^[a-zA-Z0-9:_.\\]+(-[a-zA-Z0-9:_.\\]+)*\.slice$`)
+	cgroupPathPart = regexp.MustCompile(`^[a-zA-Z0-9:_.\-]+$`)
+)
+
+// validateCgroupParent checks the form of a cgroup parent. Whether it
+// suits the daemon's cgroup driver is only known when the container is
+// created.
+func validateCgroupParent(parent string) error {
+	if parent == "" {
+		return nil
+	}
+	if strings.HasSuffix(parent, ".slice") {
+		if !sliceName.MatchString(parent) {
+			return fmt.Errorf("invalid cgroup parent slice %q: %s", parent, cgroupParentHelp)
+		}
+		return nil
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(parent, "/"), "/") {
+		if part == "." || part == ".." || !cgroupPathPart.MatchString(part) {
+			return fmt.Errorf("invalid cgroup parent %q: %s", parent, cgroupParentHelp)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// namePattern is the character set Docker accepts for container names.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// hostnameLabel is one RFC 1123 label: up to 63 letters, digits and
+// dashes, not starting or ending with a dash.
+var hostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+var hostnameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q: only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	return nil
+}
+
+// ValidateHostname checks name against RFC 1123, which hostnames and
+// domain names must both follow.
+func ValidateHostname(name string) error {
+	if len(name) > 253 {
+		return fmt.Errorf("invalid hostname %q: longer than 253 characters", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !hostnameLabel.MatchString(label) {
+			return fmt.Errorf("invalid hostname %q: labels must be 1-63 letters, digits or dashes "+
+				"and can't start or end with a dash", name)
+		}
+	}
+	return nil
+}
+
+// hostname returns the configured hostname or one derived from the task
+// name, so containers don't get their short container ID.
+func (c *Config) hostname() string {
+	if c.Hostname != "" {
+		return c.Hostname
+	}
+	h := hostnameInvalid.ReplaceAllString(strings.ToLower(c.Name), "-")
+	if len(h) > 63 {
+		h = h[:63]
+	}
+	return strings.Trim(h, "-")
+}
+
+// Validate checks the config for values Docker would reject or that can't
+// work together, so the error surfaces before any container is created.
+func (c *Config) Validate() error {
+	if c.Cpu > 0 && (c.CpuQuota != 0 || c.CpuPeriod != 0) {
+		return errors.New("cpu and cpu quota/period are mutually exclusive: " +
+			"Docker turns cpu into a quota over a fixed 100ms period " +
+			"(cpu.max on cgroup v2, cpu.cfs_quota_us/cpu.cfs_period_us on v1)")
+	}
+	if c.CpuPeriod != 0 && (c.CpuPeriod < 1000 || c.CpuPeriod > 1000000) {
+		return fmt.Errorf("cpu period must be between 1000 and 1000000 microseconds, got %d "+
+			"(the kernel range for cpu.max on cgroup v2 and cpu.cfs_period_us on v1)", c.CpuPeriod)
+	}
+	if c.CpuQuota != 0 && c.CpuQuota != -1 && c.CpuQuota < 1000 {
+		return fmt.Errorf("cpu quota must be at least 1000 microseconds or -1 for unlimited, got %d "+
+			"(cgroup v2 writes it as the first field of cpu.max, v1 as cpu.cfs_quota_us)", c.CpuQuota)
+	}
+	if c.Hostname != "" {
+		if err := ValidateHostname(c.Hostname); err != nil {
+			return err
+		}
+	}
+	if c.Domainname != "" {
+		if err := ValidateHostname(c.Domainname); err != nil {
+			return fmt.Errorf("domainname: %w", err)
+		}
+	}
+	if c.MemoryReservation < 0 {
+		return fmt.Errorf("memory reservation must be positive, got %d", c.MemoryReservation)
+	}
+	if c.Memory > 0 && c.MemoryReservation > c.Memory {
+		return fmt.Errorf("memory reservation %d exceeds memory limit %d", c.MemoryReservation, c.Memory)
+	}
+	if c.MemorySwap != 0 {
+		if err := c.validateMemorySwap(); err != nil {
+			return err
+		}
+	}
+	if c.DeregisterDelay < 0 {
+		return fmt.Errorf("deregister delay must not be negative, got %v", c.DeregisterDelay)
+	}
+	if c.PullTimeout < 0 {
+		return fmt.Errorf("pull timeout must not be negative, got %v", c.PullTimeout)
+	}
+	if c.MaxLogBytes < 0 {
+		return fmt.Errorf("max log bytes must be positive, got %d", c.MaxLogBytes)
+	}
+	if c.ShmSize < 0 {
+		return fmt.Errorf("shm size must not be negative, got %d", c.ShmSize)
+	}
+	if c.Memory > 0 && c.ShmSize > c.Memory {
+		return fmt.Errorf("shm size %d exceeds memory limit %d", c.ShmSize, c.Memory)
+	}
+	for _, dm := range c.Devices {
+		if err := dm.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.validateBlkio(); err != nil {
+		return err
+	}
+	if err := validateSysctls(c.Sysctls); err != nil {
+		return err
+	}
+	if err := validateSecurityOpt(c.SecurityOpt); err != nil {
+		return err
+	}
+	if err := validateUsernsMode(c.UsernsMode); err != nil {
+		return err
+	}
+	if err := validateCgroupParent(c.CgroupParent); err != nil {
+		return err
+	}
+	if c.CallbackURL != "" {
+		if err := validateCallbackURL(c.CallbackURL); err != nil {
+			return err
+		}
+	}
+	if err := c.validateBuild(); err != nil {
+		return err
+	}
+	if err := ValidateRestartPolicy(c.RestartPolicy); err != nil {
+		return err
+	}
+	if err := c.validateInitContainers(); err != nil {
+		return err
+	}
+	if c.RestartBackoff != nil {
+		if err := c.RestartBackoff.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, m := range c.Mounts {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, tol := range c.Tolerations {
+		if err := tol.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.LivenessProbe != nil {
+		if err := c.LivenessProbe.Validate(); err != nil {
+			return fmt.Errorf("liveness probe: %w", err)
+		}
+	}
+	if c.ReadinessProbe != nil {
+		if err := c.ReadinessProbe.Validate(); err != nil {
+			return fmt.Errorf("readiness probe: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateMemorySwap applies Docker's rules for a memory swap limit up
+// front, so a bad combination fails before the container is created.
+func (c *Config) validateMemorySwap() error {
+	switch {
+	case c.MemorySwap < -1:
+		return fmt.Errorf("memory swap must be at least the memory limit, or -1 for unlimited swap, got %d", c.MemorySwap)
+	case c.Memory <= 0:
+		return fmt.Errorf("memory swap %d requires a memory limit", c.MemorySwap)
+	case c.MemorySwap != -1 && c.MemorySwap < c.Memory:
+		return fmt.Errorf("memory swap %d is below memory limit %d; set it equal to the limit to disable swap", c.MemorySwap, c.Memory)
+	}
+	return nil
+}
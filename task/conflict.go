@@ -0,0 +1,68 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/uuid"
+)
+
+// ManagedLabel marks containers created by ordo, so leftovers from an
+// unclean restart can be told apart from containers someone else owns.
+const ManagedLabel = "ordo.managed"
+
+// TaskIDLabel holds the ID of the task a container was created for.
+const TaskIDLabel = "ordo.task-id"
+
+// containerLabels is the config's labels plus ManagedLabel and, for a
+// task's own container, TaskIDLabel.
+func (c *Config) containerLabels() map[string]string {
+	labels := make(map[string]string, len(c.Labels)+2)
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	labels[ManagedLabel] = "true"
+	if c.TaskID != uuid.Nil {
+		labels[TaskIDLabel] = c.TaskID.String()
+	}
+	return labels
+}
+
+// resolveNameConflict handles an existing container with the config's
+// name. A stopped container of ours is removed so the create can be
+// retried; a running, healthy one created for this same task, as told by
+// TaskIDLabel, is adopted and its ID returned. Anything else is an error.
+func (d *Docker) resolveNameConflict(ctx context.Context) (string, error) {
+	name := d.Config.Name
+	existing, err := d.Client.ContainerInspect(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("inspecting existing container %s: %w", name, err)
+	}
+	if existing.Config == nil || existing.Config.Labels[ManagedLabel] != "true" {
+		return "", fmt.Errorf("container name %s is used by a container not managed by ordo", name)
+	}
+
+	if !existing.State.Running {
+		log.Printf("Removing stopped leftover container %s (%s) before recreating it\n", name, existing.ID)
+		err := d.Client.ContainerRemove(ctx, existing.ID, types.ContainerRemoveOptions{RemoveVolumes: true, Force: true})
+		if err != nil {
+			return "", fmt.Errorf("removing leftover container %s: %w", name, err)
+		}
+		return "", nil
+	}
+
+	owner := existing.Config.Labels[TaskIDLabel]
+	if d.Config.TaskID == uuid.Nil || owner != d.Config.TaskID.String() {
+		return "", fmt.Errorf("container %s is already running for another task", name)
+	}
+	if existing.Config.Image != d.Config.Image {
+		return "", fmt.Errorf("container %s is already running image %s, not %s", name, existing.Config.Image, d.Config.Image)
+	}
+	if existing.State.Health != nil && existing.State.Health.Status != types.Healthy {
+		return "", fmt.Errorf("container %s is already running but is %s", name, existing.State.Health.Status)
+	}
+	log.Printf("Adopting running container %s (%s) instead of creating a new one\n", name, existing.ID)
+	return existing.ID, nil
+}
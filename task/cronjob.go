@@ -0,0 +1,23 @@
+package task
+
+import "fmt"
+
+// ConcurrencyPolicy values say what a recurring task does when its
+// previous run is still going at the next scheduled time.
+const (
+	// ConcurrencyAllow starts the new run alongside the old one.
+	ConcurrencyAllow = "Allow"
+	// ConcurrencyForbid skips the new run.
+	ConcurrencyForbid = "Forbid"
+	// ConcurrencyReplace stops the old run and starts the new one.
+	ConcurrencyReplace = "Replace"
+)
+
+func ValidateConcurrencyPolicy(policy string) error {
+	switch policy {
+	case "", ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace:
+		return nil
+	}
+	return fmt.Errorf("concurrency policy must be %s, %s or %s, got %q",
+		ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace, policy)
+}
@@ -0,0 +1,33 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type DeviceMapping struct {
+	PathOnHost        string
+	PathInContainer   string
+	CgroupPermissions string
+}
+
+// Validate checks that the device exists on this node and that its
+// permissions are a combination of r, w and m.
+func (dm DeviceMapping) Validate() error {
+	if dm.PathOnHost == "" {
+		return errors.New("device mapping needs a host path")
+	}
+	if _, err := os.Stat(dm.PathOnHost); err != nil {
+		return fmt.Errorf("device %s is not present on this node: %w", dm.PathOnHost, err)
+	}
+
+	for i, p := range dm.CgroupPermissions {
+		if !strings.ContainsRune("rwm", p) || strings.ContainsRune(dm.CgroupPermissions[:i], p) {
+			return fmt.Errorf("invalid permissions %q for device %s: must be a subset of \"rwm\"",
+				dm.CgroupPermissions, dm.PathOnHost)
+		}
+	}
+	return nil
+}
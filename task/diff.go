@@ -0,0 +1,33 @@
+package task
+
+import (
+	"context"
+	"fmt"
+)
+
+// ContainerChange is a path that differs between a container's writable
+// layer and its image.
+type ContainerChange struct {
+	Kind string
+	Path string
+}
+
+// changeKinds names Docker's change kinds, which it reports as 0, 1 and 2.
+var changeKinds = []string{"modified", "added", "deleted"}
+
+func (d *Docker) Diff(id string) ([]ContainerChange, error) {
+	items, err := d.Client.ContainerDiff(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]ContainerChange, 0, len(items))
+	for _, item := range items {
+		kind := fmt.Sprintf("unknown (%d)", item.Kind)
+		if int(item.Kind) < len(changeKinds) {
+			kind = changeKinds[item.Kind]
+		}
+		changes = append(changes, ContainerChange{Kind: kind, Path: item.Path})
+	}
+	return changes, nil
+}
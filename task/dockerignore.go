@@ -0,0 +1,132 @@
+package task
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is one line of a .dockerignore file.
+type ignorePattern struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// dockerignore holds the patterns from a build context's .dockerignore.
+// As with the Docker CLI, the last pattern matching a path decides
+// whether it's excluded, and a pattern matching a directory excludes
+// everything under it.
+type dockerignore struct {
+	patterns []ignorePattern
+	// negations is set when some pattern starts with "!", in which case
+	// an excluded directory still has to be walked.
+	negations bool
+}
+
+// readDockerignore loads dir/.dockerignore. A missing file excludes
+// nothing.
+func readDockerignore(dir string) (*dockerignore, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if errors.Is(err, os.ErrNotExist) {
+		return &dockerignore{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	di := &dockerignore{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		if negate {
+			line = strings.TrimSpace(line[1:])
+		}
+		line = strings.TrimPrefix(path.Clean(filepath.ToSlash(line)), "/")
+		re, err := compileIgnorePattern(line)
+		if err != nil {
+			return nil, fmt.Errorf(".dockerignore pattern %q: %w", line, err)
+		}
+		di.patterns = append(di.patterns, ignorePattern{re: re, negate: negate})
+		di.negations = di.negations || negate
+	}
+	return di, s.Err()
+}
+
+// excluded reports whether the slash-separated path rel, relative to the
+// context, is left out of the build context.
+func (di *dockerignore) excluded(rel string) bool {
+	excluded := false
+	for _, p := range di.patterns {
+		if p.matches(rel) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// matches reports whether the pattern matches rel or one of its parent
+// directories.
+func (p ignorePattern) matches(rel string) bool {
+	for dir := rel; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if p.re.MatchString(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileIgnorePattern turns a .dockerignore pattern into a regexp. "*"
+// and "?" don't cross directories, "**" matches any number of them and
+// "[...]" is a character class.
+func compileIgnorePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				return nil, errors.New("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,100 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDockerignoreExcluded(t *testing.T) {
+	tests := []struct {
+		name     string
+		ignore   string
+		path     string
+		excluded bool
+	}{
+		{"no patterns", "", "main.go", false},
+		{"comments and blank lines", "# *.go\n\n", "main.go", false},
+		{"exact file", "secret.env", "secret.env", true},
+		{"other file", "secret.env", "main.go", false},
+		{"star at the root", "*.log", "build.log", true},
+		{"star doesn't cross directories", "*.log", "logs/build.log", false},
+		{"double star in any directory", "**/*.log", "logs/2024/build.log", true},
+		{"double star at the root", "**/*.log", "build.log", true},
+		{"trailing double star", "logs/**", "logs/2024/build.log", true},
+		{"directory excludes its contents", "node_modules", "node_modules/left-pad/index.js", true},
+		{"trailing slash", "build/", "build/out/app", true},
+		{"leading slash", "/vendor", "vendor/lib.go", true},
+		{"question mark", "?.txt", "a.txt", true},
+		{"question mark is one character", "?.txt", "ab.txt", false},
+		{"character class", "[ab].txt", "b.txt", true},
+		{"character class miss", "[ab].txt", "c.txt", false},
+		{"negated character class", "[!ab].txt", "c.txt", true},
+		{"escaped star", `\*.txt`, "*.txt", true},
+		{"escaped star is literal", `\*.txt`, "a.txt", false},
+		{"negation keeps a file", "*.md\n!README.md", "README.md", false},
+		{"negation leaves others excluded", "*.md\n!README.md", "CHANGES.md", true},
+		{"last match wins", "!README.md\n*.md", "README.md", true},
+		{"negation inside an excluded directory", "docs\n!docs/keep.txt", "docs/keep.txt", false},
+		{"sibling of a negated file", "docs\n!docs/keep.txt", "docs/drop.txt", true},
+		{"negation with spaces", "*.md\n!  README.md", "README.md", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(tt.ignore), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			di, err := readDockerignore(dir)
+			if err != nil {
+				t.Fatalf("readDockerignore: %v", err)
+			}
+			if got := di.excluded(tt.path); got != tt.excluded {
+				t.Errorf("excluded(%q) with %q = %v, want %v", tt.path, tt.ignore, got, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestDockerignoreNegations(t *testing.T) {
+	tests := []struct {
+		ignore    string
+		negations bool
+	}{
+		{"*.log\nbuild", false},
+		{"*.md\n!README.md", true},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(tt.ignore), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		di, err := readDockerignore(dir)
+		if err != nil {
+			t.Fatalf("readDockerignore(%q): %v", tt.ignore, err)
+		}
+		if di.negations != tt.negations {
+			t.Errorf("negations for %q = %v, want %v", tt.ignore, di.negations, tt.negations)
+		}
+	}
+}
+
+func TestDockerignoreMissing(t *testing.T) {
+	di, err := readDockerignore(t.TempDir())
+	if err != nil {
+		t.Fatalf("readDockerignore: %v", err)
+	}
+	if di.excluded("main.go") {
+		t.Error("a missing .dockerignore excluded main.go")
+	}
+}
+
+func TestDockerignoreInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("[abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDockerignore(dir); err == nil {
+		t.Error("readDockerignore accepted an unterminated character class")
+	}
+}
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const envPrefix = "TASK_ENV_"
+
+// ConfigFromEnv builds a Config from TASK_* environment variables for
+// running a single task without a manager:
+//
+//	TASK_IMAGE           image to run (required)
+//	TASK_NAME            container name
+//	TASK_CMD             command, comma separated or split on whitespace
+//	TASK_CPU             CPUs as a float, e.g. 0.5
+//	TASK_MEMORY          memory limit in bytes
+//	TASK_DISK            disk in bytes
+//	TASK_SHM_SIZE        /dev/shm size in bytes
+//	TASK_PLATFORM        image platform, e.g. linux/arm64
+//	TASK_RESTART_POLICY  Docker restart policy
+//	TASK_ENV_<NAME>      passed to the container as NAME
+//	TASK_SECRETS_DIR     directory of files for ${secret:name} references
+func ConfigFromEnv() (Config, error) {
+	c := Config{
+		Name:          os.Getenv("TASK_NAME"),
+		Image:         os.Getenv("TASK_IMAGE"),
+		Platform:      os.Getenv("TASK_PLATFORM"),
+		RestartPolicy: os.Getenv("TASK_RESTART_POLICY"),
+		Cmd:           splitCmd(os.Getenv("TASK_CMD")),
+	}
+	if dir := os.Getenv("TASK_SECRETS_DIR"); dir != "" {
+		c.Secrets = FileSecrets{Dir: dir}
+	}
+	if c.Image == "" {
+		return Config{}, errors.New("TASK_IMAGE is required")
+	}
+
+	var err error
+	if v := os.Getenv("TASK_CPU"); v != "" {
+		c.Cpu, err = strconv.ParseFloat(v, 64)
+		if err != nil || c.Cpu < 0 {
+			return Config{}, fmt.Errorf("invalid TASK_CPU %q", v)
+		}
+	}
+	for name, dst := range map[string]*int64{
+		"TASK_MEMORY":   &c.Memory,
+		"TASK_DISK":     &c.Disk,
+		"TASK_SHM_SIZE": &c.ShmSize,
+	} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		*dst, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || *dst < 0 {
+			return Config{}, fmt.Errorf("invalid %s %q", name, v)
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envPrefix) {
+			continue
+		}
+		kv = strings.TrimPrefix(kv, envPrefix)
+		if strings.HasPrefix(kv, "=") {
+			return Config{}, fmt.Errorf("%s variable has no name", envPrefix)
+		}
+		c.Env = append(c.Env, kv)
+	}
+
+	if c.Name != "" {
+		if err := ValidateName(c.Name); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+func splitCmd(s string) []string {
+	if strings.Contains(s, ",") {
+		var cmd []string
+		for _, part := range strings.Split(s, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				cmd = append(cmd, part)
+			}
+		}
+		return cmd
+	}
+	return strings.Fields(s)
+}
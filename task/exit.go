@@ -0,0 +1,28 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+)
+
+// OOMKilledReason is the StatusReason of tasks whose container was
+// killed for exceeding its memory limit.
+const OOMKilledReason = "OOMKilled"
+
+var ErrOOMKilled = errors.New("container was killed for exceeding its memory limit")
+
+// ContainerExit reports how a stopped container exited. It returns
+// ErrOOMKilled when the kernel killed the container for using too much
+// memory, which otherwise only shows up as exit code 137.
+func ContainerExit(ctr *types.ContainerJSON) (int, error) {
+	code := ctr.State.ExitCode
+	if ctr.State.OOMKilled {
+		return code, ErrOOMKilled
+	}
+	if code != 0 {
+		return code, fmt.Errorf("container exited with code %d", code)
+	}
+	return 0, nil
+}
@@ -0,0 +1,137 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+)
+
+type ImagePruneResult struct {
+	ImagesDeleted  []string
+	SpaceReclaimed uint64
+}
+
+// PruneImages removes dangling images. The daemon never prunes an image
+// a container still uses.
+func (d *Docker) PruneImages() (ImagePruneResult, error) {
+	report, err := d.Client.ImagesPrune(context.Background(), filters.NewArgs(filters.Arg("dangling", "true")))
+	if err != nil {
+		return ImagePruneResult{}, err
+	}
+
+	var result ImagePruneResult
+	for _, item := range report.ImagesDeleted {
+		if item.Deleted != "" {
+			result.ImagesDeleted = append(result.ImagesDeleted, item.Deleted)
+		}
+	}
+	result.SpaceReclaimed = report.SpaceReclaimed
+	return result, nil
+}
+
+// RemoveUnusedImages removes every image that no container uses and that
+// isn't one of the keep references, such as images of tasks that haven't
+// started yet. Removal isn't forced, so an image a container starts using
+// after the listing is skipped rather than pulled out from under it. The
+// space reclaimed is the drop in the daemon's layer usage.
+func (d *Docker) RemoveUnusedImages(keep []string) (ImagePruneResult, error) {
+	ctx := context.Background()
+	before, err := d.Client.DiskUsage(ctx)
+	if err != nil {
+		return ImagePruneResult{}, err
+	}
+
+	used := make(map[string]bool)
+	containers, err := d.Client.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return ImagePruneResult{}, err
+	}
+	for _, c := range containers {
+		used[c.ImageID] = true
+	}
+	for _, ref := range keep {
+		img, _, err := d.Client.ImageInspectWithRaw(ctx, ref)
+		if errdefs.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return ImagePruneResult{}, err
+		}
+		used[img.ID] = true
+	}
+
+	images, err := d.Client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return ImagePruneResult{}, err
+	}
+
+	var result ImagePruneResult
+	for _, img := range images {
+		if used[img.ID] {
+			continue
+		}
+		// An image with several tags can only be removed unforced one
+		// tag at a time.
+		var refs []string
+		for _, tag := range img.RepoTags {
+			if tag != "<none>:<none>" {
+				refs = append(refs, tag)
+			}
+		}
+		if len(refs) == 0 {
+			refs = []string{img.ID}
+		}
+		for _, ref := range refs {
+			items, err := d.Client.ImageRemove(ctx, ref, types.ImageRemoveOptions{PruneChildren: true})
+			if errdefs.IsConflict(err) {
+				log.Printf("Keeping image %s, it's in use: %v\n", ref, err)
+				break
+			}
+			if err != nil && !errdefs.IsNotFound(err) {
+				return result, err
+			}
+			for _, item := range items {
+				if item.Deleted != "" {
+					result.ImagesDeleted = append(result.ImagesDeleted, item.Deleted)
+				}
+			}
+		}
+	}
+
+	after, err := d.Client.DiskUsage(ctx)
+	if err == nil && after.LayersSize < before.LayersSize {
+		result.SpaceReclaimed = uint64(before.LayersSize - after.LayersSize)
+	}
+	return result, nil
+}
+
+// ErrImageInUse is returned by RemoveImage when the daemon refuses to
+// remove an image because a container or another tag still needs it.
+var ErrImageInUse = errors.New("image is in use")
+
+// RemoveImage untags image and deletes its layers if nothing else
+// references them. Unlike RemoveUnusedImages it never forces removal, so
+// an image a container still uses, even a stopped one, is left alone and
+// ErrImageInUse returned.
+func (d *Docker) RemoveImage(image string) (ImagePruneResult, error) {
+	items, err := d.Client.ImageRemove(context.Background(), image, types.ImageRemoveOptions{PruneChildren: true})
+	if errdefs.IsConflict(err) {
+		return ImagePruneResult{}, fmt.Errorf("%w: %s: %v", ErrImageInUse, image, err)
+	}
+	if err != nil && !errdefs.IsNotFound(err) {
+		return ImagePruneResult{}, err
+	}
+
+	var result ImagePruneResult
+	for _, item := range items {
+		if item.Deleted != "" {
+			result.ImagesDeleted = append(result.ImagesDeleted, item.Deleted)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// InitStep records how one init container went.
+type InitStep struct {
+	Name     string
+	Image    string
+	ExitCode int
+	Duration time.Duration
+	Error    string `json:",omitempty"`
+}
+
+func (c *Config) validateInitContainers() error {
+	for i, ic := range c.InitContainers {
+		if ic.Image == "" && ic.Build == nil {
+			return fmt.Errorf("init container %d has no image", i)
+		}
+		if len(ic.InitContainers) > 0 {
+			return fmt.Errorf("init container %d can't have init containers of its own", i)
+		}
+		if err := ic.Validate(); err != nil {
+			return fmt.Errorf("init container %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// runInitContainers runs each init container to completion in order,
+// stopping at the first that doesn't exit 0. They run on the same Docker
+// host as the main container, which is what lets them share its mounts.
+func (d *Docker) runInitContainers(ctx context.Context) ([]InitStep, error) {
+	var steps []InitStep
+	for i, ic := range d.Config.InitContainers {
+		c := ic
+		if c.Name == "" && d.Config.Name != "" {
+			c.Name = fmt.Sprintf("%s-init-%d", d.Config.Name, i)
+		}
+		c.Mounts = append(append([]Mount{}, d.Config.Mounts...), ic.Mounts...)
+		c.MountBase = d.Config.MountBase
+		c.Secrets = d.Config.Secrets
+		c.RestartPolicy = "no"
+		if c.Platform == "" {
+			c.Platform = d.Config.Platform
+		}
+
+		log.Printf("Running init container %d/%d %s for %s\n", i+1, len(d.Config.InitContainers), c.Image, d.Config.Name)
+		init := &Docker{Client: d.Client, Config: c}
+		step := init.runToCompletion(ctx)
+		steps = append(steps, step)
+		if step.Error != "" {
+			log.Printf("Init container %s for %s failed: %s\n", step.Name, d.Config.Name, step.Error)
+			return steps, fmt.Errorf("init container %s failed: %s", step.Name, step.Error)
+		}
+		log.Printf("Init container %s for %s completed in %v\n", step.Name, d.Config.Name, step.Duration)
+	}
+	return steps, nil
+}
+
+// runToCompletion runs the container, waits for it to exit and removes
+// it.
+func (d *Docker) runToCompletion(ctx context.Context) InitStep {
+	start := time.Now()
+	step := InitStep{Name: d.Config.Name, Image: d.Config.Image}
+
+	result := d.Run()
+	if result.Error != nil {
+		step.Error = result.Error.Error()
+		return step
+	}
+	if step.Name == "" {
+		step.Name = result.ContainerId
+	}
+	defer d.Remove(result.ContainerId)
+
+	statusCh, errCh := d.Client.ContainerWait(ctx, result.ContainerId, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		step.Error = err.Error()
+	case status := <-statusCh:
+		step.ExitCode = int(status.StatusCode)
+		if status.Error != nil {
+			step.Error = status.Error.Message
+		} else if status.StatusCode != 0 {
+			step.Error = fmt.Sprintf("exited with code %d", status.StatusCode)
+		}
+	}
+	step.Duration = time.Since(start)
+	return step
+}
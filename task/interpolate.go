@@ -0,0 +1,122 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SecretProvider resolves ${secret:name} references in a task's env.
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// FileSecrets reads each secret from a file named after it in Dir, like
+// Docker and Kubernetes mount secrets. A trailing newline is dropped.
+type FileSecrets struct {
+	Dir string
+}
+
+func (f FileSecrets) Secret(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	b, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// envRef matches ${VAR}, ${secret:name} and the $${ escape for a
+// literal "${".
+var envRef = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// InterpolateEnv resolves ${VAR} references against the other entries of
+// env and ${secret:name} references through secrets. Any reference that
+// can't be resolved is an error rather than being passed through.
+func InterpolateEnv(env []string, secrets SecretProvider) ([]string, error) {
+	r := envResolver{
+		raw:       make(map[string]string),
+		resolved:  make(map[string]string),
+		resolving: make(map[string]bool),
+		secrets:   secrets,
+	}
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		r.raw[name] = value
+	}
+
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			out = append(out, kv)
+			continue
+		}
+		value, err := r.resolve(name)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
+		}
+		out = append(out, name+"="+value)
+	}
+	return out, nil
+}
+
+type envResolver struct {
+	raw       map[string]string
+	resolved  map[string]string
+	resolving map[string]bool
+	secrets   SecretProvider
+}
+
+func (r *envResolver) resolve(name string) (string, error) {
+	if v, ok := r.resolved[name]; ok {
+		return v, nil
+	}
+	raw, ok := r.raw[name]
+	if !ok {
+		return "", fmt.Errorf("undefined variable %s", name)
+	}
+	if r.resolving[name] {
+		return "", fmt.Errorf("variable %s refers to itself", name)
+	}
+
+	r.resolving[name] = true
+	v, err := r.expand(raw)
+	delete(r.resolving, name)
+	if err != nil {
+		return "", err
+	}
+	r.resolved[name] = v
+	return v, nil
+}
+
+func (r *envResolver) expand(s string) (string, error) {
+	var err error
+	out := envRef.ReplaceAllStringFunc(s, func(m string) string {
+		if err != nil {
+			return ""
+		}
+		if m == "$${" {
+			return "${"
+		}
+
+		ref := m[2 : len(m)-1]
+		var v string
+		if name, ok := strings.CutPrefix(ref, "secret:"); ok {
+			if r.secrets == nil {
+				err = errors.New("no secret provider configured")
+				return ""
+			}
+			v, err = r.secrets.Secret(name)
+		} else {
+			v, err = r.resolve(ref)
+		}
+		return v
+	})
+	return out, err
+}
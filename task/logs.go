@@ -0,0 +1,121 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// DefaultMaxLogBytes bounds the startup logs kept in a DockerResult when
+// Config.MaxLogBytes is zero.
+const DefaultMaxLogBytes = 256 << 10
+
+const truncatedMarker = "\n[truncated]\n"
+
+var errLogLimit = errors.New("log limit reached")
+
+// cappedBuffer keeps at most max bytes. The write that would exceed it
+// fails, which stops stdcopy from reading any more of the stream.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int64
+	truncated bool
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	room := c.max - int64(c.buf.Len())
+	if int64(len(p)) > room {
+		c.buf.Write(p[:room])
+		c.truncated = true
+		return int(room), errLogLimit
+	}
+	return c.buf.Write(p)
+}
+
+func (c *cappedBuffer) String() string {
+	if c.truncated {
+		return c.buf.String() + truncatedMarker
+	}
+	return c.buf.String()
+}
+
+func (c *Config) maxLogBytes() int64 {
+	if c.MaxLogBytes > 0 {
+		return c.MaxLogBytes
+	}
+	return DefaultMaxLogBytes
+}
+
+// captureLogs copies the container's output so far to the worker's
+// stdout and stderr and returns it, cut off at Config.MaxLogBytes.
+func (d *Docker) captureLogs(ctx context.Context, id string) (string, error) {
+	out, err := d.Client.ContainerLogs(ctx, id, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	logs := &cappedBuffer{max: d.Config.maxLogBytes()}
+	_, err = stdcopy.StdCopy(io.MultiWriter(os.Stdout, logs), io.MultiWriter(os.Stderr, logs), out)
+	if err != nil && !errors.Is(err, errLogLimit) {
+		return "", err
+	}
+	return logs.String(), nil
+}
+
+// FollowLogs streams the container's stdout and stderr into out until the
+// container stops or ctx is cancelled.
+func (d *Docker) FollowLogs(ctx context.Context, id string, out io.Writer) error {
+	stream, err := d.Client.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	_, err = stdcopy.StdCopy(out, out, stream)
+	return err
+}
+
+// LogOptions selects the output StreamLogs returns. Tail is how many of
+// the most recent lines to start from, zero meaning all of them, and a
+// zero Since doesn't filter by time.
+type LogOptions struct {
+	Follow bool
+	Tail   int
+	Since  time.Time
+}
+
+// StreamLogs writes the container's stdout and stderr to out. With
+// Follow it keeps writing until the container stops or ctx is cancelled.
+func (d *Docker) StreamLogs(ctx context.Context, id string, opts LogOptions, out io.Writer) error {
+	o := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+	}
+	if opts.Tail > 0 {
+		o.Tail = strconv.Itoa(opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		o.Since = strconv.FormatInt(opts.Since.Unix(), 10)
+	}
+	stream, err := d.Client.ContainerLogs(ctx, id, o)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	_, err = stdcopy.StdCopy(out, out, stream)
+	return err
+}
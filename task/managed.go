@@ -0,0 +1,53 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ManagedContainer is a container carrying ManagedLabel, whether or not
+// any task still tracks it.
+type ManagedContainer struct {
+	ID      string
+	Name    string
+	Image   string
+	State   string
+	Status  string
+	Labels  map[string]string
+	Created time.Time
+}
+
+// ManagedContainers lists every container ordo created on this daemon,
+// running or not.
+func (d *Docker) ManagedContainers() ([]ManagedContainer, error) {
+	ctx := context.Background()
+	containers, err := d.Client.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", ManagedLabel+"=true")),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	managed := make([]ManagedContainer, 0, len(containers))
+	for _, c := range containers {
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		managed = append(managed, ManagedContainer{
+			ID:      c.ID,
+			Name:    name,
+			Image:   c.Image,
+			State:   c.State,
+			Status:  c.Status,
+			Labels:  c.Labels,
+			Created: time.Unix(c.Created, 0).UTC(),
+		})
+	}
+	return managed, nil
+}
@@ -0,0 +1,14 @@
+package task
+
+import "strconv"
+
+// MetadataEnv describes where a task is running, for containers that need
+// to know their own identity.
+func MetadataEnv(t Task, nodeName string) []string {
+	return []string{
+		"GOORCH_TASK_ID=" + t.ID.String(),
+		"GOORCH_TASK_NAME=" + t.Name,
+		"GOORCH_NODE_NAME=" + nodeName,
+		"GOORCH_REPLICA_INDEX=" + strconv.Itoa(t.ReplicaIndex),
+	}
+}
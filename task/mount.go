@@ -0,0 +1,99 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+)
+
+var (
+	// ErrMountOutsideBase is returned when a bind mount source resolves
+	// to a path outside the worker's mount base.
+	ErrMountOutsideBase = errors.New("mount source is outside the mount base")
+	// ErrBuildContextOutsideBase is the same for a build context.
+	ErrBuildContextOutsideBase = errors.New("build context is outside the mount base")
+	// ErrSeccompProfileOutsideBase is the same for a seccomp profile.
+	ErrSeccompProfileOutsideBase = errors.New("seccomp profile is outside the mount base")
+)
+
+// Mount bind-mounts a host path into the container. A relative Source is
+// resolved against the worker's workspace, which keeps manifests free of
+// node-specific absolute paths. An absolute Source must be inside the
+// workspace too, so tasks can't mount arbitrary host paths.
+type Mount struct {
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+func (m Mount) Validate() error {
+	if m.Source == "" {
+		return errors.New("mount source is required")
+	}
+	if !filepath.IsAbs(m.Target) {
+		return fmt.Errorf("mount target %q must be an absolute path", m.Target)
+	}
+	return nil
+}
+
+// mounts resolves the config's bind mounts for the Docker API. On a
+// daemon with userns-remap, a writable relative source that doesn't exist
+// yet is created for the task and handed to the remapped root user.
+// Existing paths are never chowned, since other tasks may share them.
+func (c *Config) mounts() ([]mount.Mount, error) {
+	var mounts []mount.Mount
+	for _, m := range c.Mounts {
+		if c.remapped() && !m.ReadOnly && !filepath.IsAbs(m.Source) {
+			if err := c.createForRemap(m.Source); err != nil {
+				return nil, err
+			}
+		}
+		src, err := resolveInBase(c.MountBase, m.Source, "mount source", ErrMountOutsideBase)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   src,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+	return mounts, nil
+}
+
+// resolveInBase joins a relative src to base and checks the result, or an
+// absolute src, is under base, returning outside if it isn't. Symlinks
+// are followed first, so neither "../.." nor a link inside the workspace
+// can escape it. what names src in errors.
+func resolveInBase(base, src, what string, outside error) (string, error) {
+	if base == "" {
+		return "", fmt.Errorf("%s %q can't be used: the worker has no workspace", what, src)
+	}
+
+	root, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", fmt.Errorf("error resolving workspace %s: %w", base, err)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	path := src
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, src)
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s %q: %w", what, src, err)
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q resolves to %s", outside, src, path)
+	}
+	return path, nil
+}
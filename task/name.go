@@ -0,0 +1,52 @@
+package task
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var namePlaceholder = regexp.MustCompile(`\{([a-zA-Z0-9_.-]+)\}`)
+
+// ExpandNameTemplate resolves a name template such as "{app}-{env}-{index}".
+// {image} is the image's repository name without registry or tag,
+// {index} is index, {shortid} is the first 8 characters of the task ID and
+// any other placeholder is looked up in the task's labels. The result
+// must be a valid container name.
+func ExpandNameTemplate(tmpl string, t Task, index int) (string, error) {
+	var missing []string
+	name := namePlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		key := m[1 : len(m)-1]
+		switch key {
+		case "image":
+			return imageName(t.Image)
+		case "index":
+			return strconv.Itoa(index)
+		case "shortid":
+			return t.ID.String()[:8]
+		}
+		if v, ok := t.Labels[key]; ok {
+			return v
+		}
+		missing = append(missing, key)
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("name template %q: no value for %s", tmpl, strings.Join(missing, ", "))
+	}
+	if err := ValidateName(name); err != nil {
+		return "", fmt.Errorf("name template %q: %w", tmpl, err)
+	}
+	return name, nil
+}
+
+func imageName(image string) string {
+	_, p := splitImage(image)
+	p = path.Base(p)
+	if i := strings.IndexAny(p, ":@"); i >= 0 {
+		p = p[:i]
+	}
+	return p
+}
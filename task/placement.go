@@ -0,0 +1,26 @@
+package task
+
+import "time"
+
+// PlacementDecision records how the scheduler placed a task, or why it
+// couldn't, the last time it tried.
+type PlacementDecision struct {
+	Time      time.Time
+	Scheduler string
+	// Nodes is every node considered, in registration order.
+	Nodes []NodeDecision
+	// Chosen is the node the task was placed on, empty if none was.
+	Chosen string `json:",omitempty"`
+	// Reason is what decided the choice, or why there was none.
+	Reason string
+}
+
+// NodeDecision is how one node fared in a placement decision. Nodes
+// that were candidates have a score; the others say which constraint
+// ruled them out.
+type NodeDecision struct {
+	Node       string
+	Candidate  bool
+	Score      float64 `json:",omitempty"`
+	Eliminated string  `json:",omitempty"`
+}
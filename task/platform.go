@@ -0,0 +1,81 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// archAliases maps the kernel architecture names reported by Docker's
+// Info endpoint to the names used in image platforms.
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armv7l":  "arm",
+	"i386":    "386",
+	"i686":    "386",
+}
+
+// NativePlatform returns the daemon's platform as "os/arch".
+func (d *Docker) NativePlatform(ctx context.Context) (string, error) {
+	info, err := d.Client.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	arch := info.Architecture
+	if alias, ok := archAliases[arch]; ok {
+		arch = alias
+	}
+	return fmt.Sprintf("%s/%s", info.OSType, arch), nil
+}
+
+// resolvePlatform defaults Config.Platform to the daemon's platform and
+// rejects platforms the daemon can't run.
+func (d *Docker) resolvePlatform(ctx context.Context) (*specs.Platform, error) {
+	native, err := d.NativePlatform(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error detecting node platform: %w", err)
+	}
+	if d.Config.Platform == "" {
+		d.Config.Platform = native
+	}
+
+	if !PlatformMatches(d.Config.Platform, native) {
+		return nil, fmt.Errorf("platform mismatch: task requires %s but node is %s", d.Config.Platform, native)
+	}
+	return ParsePlatform(d.Config.Platform)
+}
+
+// ParsePlatform parses a platform of the form "os/arch[/variant]".
+func ParsePlatform(s string) (*specs.Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid platform %q: expected os/arch[/variant]", s)
+	}
+
+	p := &specs.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// PlatformMatches reports whether a task requiring want can run on a node
+// with platform have. Only the OS and architecture are compared.
+func PlatformMatches(want, have string) bool {
+	if want == "" || have == "" {
+		return true
+	}
+	w, err := ParsePlatform(want)
+	if err != nil {
+		return false
+	}
+	h, err := ParsePlatform(have)
+	if err != nil {
+		return false
+	}
+	return w.OS == h.OS && w.Architecture == h.Architecture
+}
@@ -0,0 +1,46 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// Podman runs tasks through Podman's Docker-compatible API, so it reuses
+// the Docker implementation with a client pointed at the Podman socket.
+// The socket must be enabled, e.g. with `systemctl --user enable --now
+// podman.socket` for rootless Podman.
+type Podman struct {
+	*Docker
+}
+
+func NewPodman(c *Config) (*Podman, error) {
+	dc, err := client.NewClientWithOpts(
+		client.WithHost(podmanHost()),
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating podman client: %w", err)
+	}
+	return &Podman{
+		Docker: &Docker{
+			Client: dc,
+			Config: *c,
+		},
+	}, nil
+}
+
+// podmanHost finds the Podman API socket: CONTAINER_HOST if it names a
+// unix socket, else the rootless socket under XDG_RUNTIME_DIR for
+// non-root users, else the rootful one.
+func podmanHost() string {
+	if h := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(h, "unix://") {
+		return h
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+		return fmt.Sprintf("unix://%s/podman/podman.sock", dir)
+	}
+	return "unix:///run/podman/podman.sock"
+}
@@ -0,0 +1,130 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultProbePeriod           = 10 * time.Second
+	defaultProbeFailureThreshold = 3
+	defaultProbeTimeout          = time.Second
+)
+
+type HTTPGetAction struct {
+	Path string
+	Port int
+}
+
+type TCPSocketAction struct {
+	Port int
+}
+
+// ExecAction runs Command inside the container; exit code 0 is healthy.
+// It suits images whose health can only be seen from inside, such as a
+// lock file or a CLI status command.
+type ExecAction struct {
+	Command []string
+}
+
+// Probe checks a task's container. Exactly one of HTTPGet, TCPSocket and
+// Exec is set. Ports are container ports; the worker probes the host port
+// Docker published them on. Timeout bounds each check and defaults to
+// one second.
+type Probe struct {
+	HTTPGet          *HTTPGetAction
+	TCPSocket        *TCPSocketAction
+	Exec             *ExecAction
+	InitialDelay     time.Duration
+	Period           time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
+}
+
+func (p *Probe) Validate() error {
+	actions := 0
+	for _, set := range []bool{p.HTTPGet != nil, p.TCPSocket != nil, p.Exec != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return errors.New("probe needs exactly one of HTTPGet, TCPSocket or Exec")
+	}
+	if p.Exec != nil {
+		if len(p.Exec.Command) == 0 {
+			return errors.New("exec probe needs a command")
+		}
+	} else if p.Port() <= 0 || p.Port() > 65535 {
+		return fmt.Errorf("invalid probe port %d", p.Port())
+	}
+	if p.InitialDelay < 0 || p.Period < 0 || p.Timeout < 0 || p.FailureThreshold < 0 {
+		return errors.New("probe delay, period, timeout and failure threshold must not be negative")
+	}
+	return nil
+}
+
+func (p *Probe) Port() int {
+	if p.HTTPGet != nil {
+		return p.HTTPGet.Port
+	}
+	if p.TCPSocket != nil {
+		return p.TCPSocket.Port
+	}
+	return 0
+}
+
+func (p *Probe) PeriodOrDefault() time.Duration {
+	if p.Period == 0 {
+		return defaultProbePeriod
+	}
+	return p.Period
+}
+
+func (p *Probe) TimeoutOrDefault() time.Duration {
+	if p.Timeout == 0 {
+		return defaultProbeTimeout
+	}
+	return p.Timeout
+}
+
+func (p *Probe) FailureThresholdOrDefault() int {
+	if p.FailureThreshold == 0 {
+		return defaultProbeFailureThreshold
+	}
+	return p.FailureThreshold
+}
+
+// Check runs the probe once against addr, a host:port the container port
+// is published on.
+func (p *Probe) Check(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.TimeoutOrDefault())
+	defer cancel()
+
+	if p.TCPSocket != nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s%s", addr, p.HTTPGet.Path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("probe returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+const (
+	defaultRegistry  = "docker.io"
+	pullAttempts     = 3
+	pullRetryBackoff = 2 * time.Second
+)
+
+// ErrImagePullTimeout is returned when a pull doesn't finish within
+// Config.PullTimeout. Another node may have the image cached, so it's
+// worth placing the task elsewhere.
+var ErrImagePullTimeout = errors.New("image pull timed out")
+
+// PullTimedOut reports whether a failed task's StatusReason is an
+// ErrImagePullTimeout, which is how the manager learns of one.
+func PullTimedOut(reason string) bool {
+	return strings.HasPrefix(reason, ErrImagePullTimeout.Error())
+}
+
+// Pull fetches the task's image, trying each configured registry mirror
+// in order and finally the image's canonical registry. Mirrors only
+// stand in for Docker Hub; images from other registries are pulled from
+// them directly. The source the
+// image was pulled from is returned in DockerResult.Result. After a
+// successful Pull, Run uses the local image without pulling again. A
+// task with a Build is built instead. Config.PullTimeout bounds the whole
+// pull, mirrors and retries included, and doesn't count against the
+// task's own run time.
+func (d *Docker) Pull() DockerResult {
+	start := time.Now()
+	result := d.pullImage()
+	result.Timings.Pull = time.Since(start)
+	return result
+}
+
+func (d *Docker) pullImage() DockerResult {
+	if d.Config.Build != nil {
+		return d.Build()
+	}
+
+	ctx := context.Background()
+	if d.Config.PullTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Config.PullTimeout)
+		defer cancel()
+	}
+	host, path := splitImage(d.Config.Image)
+	mirrors := d.Config.RegistryMirrors
+	if !dockerHub(host) {
+		mirrors = nil
+	}
+
+	var err error
+	for _, mirror := range mirrors {
+		mirror = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(mirror, "https://"), "http://"), "/")
+		ref := mirror + "/" + path
+		err = d.pullWithRetry(ctx, ref, "")
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Printf("Error pulling image %s from mirror %s: %v\n", d.Config.Image, mirror, err)
+			continue
+		}
+		err = d.Client.ImageTag(ctx, ref, d.Config.Image)
+		if err != nil {
+			log.Printf("Error tagging image %s as %s: %v\n", ref, d.Config.Image, err)
+			return DockerResult{Action: "pull", Error: err}
+		}
+		d.pulled = true
+		return DockerResult{Action: "pull", Result: mirror}
+	}
+
+	if ctx.Err() == nil {
+		var auth string
+		auth, err = d.Config.RegistryAuth.encode()
+		if err == nil {
+			err = d.pullWithRetry(ctx, d.Config.Image, auth)
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %s after %v", ErrImagePullTimeout, d.Config.Image, d.Config.PullTimeout)
+	}
+	if err != nil {
+		log.Printf("Error pulling image %s: %v\n", d.Config.Image, err)
+		return DockerResult{Action: "pull", Error: err}
+	}
+	d.pulled = true
+	return DockerResult{Action: "pull", Result: host}
+}
+
+func (d *Docker) pullWithRetry(ctx context.Context, ref, auth string) error {
+	var err error
+	for attempt := 1; attempt <= pullAttempts; attempt++ {
+		err = d.pull(ctx, ref, auth)
+		if err == nil || ctx.Err() != nil || !isTransientPullError(err) {
+			return err
+		}
+		if attempt < pullAttempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pullRetryBackoff):
+			}
+		}
+	}
+	return err
+}
+
+func (d *Docker) pull(ctx context.Context, ref, auth string) error {
+	reader, err := d.Client.ImagePull(ctx, ref, types.ImagePullOptions{Platform: d.Config.Platform, RegistryAuth: auth})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// Registry errors such as a missing manifest are reported inside the
+	// progress stream rather than by ImagePull itself.
+	return jsonmessage.DisplayJSONMessagesStream(reader, os.Stdout, os.Stdout.Fd(), false, nil)
+}
+
+func isTransientPullError(err error) bool {
+	if errdefs.IsUnauthorized(err) || errdefs.IsForbidden(err) ||
+		errdefs.IsNotFound(err) || errdefs.IsInvalidParameter(err) {
+		return false
+	}
+
+	var jerr *jsonmessage.JSONError
+	if errors.As(err, &jerr) {
+		msg := strings.ToLower(jerr.Message)
+		for _, s := range []string{"unauthorized", "denied", "not found", "manifest unknown"} {
+			if strings.Contains(msg, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// dockerHub reports whether host is one of Docker Hub's names.
+func dockerHub(host string) bool {
+	switch host {
+	case defaultRegistry, "index.docker.io", "registry-1.docker.io":
+		return true
+	}
+	return false
+}
+
+// splitImage separates an image reference into its registry host and
+// the repository path relative to that host.
+func splitImage(image string) (string, string) {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return defaultRegistry, fmt.Sprintf("library/%s", image)
+	}
+
+	host := image[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return defaultRegistry, image
+	}
+	return host, image[i+1:]
+}
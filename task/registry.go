@@ -0,0 +1,40 @@
+package task
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+)
+
+// RegistryAuth is the credentials used to pull a task's image from a
+// private registry. ServerAddress is informational; the credentials are
+// only sent to the image's own registry, never to a mirror.
+type RegistryAuth struct {
+	Username      string
+	Password      string
+	ServerAddress string
+}
+
+// String leaves the password out so credentials can't leak into logs.
+func (a RegistryAuth) String() string {
+	return fmt.Sprintf("%s@%s", a.Username, a.ServerAddress)
+}
+
+// encode returns the credentials in the form the daemon expects in
+// ImagePullOptions.RegistryAuth.
+func (a *RegistryAuth) encode() (string, error) {
+	if a == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(types.AuthConfig{
+		Username:      a.Username,
+		Password:      a.Password,
+		ServerAddress: a.ServerAddress,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,45 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateRestartPolicy checks a Docker restart policy. Empty is valid
+// and means whatever default applies.
+func ValidateRestartPolicy(policy string) error {
+	switch policy {
+	case "", "no", "always", "unless-stopped", "on-failure":
+		return nil
+	}
+	if n, ok := strings.CutPrefix(policy, "on-failure:"); ok {
+		if retries, err := strconv.Atoi(n); err == nil && retries >= 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown restart policy %q, must be no, always, unless-stopped or on-failure[:max-retries]", policy)
+}
+
+// MaxRestartHistory is how many restarts a task remembers.
+const MaxRestartHistory = 20
+
+// RestartEvent records one replacement of a task's container.
+type RestartEvent struct {
+	Time   time.Time
+	Reason string
+	// ExitCode and ContainerID are those of the container replaced.
+	ExitCode    int
+	ContainerID string
+}
+
+// RecordRestart adds e to the task's restart history, dropping the
+// oldest entries past MaxRestartHistory.
+func (t *Task) RecordRestart(e RestartEvent) {
+	t.RestartHistory = append(t.RestartHistory, e)
+	if n := len(t.RestartHistory) - MaxRestartHistory; n > 0 {
+		t.RestartHistory = slices.Delete(t.RestartHistory, 0, n)
+	}
+}
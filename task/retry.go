@@ -0,0 +1,36 @@
+package task
+
+import (
+	"log"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+const (
+	daemonAttempts     = 3
+	daemonRetryBackoff = 500 * time.Millisecond
+)
+
+// retryTransient runs op until it succeeds, fails with an error that
+// retrying won't fix, or runs out of attempts.
+func retryTransient(op func() error) error {
+	var err error
+	for attempt := 1; attempt <= daemonAttempts; attempt++ {
+		err = op()
+		if err == nil || !isTransientDaemonError(err) {
+			return err
+		}
+		log.Printf("Transient daemon error (attempt %d/%d): %v\n", attempt, daemonAttempts, err)
+		if attempt < daemonAttempts {
+			time.Sleep(daemonRetryBackoff * time.Duration(attempt))
+		}
+	}
+	return err
+}
+
+func isTransientDaemonError(err error) bool {
+	return errdefs.IsUnavailable(err) || errdefs.IsDeadline(err) ||
+		client.IsErrConnectionFailed(err)
+}
@@ -0,0 +1,135 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Runtime is a container engine a worker runs tasks on. Each value is
+// bound to the Config of the task it was created for.
+type Runtime interface {
+	Pull() DockerResult
+	Run() DockerResult
+	Stop(id string) DockerResult
+	Remove(id string) DockerResult
+	Kill(id string) DockerResult
+	Restart(id string) DockerResult
+	Rename(id, newName string) DockerResult
+	UpdateResources(id string, cpu float64, memory int64) DockerResult
+	Inspect(id string) DockerInspectResponse
+	Logs(id string) (string, error)
+	FollowLogs(ctx context.Context, id string, out io.Writer) error
+	StreamLogs(ctx context.Context, id string, opts LogOptions, out io.Writer) error
+	Attach(ctx context.Context, id string, stdin io.Reader, stdout, stderr io.Writer) error
+	Stats(id string) (*types.StatsJSON, error)
+	Exec(ctx context.Context, id string, cmd []string) (ExecResult, error)
+	Diff(id string) ([]ContainerChange, error)
+	PruneImages() (ImagePruneResult, error)
+	RemoveUnusedImages(keep []string) (ImagePruneResult, error)
+	RemoveImage(image string) (ImagePruneResult, error)
+	ManagedContainers() ([]ManagedContainer, error)
+	UsernsRemap(ctx context.Context) (UsernsRemap, error)
+}
+
+// NewRuntime returns the runtime named kind for c. An empty kind means
+// Docker.
+func NewRuntime(kind string, c *Config) (Runtime, error) {
+	switch kind {
+	case "", RuntimeDocker:
+		d, err := NewDocker(c)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	case RuntimePodman:
+		p, err := NewPodman(c)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q: must be %q or %q", kind, RuntimeDocker, RuntimePodman)
+	}
+}
+
+type ExecResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+func (d *Docker) Logs(id string) (string, error) {
+	ctx := context.Background()
+	out, err := d.Client.ContainerLogs(ctx, id, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	var buf bytes.Buffer
+	_, err = stdcopy.StdCopy(&buf, &buf, out)
+	return buf.String(), err
+}
+
+func (d *Docker) Stats(id string) (*types.StatsJSON, error) {
+	ctx := context.Background()
+	resp, err := d.Client.ContainerStatsOneShot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Exec runs cmd inside the container and waits for it to finish. If ctx
+// ends first the attached stream is closed and ctx's error returned; the
+// daemon drops the exec instance once its process exits.
+func (d *Docker) Exec(ctx context.Context, id string, cmd []string) (ExecResult, error) {
+	created, err := d.Client.ContainerExecCreate(ctx, id, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return ExecResult{}, err
+	}
+
+	attach, err := d.Client.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return ExecResult{}, err
+	}
+	defer attach.Close()
+	stop := context.AfterFunc(ctx, attach.Close)
+	defer stop()
+
+	var stdout, stderr bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, &stderr, attach.Reader)
+	if ctx.Err() != nil {
+		return ExecResult{}, ctx.Err()
+	}
+	if err != nil && err != io.EOF {
+		return ExecResult{}, err
+	}
+
+	inspect, err := d.Client.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return ExecResult{}, err
+	}
+	return ExecResult{ExitCode: inspect.ExitCode, Stdout: stdout.String(), Stderr: stderr.String()}, nil
+}
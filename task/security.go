@@ -0,0 +1,71 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// validateSecurityOpt accepts the options Docker understands:
+// no-new-privileges[:true|false], seccomp=<profile>, apparmor=<profile>
+// and label=<value>.
+func validateSecurityOpt(opts []string) error {
+	for _, opt := range opts {
+		switch opt {
+		case "no-new-privileges", "no-new-privileges:true", "no-new-privileges:false":
+			continue
+		}
+
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok || value == "" {
+			return fmt.Errorf("invalid security opt %q: expected key=value or no-new-privileges", opt)
+		}
+		switch key {
+		case "seccomp", "apparmor", "label":
+		default:
+			return fmt.Errorf("invalid security opt %q: unknown key %q", opt, key)
+		}
+	}
+	return nil
+}
+
+// LoadSeccompProfile reads a seccomp profile and returns it compacted,
+// ready to pass as seccomp=<profile>. The daemon expects the profile
+// itself rather than a path.
+func LoadSeccompProfile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading seccomp profile: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return "", fmt.Errorf("seccomp profile %s is not valid JSON: %w", path, err)
+	}
+	return buf.String(), nil
+}
+
+// securityOpts resolves seccomp profile paths into the profiles
+// themselves. Like mount sources, a path is resolved against the worker's
+// workspace and must be inside it. "unconfined" and inline JSON profiles
+// are passed through.
+func (c *Config) securityOpts() ([]string, error) {
+	var opts []string
+	for _, opt := range c.SecurityOpt {
+		profile, ok := strings.CutPrefix(opt, "seccomp=")
+		if ok && profile != "unconfined" && !strings.HasPrefix(profile, "{") {
+			path, err := resolveInBase(c.MountBase, profile, "seccomp profile", ErrSeccompProfileOutsideBase)
+			if err != nil {
+				return nil, err
+			}
+			p, err := LoadSeccompProfile(path)
+			if err != nil {
+				return nil, err
+			}
+			opt = "seccomp=" + p
+		}
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
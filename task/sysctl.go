@@ -0,0 +1,35 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// namespacedSysctls are the IPC sysctls Docker lets a container set.
+// Everything under net. and fs.mqueue. is allowed too.
+var namespacedSysctls = map[string]bool{
+	"kernel.msgmax":          true,
+	"kernel.msgmnb":          true,
+	"kernel.msgmni":          true,
+	"kernel.sem":             true,
+	"kernel.shmall":          true,
+	"kernel.shmmax":          true,
+	"kernel.shmmni":          true,
+	"kernel.shm_rmid_forced": true,
+}
+
+// validateSysctls rejects sysctls that aren't namespaced. Docker refuses
+// to create a container with one, since setting it would change the
+// whole host.
+func validateSysctls(sysctls map[string]string) error {
+	for k, v := range sysctls {
+		if v == "" {
+			return fmt.Errorf("sysctl %s has no value", k)
+		}
+		if namespacedSysctls[k] || strings.HasPrefix(k, "net.") || strings.HasPrefix(k, "fs.mqueue.") {
+			continue
+		}
+		return fmt.Errorf("sysctl %s is not namespaced and can't be set per container", k)
+	}
+	return nil
+}
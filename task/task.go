@@ -2,9 +2,9 @@ package task
 
 import (
 	"context"
-	"io"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 	"time"
 
 	"math"
@@ -12,7 +12,8 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/go-connections/nat"
 	"github.com/google/uuid"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
@@ -26,32 +27,227 @@ const (
 	Running
 	Completed
 	Failed
+	// Starting tasks have a running container that hasn't passed its
+	// readiness probe yet.
+	Starting
 )
 
+var stateNames = []string{"Pending", "Scheduled", "Running", "Completed", "Failed", "Starting"}
+
+func (s State) String() string {
+	if int(s) < 0 || int(s) >= len(stateNames) {
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+	return stateNames[s]
+}
+
+func ParseState(name string) (State, error) {
+	for i, n := range stateNames {
+		if strings.EqualFold(n, name) {
+			return State(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown task state %q", name)
+}
+
+// Terminal reports whether a task in this state will never run again.
+func (s State) Terminal() bool {
+	return s == Completed || s == Failed
+}
+
 type Task struct {
-	ID            uuid.UUID
-	ContainerID   string
-	Name          string
-	State         State
-	Image         string
-	CPU           float64
-	Memory        int64
-	Disk          int64
-	ExposedPorts  nat.PortSet
-	PortBindings  map[string]string
-	RestartPolicy string
-	StartTime     time.Time
-	FinishTime    time.Time
+	ID             uuid.UUID
+	ContainerID    string
+	Name           string
+	State          State
+	Image          string
+	CPU            float64
+	Memory         int64
+	Disk           int64
+	ExposedPorts   nat.PortSet
+	PortBindings   map[string]string
+	RestartPolicy  string
+	StartTime      time.Time
+	FinishTime     time.Time
+	StatusReason   string
+	ExitCode       int
+	RestartCount   int
+	Singleton      bool
+	ShmSize        int64
+	Platform       string
+	Labels         map[string]string
+	LivenessProbe  *Probe
+	ReadinessProbe *Probe
+	Devices        []DeviceMapping
+	SecurityOpt    []string
+	NodeName       string
+	RemoveOnStop   *bool
+	// MemoryReservation is the memory the scheduler guarantees the task.
+	// Memory stays the hard cap it can burst up to. Zero reserves Memory.
+	MemoryReservation int64
+	// ObservedMemory is what earlier runs of the task actually used. The
+	// manager sets it for usage-based scheduling and reserves it in place
+	// of MemoryReservation; it only affects placement, never the container.
+	ObservedMemory int64
+	// MinMemory is the least memory the scheduler reserves for the task.
+	// The manager raises it when the task is OOM-killed, so the retry
+	// goes to a node with room for more.
+	MinMemory int64
+	// NameTemplate generates Name when it's empty, e.g.
+	// "{app}-{env}-{index}". See ExpandNameTemplate.
+	NameTemplate string
+	// CreateTime is when the manager accepted the task.
+	CreateTime time.Time
+	// Annotations are free-form metadata such as an owner or git sha.
+	// They are stored and returned but never used for scheduling.
+	Annotations map[string]string
+	// Build builds Image on the worker instead of pulling it.
+	Build *BuildSpec
+	// Tolerations let the task run on nodes with matching taints.
+	Tolerations []Toleration
+	Mounts      []Mount
+	// RestartBackoff has the worker restart an on-failure task with
+	// exponential backoff instead of leaving it to Docker.
+	RestartBackoff *RestartBackoff
+	// InitContainers run to completion, in order, before the task's
+	// container first starts. Crash restarts don't run them again, and
+	// a restart through the API only does if it sets RerunInit.
+	// InitSteps records how each one went.
+	InitContainers []Config
+	InitSteps      []InitStep
+	// RemoveImageOnStop removes the task's image when it finishes,
+	// unless another task on the worker still uses it.
+	RemoveImageOnStop bool
+	// Sysctls sets namespaced kernel parameters such as
+	// net.core.somaxconn.
+	Sysctls map[string]string
+	// PullTimeout bounds pulling the image. Zero waits indefinitely.
+	PullTimeout time.Duration
+	// ReplicaIndex is the {index} the task's name was generated with.
+	ReplicaIndex int
+	// InjectMetadataEnv gives the container GOORCH_TASK_ID,
+	// GOORCH_TASK_NAME, GOORCH_NODE_NAME and GOORCH_REPLICA_INDEX.
+	InjectMetadataEnv bool
+	// CpuAlertThreshold and MemoryAlertThreshold are percentages of the
+	// task's CPU and memory limits. Staying above one publishes an alert
+	// event. Zero disables the alert.
+	CpuAlertThreshold    float64
+	MemoryAlertThreshold float64
+	// RegistryAuth pulls Image from a private registry. The manager fills
+	// it in from its credential store when the task leaves it empty. It's
+	// only set on the copy of a task sent to a worker; the manager and
+	// the worker keep it off the tasks they store and report.
+	RegistryAuth *RegistryAuth
+	// DeregisterDelay is how long a graceful stop waits after taking the
+	// running task out of service before stopping its container, so load
+	// balancers and in-flight requests can drain.
+	DeregisterDelay time.Duration
+	// MemorySwap is the memory plus swap the task may use. Equal to
+	// Memory disables swap, -1 allows unlimited swap and zero leaves
+	// Docker's default of twice Memory.
+	MemorySwap int64
+	// LogToFile has the worker copy the container's output to a rotating
+	// file under its log directory. LogFile is that file's path.
+	LogToFile bool
+	LogFile   string
+	// StartAt holds the task Pending until the given time. Zero places
+	// it as soon as possible.
+	StartAt time.Time
+	// Schedule is a cron expression. A task with one is a template the
+	// manager starts a new task from at each scheduled time, subject to
+	// ConcurrencyPolicy.
+	Schedule          string
+	ConcurrencyPolicy string
+	// AttachStdin keeps the container's stdin open so clients attaching
+	// through /tasks/{id}/attach can write to it.
+	AttachStdin bool
+	// ScheduledBy is the scheduler that placed the task, which with a
+	// chain of schedulers is the one that succeeded.
+	ScheduledBy string
+	// UsernsMode "host" runs the task outside the daemon's userns-remap.
+	UsernsMode string
+	// CallbackURL is sent the task's result, including the end of its
+	// output, once it finishes.
+	CallbackURL string
+	// SoftDependsOn lists tasks this one prefers to start after. It
+	// waits for them to be running, but only for up to
+	// SoftDependsTimeout after it was created, and not at all for ones
+	// that don't exist or have finished. Zero timeout uses the manager's
+	// default.
+	SoftDependsOn      []uuid.UUID
+	SoftDependsTimeout time.Duration
+	// CgroupParent places the container under a cgroup the host
+	// manages, such as a systemd slice. Empty uses the daemon's default.
+	CgroupParent string
+	// Hostname defaults to the task name with characters a hostname can't
+	// contain replaced by dashes.
+	Hostname   string
+	Domainname string
+	// TopologySpread spreads the task's replicas over the domains of a
+	// node label.
+	TopologySpread *TopologySpread
+	// Placement is the scheduler's last decision about where to place
+	// the task.
+	Placement *PlacementDecision
+	// Priority orders evictions: when a node has to shed tasks, lower
+	// priorities go first.
+	Priority int
+	// RestartHistory is the task's most recent restarts, oldest first,
+	// whether the worker replaced a crashed container or the manager
+	// moved the task to another node.
+	RestartHistory []RestartEvent
+	// AvoidNodes are nodes the manager won't place the task on again,
+	// such as ones where pulling its image timed out.
+	AvoidNodes []string
+	// Timings is how long each phase of the task's latest container
+	// took. The worker records it and the manager copies it over when it
+	// reconciles.
+	Timings Timings
+	// CorrelationID ties together everything done on behalf of the
+	// request that submitted the task.
+	CorrelationID string
 }
 
-type TaskEvent struct {
+// ReservedMemory is the memory the scheduler accounts for the task: what
+// it has been observed using, else its reservation, else its hard limit.
+// Shared memory is part of that, so it's never less than ShmSize, nor
+// than MinMemory.
+func (t Task) ReservedMemory() int64 {
+	reserved := t.Memory
+	switch {
+	case t.ObservedMemory > 0:
+		reserved = t.ObservedMemory
+	case t.MemoryReservation > 0:
+		reserved = t.MemoryReservation
+	}
+	return max(reserved, t.ShmSize, t.MinMemory)
+}
+
+// TaskSummary is the compact form of a Task returned by list endpoints.
+type TaskSummary struct {
 	ID        uuid.UUID
+	Name      string
 	State     State
-	Timestamp time.Time
-	Task      Task
+	Image     string
+	Node      string
+	StartTime time.Time
+}
+
+type TaskEvent struct {
+	ID            uuid.UUID
+	State         State
+	Timestamp     time.Time
+	Task          Task
+	CorrelationID string
+	// Alert is set on events reporting a usage alert rather than a
+	// state change.
+	Alert *Alert
 }
 
 type Config struct {
+	// TaskID is the task the container is created for, recorded in its
+	// TaskIDLabel. Init containers leave it unset.
+	TaskID        uuid.UUID `json:"-"`
 	Name          string
 	AttachStdin   bool
 	AttachStdout  bool
@@ -64,11 +260,163 @@ type Config struct {
 	Disk          int64
 	Env           []string
 	RestartPolicy string
+	Labels        map[string]string
+	// MemoryReservation is the soft limit Docker reclaims memory down to
+	// under contention, while Memory is the hard cap. It must not exceed
+	// Memory.
+	MemoryReservation int64
+	// NodeName pins the task to one node, bypassing scoring. The task
+	// stays pending rather than run elsewhere if that node can't take it.
+	NodeName string
+	// RemoveOnStop removes the container when the task is stopped. Set it
+	// to false to keep the container and its logs for inspection; nil
+	// means true.
+	RemoveOnStop *bool
+	// Annotations ride along with the task and aren't passed to Docker,
+	// so unlike labels their values have no length or charset limits.
+	Annotations map[string]string
+	// LivenessProbe restarts the task when it fails FailureThreshold
+	// times in a row.
+	LivenessProbe *Probe
+	// ReadinessProbe keeps the task Starting until it passes, and fails
+	// the task if it doesn't pass within FailureThreshold attempts.
+	ReadinessProbe *Probe
+	Devices        []DeviceMapping
+	// BlkioWeight is the relative block IO weight, 10 to 1000. Zero leaves
+	// the daemon default.
+	BlkioWeight    uint16
+	DeviceReadBps  []ThrottleDevice
+	DeviceWriteBps []ThrottleDevice
+	// CpuQuota and CpuPeriod, in microseconds, set the CFS bandwidth limit
+	// directly. They can't be combined with Cpu.
+	CpuQuota  int64
+	CpuPeriod int64
+	// OomScoreAdj is added to the container's OOM score; higher values make
+	// it more likely to be killed first.
+	OomScoreAdj    int
+	OomKillDisable bool
+	// ShmSize is the size of /dev/shm in bytes. Zero keeps Docker's 64MB
+	// default. Shared memory is charged to the container's memory, so it
+	// must fit within Memory, and the scheduler reserves at least this
+	// much for the task.
+	ShmSize int64
+	// Platform selects the image variant to pull and run, e.g.
+	// "linux/arm64". Empty means the node's native platform.
+	Platform string
+	// Singleton tasks never have more than one active instance with the
+	// same name across the cluster.
+	Singleton bool
+	// RegistryMirrors are registry hosts tried in order before Docker Hub
+	// when pulling images from it.
+	RegistryMirrors []string
+	// Hostname defaults to the task name with characters a hostname can't
+	// contain replaced by dashes.
+	Hostname   string
+	Domainname string
+	// Secrets resolves ${secret:name} references in Env. It's set by the
+	// worker and never serialized.
+	Secrets SecretProvider `json:"-"`
+	// SecurityOpt confines the container, e.g. "seccomp=profile.json",
+	// "apparmor=my-profile" or "no-new-privileges". Empty keeps Docker's
+	// default seccomp and AppArmor profiles.
+	SecurityOpt []string
+	// Build builds the image from a local context instead of pulling it.
+	Build       *BuildSpec
+	Tolerations []Toleration
+	// MaxLogBytes caps the startup logs kept in DockerResult.Logs.
+	// Zero uses DefaultMaxLogBytes.
+	MaxLogBytes int64
+	Mounts      []Mount
+	// MountBase is the directory relative mount sources are resolved
+	// against and all of them must be inside. It's set by the worker and
+	// never serialized.
+	MountBase      string `json:"-"`
+	RestartBackoff *RestartBackoff
+	// InitContainers run to completion before this container starts.
+	// They get the container's mounts in addition to their own.
+	InitContainers    []Config
+	RemoveImageOnStop bool
+	Sysctls           map[string]string
+	InjectMetadataEnv bool
+	PullTimeout       time.Duration
+	RegistryAuth      *RegistryAuth
+	DeregisterDelay   time.Duration
+	// MemorySwap is Docker's memory plus swap limit. It requires Memory
+	// and must be at least Memory, or -1 for unlimited swap.
+	MemorySwap int64
+	// DetachKeys overrides DefaultDetachKeys for Attach.
+	DetachKeys string
+	UsernsMode string
+	// UsernsRemap is the daemon's remapping as detected by the worker.
+	UsernsRemap  UsernsRemap `json:"-"`
+	CallbackURL  string
+	CgroupParent string
 }
 
 type Docker struct {
 	Client *client.Client
 	Config Config
+
+	pulled bool
+}
+
+func NewConfig(t *Task) *Config {
+	return &Config{
+		TaskID:            t.ID,
+		Name:              t.Name,
+		ExposedPorts:      t.ExposedPorts,
+		Image:             t.Image,
+		Cpu:               t.CPU,
+		Memory:            t.Memory,
+		Disk:              t.Disk,
+		RestartPolicy:     t.RestartPolicy,
+		ShmSize:           t.ShmSize,
+		Singleton:         t.Singleton,
+		Platform:          t.Platform,
+		Labels:            t.Labels,
+		Annotations:       t.Annotations,
+		NodeName:          t.NodeName,
+		RemoveOnStop:      t.RemoveOnStop,
+		MemoryReservation: t.MemoryReservation,
+		LivenessProbe:     t.LivenessProbe,
+		ReadinessProbe:    t.ReadinessProbe,
+		Devices:           t.Devices,
+		SecurityOpt:       t.SecurityOpt,
+		Build:             t.Build,
+		Tolerations:       t.Tolerations,
+		Mounts:            t.Mounts,
+		RestartBackoff:    t.RestartBackoff,
+		InitContainers:    t.InitContainers,
+		RemoveImageOnStop: t.RemoveImageOnStop,
+		Sysctls:           t.Sysctls,
+		InjectMetadataEnv: t.InjectMetadataEnv,
+		PullTimeout:       t.PullTimeout,
+		RegistryAuth:      t.RegistryAuth,
+		DeregisterDelay:   t.DeregisterDelay,
+		MemorySwap:        t.MemorySwap,
+		UsernsMode:        t.UsernsMode,
+		CallbackURL:       t.CallbackURL,
+		AttachStdin:       t.AttachStdin,
+		CgroupParent:      t.CgroupParent,
+		Hostname:          t.Hostname,
+		Domainname:        t.Domainname,
+	}
+}
+
+func NewDocker(c *Config) (*Docker, error) {
+	dc, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return nil, fmt.Errorf("error creating docker client: %w", err)
+	}
+	return &Docker{
+		Client: dc,
+		Config: *c,
+	}, nil
+}
+
+type DockerInspectResponse struct {
+	Error     error
+	Container *types.ContainerJSON
 }
 
 type DockerResult struct {
@@ -76,62 +424,148 @@ type DockerResult struct {
 	Action      string
 	ContainerId string
 	Result      string
+	// Logs is the container's output at startup, up to
+	// Config.MaxLogBytes.
+	Logs      string
+	InitSteps []InitStep
+	Timings   Timings
 }
 
 func (d *Docker) Run() DockerResult {
+	if err := d.Config.Validate(); err != nil {
+		log.Printf("Invalid config for %s: %v\n", d.Config.Name, err)
+		return DockerResult{Error: err}
+	}
+
 	ctx := context.Background()
-	reader, err := d.Client.ImagePull(ctx, d.Config.Image, types.ImagePullOptions{})
+	platform, err := d.resolvePlatform(ctx)
+	if err != nil {
+		log.Printf("Error resolving platform for image %s: %v\n", d.Config.Image, err)
+		return DockerResult{Error: err}
+	}
+
+	env, err := InterpolateEnv(d.Config.Env, d.Config.Secrets)
+	if err != nil {
+		log.Printf("Error interpolating env for %s: %v\n", d.Config.Name, err)
+		return DockerResult{Error: err}
+	}
+
+	securityOpt, err := d.Config.securityOpts()
+	if err != nil {
+		log.Printf("Error loading security options for %s: %v\n", d.Config.Name, err)
+		return DockerResult{Error: err}
+	}
+
+	mounts, err := d.Config.mounts()
 	if err != nil {
-		log.Printf("Error pulling image %s: %v\n", d.Config.Image, err)
+		log.Printf("Error resolving mounts for %s: %v\n", d.Config.Name, err)
 		return DockerResult{Error: err}
 	}
-	io.Copy(os.Stdout, reader)
+
+	var timings Timings
+	if !d.pulled {
+		pull := d.Pull()
+		if pull.Error != nil {
+			return pull
+		}
+		timings.Pull = pull.Timings.Pull
+	}
+
+	initSteps, err := d.runInitContainers(ctx)
+	if err != nil {
+		return DockerResult{Error: err, InitSteps: initSteps}
+	}
 
 	rp := container.RestartPolicy{
-		Name: d.Config.RestartPolicy,
+		Name: d.Config.dockerRestartPolicy(),
 	}
 	r := container.Resources{
-		Memory:   d.Config.Memory,
-		NanoCPUs: int64(d.Config.Cpu * math.Pow(10, 9)),
+		Memory:            d.Config.Memory,
+		MemoryReservation: d.Config.MemoryReservation,
+		MemorySwap:        d.Config.MemorySwap,
+		NanoCPUs:          int64(d.Config.Cpu * math.Pow(10, 9)),
+		CPUQuota:          d.Config.CpuQuota,
+		CPUPeriod:         d.Config.CpuPeriod,
+		CgroupParent:      d.Config.CgroupParent,
+	}
+	for _, dm := range d.Config.Devices {
+		if dm.PathInContainer == "" {
+			dm.PathInContainer = dm.PathOnHost
+		}
+		if dm.CgroupPermissions == "" {
+			dm.CgroupPermissions = "rwm"
+		}
+		r.Devices = append(r.Devices, container.DeviceMapping{
+			PathOnHost:        dm.PathOnHost,
+			PathInContainer:   dm.PathInContainer,
+			CgroupPermissions: dm.CgroupPermissions,
+		})
+	}
+	d.Config.applyBlkio(&r)
+	if d.Config.OomKillDisable {
+		r.OomKillDisable = &d.Config.OomKillDisable
 	}
 	cc := container.Config{
 		Image:        d.Config.Image,
+		Cmd:          d.Config.Cmd,
 		Tty:          false,
-		Env:          d.Config.Env,
+		OpenStdin:    d.Config.AttachStdin,
+		Env:          env,
 		ExposedPorts: d.Config.ExposedPorts,
+		Labels:       d.Config.containerLabels(),
+		Hostname:     d.Config.hostname(),
+		Domainname:   d.Config.Domainname,
 	}
 	hc := container.HostConfig{
 		RestartPolicy:   rp,
 		Resources:       r,
 		PublishAllPorts: true,
+		ShmSize:         d.Config.ShmSize,
+		OomScoreAdj:     d.Config.OomScoreAdj,
+		SecurityOpt:     securityOpt,
+		Mounts:          mounts,
+		Sysctls:         d.Config.Sysctls,
+		UsernsMode:      container.UsernsMode(d.Config.UsernsMode),
 	}
 
-	resp, err := d.Client.ContainerCreate(ctx, &cc, &hc, nil, nil, d.Config.Name)
+	created := time.Now()
+	resp, err := d.Client.ContainerCreate(ctx, &cc, &hc, nil, platform, d.Config.Name)
+	if errdefs.IsConflict(err) && d.Config.Name != "" {
+		var adopted string
+		adopted, err = d.resolveNameConflict(ctx)
+		if err == nil && adopted != "" {
+			return DockerResult{ContainerId: adopted, Action: "adopt", Result: "success"}
+		}
+		if err == nil {
+			resp, err = d.Client.ContainerCreate(ctx, &cc, &hc, nil, platform, d.Config.Name)
+		}
+	}
 	if err != nil {
 		log.Printf("Error creating container using image %s: %v\n", d.Config.Image, err)
 		return DockerResult{Error: err}
 	}
+	timings.Create = time.Since(created)
 
+	timings.Started = time.Now()
 	err = d.Client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{})
+	timings.Start = time.Since(timings.Started)
 
 	if err != nil {
 		log.Printf("Error starting container %s: %v\n", resp.ID, err)
 		return DockerResult{Error: err}
 	}
 
-	d.Config.Runtime.ContainerID = resp.ID
-	out, err := cli.ContainerLogs(
-		ctx,
-		resp.ID,
-		types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true}
-	)
+	logs, err := d.captureLogs(ctx, resp.ID)
 	if err != nil {
 		log.Printf("Error getting logs for container %s: %v\n", resp.ID, err)
 		return DockerResult{Error: err}
 	}
 
-	stdcopy.StdCopy(os.Stdout, os.Stderr, out)
-	return DockerResult{ContainerId: resp.ID, Action: "start", Result: "success"}
+	result := "success"
+	if limits := d.Config.blkioSummary(); limits != "" {
+		result = fmt.Sprintf("success (%s)", limits)
+	}
+	return DockerResult{ContainerId: resp.ID, Action: "start", Result: result, Logs: logs, InitSteps: initSteps, Timings: timings}
 
 }
 
@@ -143,25 +577,165 @@ func (cli *Client) ContainerCreate(
 	platform *specs.Platform,
 	containerName string) (container.ContainerCreateCreatedBody, error)
 
+// Stop stops the container and removes it unless Config.RemoveOnStop
+// says to keep it. Timings.Stop covers both.
 func (d *Docker) Stop(id string) DockerResult {
+	start := time.Now()
+	result := d.stop(id)
+	result.Timings.Stop = time.Since(start)
+	return result
+}
+
+func (d *Docker) stop(id string) DockerResult {
 	log.Printf("Attempting to stop container %v", id)
 	ctx := context.Background()
-	err := d.Client.ContainerStop(ctx, id, nil)
+	err := retryTransient(func() error {
+		return d.Client.ContainerStop(ctx, id, nil)
+	})
+	if errdefs.IsNotFound(err) {
+		log.Printf("Container %s is already gone\n", id)
+		return DockerResult{Action: "stop", Result: "success"}
+	}
+	if notRunning(err) {
+		log.Printf("Container %s is already stopped\n", id)
+		err = nil
+	}
 	if err != nil {
 		log.Printf("Error stopping container %s: %v\n", id, err)
 		return DockerResult{Error: err}
 	}
 
+	if d.Config.RemoveOnStop != nil && !*d.Config.RemoveOnStop {
+		log.Printf("Keeping stopped container %s for inspection\n", id)
+		return DockerResult{ContainerId: id, Action: "stop", Result: "success"}
+	}
+	return d.Remove(id)
+}
+
+// Remove deletes a stopped container and its anonymous volumes.
+func (d *Docker) Remove(id string) DockerResult {
+	ctx := context.Background()
+	err := retryTransient(func() error {
+		return d.Client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
+			RemoveVolumes: true,
+			RemoveLinks:   false,
+			Force:         false,
+		})
+	})
+	if err != nil && !errdefs.IsNotFound(err) {
+		log.Printf("Error removing container %s: %v\n", id, err)
+		return DockerResult{Error: err}
+	}
+
+	return DockerResult{ContainerId: id, Action: "remove", Result: "success"}
+}
+
+// Kill sends SIGKILL to a container that didn't stop in time and removes
+// it unless RemoveOnStop is false.
+func (d *Docker) Kill(id string) DockerResult {
+	log.Printf("Killing container %v\n", id)
+	ctx := context.Background()
+	err := d.Client.ContainerKill(ctx, id, "SIGKILL")
+	if err != nil && !errdefs.IsNotFound(err) && !notRunning(err) {
+		log.Printf("Error killing container %s: %v\n", id, err)
+		return DockerResult{Error: err}
+	}
+	if d.Config.RemoveOnStop != nil && !*d.Config.RemoveOnStop {
+		return DockerResult{ContainerId: id, Action: "kill", Result: "success"}
+	}
+
 	err = d.Client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
 		RemoveVolumes: true,
-		RemoveLinks:   false,
-		Force:         false,
+		Force:         true,
 	})
-	
-	if err != nil {
+	if err != nil && !errdefs.IsNotFound(err) {
 		log.Printf("Error removing container %s: %v\n", id, err)
 		return DockerResult{Error: err}
 	}
 
-	return DockerResult{Action: "stop", Result: "success", Error: nil}
+	return DockerResult{ContainerId: id, Action: "kill", Result: "success"}
+}
+
+func (d *Docker) Rename(id, newName string) DockerResult {
+	ctx := context.Background()
+	err := d.Client.ContainerRename(ctx, id, newName)
+	if err != nil {
+		log.Printf("Error renaming container %s to %s: %v\n", id, newName, err)
+		return DockerResult{Error: err}
+	}
+
+	return DockerResult{ContainerId: id, Action: "rename", Result: "success"}
+}
+
+func (d *Docker) Inspect(id string) DockerInspectResponse {
+	ctx := context.Background()
+	resp, err := d.Client.ContainerInspect(ctx, id)
+	if err != nil {
+		log.Printf("Error inspecting container %s: %v\n", id, err)
+		return DockerInspectResponse{Error: err}
+	}
+
+	return DockerInspectResponse{Container: &resp}
+}
+
+// defaultCpuPeriod is the kernel's CFS period, in microseconds, which
+// Docker uses when a quota is set without one.
+const defaultCpuPeriod = 100000
+
+// UpdateResources changes a running container's CPU and memory limits. A
+// container created with CpuQuota has its quota scaled to cpu rather than
+// being given NanoCPUs, which Docker won't combine with a quota.
+func (d *Docker) UpdateResources(id string, cpu float64, memory int64) DockerResult {
+	ctx := context.Background()
+	resources := container.Resources{
+		NanoCPUs: int64(cpu * math.Pow(10, 9)),
+		Memory:   memory,
+	}
+	info, err := d.Client.ContainerInspect(ctx, id)
+	if err != nil {
+		log.Printf("Error inspecting container %s: %v\n", id, err)
+		return DockerResult{Error: err}
+	}
+	if hc := info.HostConfig; hc != nil && hc.CPUQuota != 0 && cpu > 0 {
+		period := hc.CPUPeriod
+		if period == 0 {
+			period = defaultCpuPeriod
+		}
+		resources.NanoCPUs = 0
+		resources.CPUPeriod = period
+		resources.CPUQuota = int64(cpu * float64(period))
+	}
+	uc := container.UpdateConfig{Resources: resources}
+	_, err = d.Client.ContainerUpdate(ctx, id, uc)
+	if err != nil && strings.Contains(err.Error(), "memoryswap") {
+		err = fmt.Errorf("memory limit %d is below the container's swap limit, which can't be lowered in place; restart the task to apply it: %w", memory, err)
+	}
+	if err != nil {
+		log.Printf("Error updating resources of container %s: %v\n", id, err)
+		return DockerResult{Error: err}
+	}
+
+	return DockerResult{ContainerId: id, Action: "update", Result: "success"}
+}
+
+func (d *Docker) Restart(id string) DockerResult {
+	ctx := context.Background()
+	err := d.Client.ContainerRestart(ctx, id, nil)
+	if err != nil {
+		log.Printf("Error restarting container %s: %v\n", id, err)
+		return DockerResult{Error: err}
+	}
+
+	return DockerResult{ContainerId: id, Action: "restart", Result: "success"}
+}
+
+// notRunning reports whether err means the container had already stopped,
+// which leaves it in the state a stop or kill asked for. The daemon
+// answers a stop with 304 and a kill with a conflict in that case.
+func notRunning(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errdefs.IsNotModified(err) ||
+		(errdefs.IsConflict(err) && strings.Contains(err.Error(), "is not running"))
 }
@@ -0,0 +1,21 @@
+package task
+
+import "time"
+
+// Timings records how long each phase of a task's container took, so a
+// slow deploy can be put down to the registry, the daemon or the app.
+// Phases that didn't happen are zero.
+type Timings struct {
+	// Pull is the image pull, or build.
+	Pull time.Duration
+	// Create and Start are the daemon's container create and start calls.
+	Create time.Duration
+	Start  time.Duration
+	// Started is when the container was started, and StartToRunning how
+	// long it then took to be Running, which includes passing its
+	// readiness probe.
+	Started        time.Time
+	StartToRunning time.Duration
+	// Stop is stopping, and unless it's kept, removing the container.
+	Stop time.Duration
+}
@@ -0,0 +1,53 @@
+package task
+
+import "fmt"
+
+// Toleration operators.
+const (
+	TolerationEqual  = "Equal"
+	TolerationExists = "Exists"
+)
+
+// Toleration lets a task be placed on nodes with a matching taint. With
+// Operator Exists any value matches; an empty Key with Exists tolerates
+// every taint. An empty Effect matches both NoSchedule and
+// PreferNoSchedule.
+type Toleration struct {
+	Key      string
+	Operator string
+	Value    string
+	Effect   string
+}
+
+func (t Toleration) Validate() error {
+	switch t.Operator {
+	case "", TolerationEqual:
+		if t.Key == "" {
+			return fmt.Errorf("toleration with operator %s needs a key", TolerationEqual)
+		}
+	case TolerationExists:
+		if t.Value != "" {
+			return fmt.Errorf("toleration %s with operator %s must not have a value", t.Key, TolerationExists)
+		}
+	default:
+		return fmt.Errorf("toleration %s has unknown operator %q", t.Key, t.Operator)
+	}
+
+	switch t.Effect {
+	case "", "NoSchedule", "PreferNoSchedule":
+	default:
+		return fmt.Errorf("toleration %s has unknown effect %q", t.Key, t.Effect)
+	}
+	return nil
+}
+
+// Tolerates reports whether the toleration matches a taint.
+func (t Toleration) Tolerates(key, value, effect string) bool {
+	if t.Effect != "" && t.Effect != effect {
+		return false
+	}
+	if t.Operator == TolerationExists {
+		return t.Key == "" || t.Key == key
+	}
+	return t.Key == key && t.Value == value
+}
@@ -0,0 +1,23 @@
+package task
+
+import "fmt"
+
+// TopologySpread keeps a task's replicas, the tasks sharing its "app"
+// label, evenly spread over the domains of a node label such as rack or
+// zone: no domain may have more than MaxSkew replicas over the one with
+// the fewest. "zone" falls back to the node's zone and "host" to its name
+// when nodes don't carry the label.
+type TopologySpread struct {
+	TopologyKey string
+	MaxSkew     int
+}
+
+func (s TopologySpread) Validate() error {
+	if s.TopologyKey == "" {
+		return fmt.Errorf("topology spread needs a topology key")
+	}
+	if s.MaxSkew < 1 {
+		return fmt.Errorf("topology spread over %s needs a max skew of at least 1, got %d", s.TopologyKey, s.MaxSkew)
+	}
+	return nil
+}
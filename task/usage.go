@@ -0,0 +1,45 @@
+package task
+
+import "time"
+
+// ResourceUsage is the resources a task reserved over time, for rough
+// chargeback. It's based on requested CPU and memory, not measured use.
+type ResourceUsage struct {
+	Seconds           float64
+	CPUSeconds        float64
+	MemoryByteSeconds float64
+}
+
+func (u *ResourceUsage) Add(o ResourceUsage) {
+	u.Seconds += o.Seconds
+	u.CPUSeconds += o.CPUSeconds
+	u.MemoryByteSeconds += o.MemoryByteSeconds
+}
+
+// Usage computes the task's resource-seconds within [from, to]. Tasks
+// that are still running are counted up to to. A zero from means since
+// the task started.
+func Usage(t Task, from, to time.Time) ResourceUsage {
+	if t.StartTime.IsZero() {
+		return ResourceUsage{}
+	}
+
+	start := t.StartTime
+	if start.Before(from) {
+		start = from
+	}
+	end := to
+	if !t.FinishTime.IsZero() && t.FinishTime.Before(end) {
+		end = t.FinishTime
+	}
+	if !end.After(start) {
+		return ResourceUsage{}
+	}
+
+	secs := end.Sub(start).Seconds()
+	return ResourceUsage{
+		Seconds:           secs,
+		CPUSeconds:        t.CPU * secs,
+		MemoryByteSeconds: float64(t.Memory) * secs,
+	}
+}
@@ -0,0 +1,110 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// UsernsModeHost opts a task out of the daemon's user-namespace
+// remapping, so root in the container is root on the host.
+const UsernsModeHost = "host"
+
+// UsernsRemap is a daemon's user-namespace remapping. When it's enabled,
+// root in a container is the unprivileged host user UID:GID, so bind
+// mounts owned by host root are read-only inside the container and files
+// the container creates are owned by UID on the host.
+type UsernsRemap struct {
+	Enabled bool
+	UID     int
+	GID     int
+}
+
+func (r UsernsRemap) String() string {
+	if !r.Enabled {
+		return "disabled"
+	}
+	return fmt.Sprintf("%d:%d", r.UID, r.GID)
+}
+
+// UsernsRemap reports whether the daemon runs with userns-remap, and
+// which host IDs container root maps to.
+func (d *Docker) UsernsRemap(ctx context.Context) (UsernsRemap, error) {
+	info, err := d.Client.Info(ctx)
+	if err != nil {
+		return UsernsRemap{}, err
+	}
+
+	var r UsernsRemap
+	for _, opt := range info.SecurityOptions {
+		if opt == "name=userns" || strings.HasPrefix(opt, "name=userns,") {
+			r.Enabled = true
+		}
+	}
+	if !r.Enabled {
+		return r, nil
+	}
+	// A remapped daemon keeps its data in <root>/<uid>.<gid>.
+	uid, gid, ok := strings.Cut(filepath.Base(info.DockerRootDir), ".")
+	if ok {
+		r.UID, _ = strconv.Atoi(uid)
+		r.GID, _ = strconv.Atoi(gid)
+	}
+	return r, nil
+}
+
+func validateUsernsMode(mode string) error {
+	if mode != "" && mode != UsernsModeHost {
+		return fmt.Errorf("invalid userns mode %q: must be empty or %q", mode, UsernsModeHost)
+	}
+	return nil
+}
+
+// remapped reports whether the container runs in a remapped user
+// namespace.
+func (c *Config) remapped() bool {
+	return c.UsernsRemap.Enabled && c.UsernsMode != UsernsModeHost && c.UsernsRemap.UID > 0
+}
+
+// createForRemap creates the writable mount source src for the task if
+// it doesn't exist yet, owned by the remapped root user so the container
+// can write to it. Its parent must already be in the workspace. A source
+// that already exists isn't touched, as it may be shared with other
+// tasks; if it's owned by host root that's logged, since the container
+// won't be able to write there.
+func (c *Config) createForRemap(src string) error {
+	parent, err := resolveInBase(c.MountBase, filepath.Dir(src), "mount source", ErrMountOutsideBase)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(filepath.Clean(src))
+	if name == ".." || name == "." || name == string(filepath.Separator) {
+		return nil
+	}
+	path := filepath.Join(parent, name)
+
+	fi, err := os.Lstat(path)
+	if err == nil {
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Uid == 0 {
+			log.Printf("Mount source %s for %s is owned by host root and won't be writable under remapped root %v\n", path, c.Name, c.UsernsRemap)
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return fmt.Errorf("error creating mount source %s: %w", path, err)
+	}
+	if err := os.Chown(path, c.UsernsRemap.UID, c.UsernsRemap.GID); err != nil {
+		log.Printf("Error handing %s to remapped root %v for %s: %v\n", path, c.UsernsRemap, c.Name, err)
+	}
+	return nil
+}
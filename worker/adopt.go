@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// ErrNotManaged is returned when asked to adopt a container ordo didn't
+// create.
+var ErrNotManaged = errors.New("container is not managed by ordo")
+
+// ManagedContainers lists the containers on this worker's runtime that
+// carry ordo's label, tracked or not.
+func (w *Worker) ManagedContainers() ([]task.ManagedContainer, error) {
+	d, err := w.runtime(&task.Task{})
+	if err != nil {
+		return nil, err
+	}
+	return d.ManagedContainers()
+}
+
+// AdoptContainer starts tracking a container ordo created but no task
+// refers to, such as one left behind by a previous worker instance. If a
+// task already tracks the container, that task is returned instead.
+func (w *Worker) AdoptContainer(containerID string) (task.Task, error) {
+	w.mu.Lock()
+	for _, t := range w.Db {
+		if t.ContainerID == containerID || (len(containerID) >= 12 && strings.HasPrefix(t.ContainerID, containerID)) {
+			w.mu.Unlock()
+			return *t, nil
+		}
+	}
+	w.mu.Unlock()
+
+	d, err := w.runtime(&task.Task{})
+	if err != nil {
+		return task.Task{}, err
+	}
+	resp := d.Inspect(containerID)
+	if resp.Error != nil {
+		return task.Task{}, fmt.Errorf("%w: container %s: %v", ErrTaskNotFound, containerID, resp.Error)
+	}
+	c := resp.Container
+	if c.Config == nil || c.Config.Labels[task.ManagedLabel] != "true" {
+		return task.Task{}, fmt.Errorf("%w: %s", ErrNotManaged, containerID)
+	}
+
+	labels := maps.Clone(c.Config.Labels)
+	delete(labels, task.ManagedLabel)
+	delete(labels, task.TaskIDLabel)
+	t := task.Task{
+		ID:          uuid.New(),
+		ContainerID: c.ID,
+		Name:        strings.TrimPrefix(c.Name, "/"),
+		Image:       c.Config.Image,
+		Labels:      labels,
+		State:       task.Completed,
+		StartTime:   w.clock().Now().UTC(),
+	}
+	if c.HostConfig != nil {
+		t.Memory = c.HostConfig.Memory
+		t.CPU = float64(c.HostConfig.NanoCPUs) / 1e9
+		t.RestartPolicy = c.HostConfig.RestartPolicy.Name
+	}
+	if c.State != nil {
+		if c.State.Running {
+			t.State = task.Running
+		} else if c.State.ExitCode != 0 {
+			t.State = task.Failed
+		}
+		t.ExitCode = c.State.ExitCode
+		if started, err := time.Parse(time.RFC3339Nano, c.State.StartedAt); err == nil {
+			t.StartTime = started.UTC()
+		}
+	}
+	t.StatusReason = "adopted"
+
+	w.putTask(t)
+	log.Printf("Adopted container %s as task %v (%s)\n", c.ID, t.ID, t.State)
+	return t, nil
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type Api struct {
+	Address string
+	Port    int
+	Worker  *Worker
+	Router  *http.ServeMux
+}
+
+func (a *Api) initRouter() {
+	a.Router = http.NewServeMux()
+	a.Router.HandleFunc("POST /tasks", a.StartTaskHandler)
+	a.Router.HandleFunc("GET /tasks", a.GetTasksHandler)
+	a.Router.HandleFunc("GET /tasks/usage", a.GetTaskUsageHandler)
+	a.Router.HandleFunc("PATCH /tasks/{id}", a.PatchTaskHandler)
+	a.Router.HandleFunc("DELETE /tasks/{id}", a.StopTaskHandler)
+	a.Router.HandleFunc("POST /tasks/{id}/restart", a.RestartTaskHandler)
+	a.Router.HandleFunc("DELETE /tasks/{id}/container", a.RemoveContainerHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/attach", a.AttachHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/logs", a.LogsHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/diff", a.GetTaskDiffHandler)
+	a.Router.HandleFunc("GET /tasks/{id}/stats", a.GetTaskStatsHandler)
+	a.Router.HandleFunc("PATCH /tasks/{id}/resources", a.PatchResourcesHandler)
+	a.Router.HandleFunc("GET /stats", a.GetStatsHandler)
+	a.Router.HandleFunc("POST /images/prune", a.PruneImagesHandler)
+	a.Router.HandleFunc("POST /images/pull", a.PullImageHandler)
+	a.Router.HandleFunc("GET /containers", a.GetContainersHandler)
+	a.Router.HandleFunc("POST /containers/{id}/adopt", a.AdoptContainerHandler)
+}
+
+func (a *Api) Start() error {
+	if err := a.Worker.DetectUsernsRemap(); err != nil {
+		return err
+	}
+	if err := task.ValidateRestartPolicy(a.Worker.DefaultRestartPolicy); err != nil {
+		return fmt.Errorf("default restart policy: %w", err)
+	}
+	a.initRouter()
+	return http.ListenAndServe(fmt.Sprintf("%s:%d", a.Address, a.Port), requestID(jsonErrors(a.Router)))
+}
+
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = uuid.NewString()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
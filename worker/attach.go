@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Attach connects stdin and out to the streams of a running task's
+// container until the container exits, the client detaches or ctx is
+// cancelled. stdout and stderr are both written to out.
+func (w *Worker) Attach(ctx context.Context, id uuid.UUID, stdin io.Reader, out io.Writer) error {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if t.State.Terminal() || t.ContainerID == "" {
+		return fmt.Errorf("%w: task %v is %v", ErrTaskNotRunning, id, t.State)
+	}
+	d, err := w.runtime(&t)
+	if err != nil {
+		return err
+	}
+	return d.Attach(ctx, t.ContainerID, stdin, out, out)
+}
+
+// AttachHandler upgrades the request to a raw stream, as Docker's own
+// attach endpoint does: the client sends "Upgrade: tcp", and after the
+// 101 response whatever it writes goes to the container's stdin while the
+// container's output comes back. Either side closing ends the attach.
+func (a *Api) AttachHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+	t, ok := a.Worker.GetTask(id)
+	if !ok {
+		writeAPIError(w, fmt.Errorf("%w: %v", ErrTaskNotFound, id))
+		return
+	}
+	if t.State.Terminal() || t.ContainerID == "" {
+		writeAPIError(w, fmt.Errorf("%w: task %v is %v", ErrTaskNotRunning, id, t.State))
+		return
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "tcp") {
+		writeError(w, http.StatusBadRequest, "Attaching requires an Upgrade: tcp request")
+		return
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("attaching to task %v: %w", id, err))
+		return
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+
+	// The server stops tracking a hijacked connection, so the attach is
+	// cancelled here once the client closes its side of it.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	var stdin io.Reader = &cancelOnEOF{r: brw.Reader, cancel: cancel}
+	if !t.AttachStdin {
+		go io.Copy(io.Discard, stdin)
+		stdin = nil
+	}
+
+	log.Printf("[%s] Client attached to task %v\n", r.Header.Get(RequestIDHeader), id)
+	err = a.Worker.Attach(ctx, id, stdin, conn)
+	if err != nil {
+		log.Printf("[%s] Attach to task %v ended: %v\n", r.Header.Get(RequestIDHeader), id, err)
+		return
+	}
+	log.Printf("[%s] Client detached from task %v\n", r.Header.Get(RequestIDHeader), id)
+}
+
+// cancelOnEOF calls cancel once reading from r fails, as it does when the
+// client hangs up.
+type cancelOnEOF struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnEOF) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil {
+		c.cancel()
+	}
+	return n, err
+}
@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// CallbackSignatureHeader carries the hex HMAC-SHA256 of a callback's
+// body, keyed with the worker's CallbackSecret, as "sha256=<hex>".
+const CallbackSignatureHeader = "X-Ordo-Signature"
+
+const (
+	defaultCallbackLogBytes = 64 << 10
+	// callbackLogLines and callbackLogTimeout bound how much of a
+	// finished container's log is read for its callback.
+	callbackLogLines    = 10000
+	callbackLogTimeout  = 10 * time.Second
+	callbackAttempts    = 5
+	callbackBackoffBase = 1 * time.Second
+	callbackBackoffMax  = 1 * time.Minute
+)
+
+var callbackClient = &http.Client{Timeout: 10 * time.Second}
+
+// CallbackResult is what a task's CallbackURL receives once the task
+// has finished. Logs is the end of the container's output, cut to
+// CallbackLogBytes.
+type CallbackResult struct {
+	TaskID          uuid.UUID
+	Name            string
+	State           string
+	ExitCode        int
+	Reason          string `json:",omitempty"`
+	StartTime       time.Time
+	FinishTime      time.Time
+	DurationSeconds float64
+	Logs            string
+	LogsTruncated   bool
+}
+
+// taskFinished posts the result of a finished task to its CallbackURL
+// in the background.
+func (w *Worker) taskFinished(t task.Task) {
+	if t.CallbackURL == "" {
+		return
+	}
+	var logs string
+	var truncated bool
+	if t.ContainerID != "" {
+		if d, err := w.runtime(&t); err == nil {
+			logs, truncated = w.callbackLogs(d, t)
+		}
+	}
+	w.sendResult(t, logs, truncated)
+}
+
+// sendResult posts the result of a finished task, with the end of its
+// output, to its CallbackURL in the background.
+func (w *Worker) sendResult(t task.Task, logs string, truncated bool) {
+	res := CallbackResult{
+		TaskID:        t.ID,
+		Name:          t.Name,
+		State:         t.State.String(),
+		ExitCode:      t.ExitCode,
+		Reason:        t.StatusReason,
+		StartTime:     t.StartTime,
+		FinishTime:    t.FinishTime,
+		Logs:          logs,
+		LogsTruncated: truncated,
+	}
+	if !t.StartTime.IsZero() && t.FinishTime.After(t.StartTime) {
+		res.DurationSeconds = t.FinishTime.Sub(t.StartTime).Seconds()
+	}
+	go w.sendCallback(t, res)
+}
+
+// callbackLogs reads the end of the container's output, where a failing
+// job usually says what went wrong. Only the last callbackLogLines lines
+// are asked for, and only CallbackLogBytes of them kept.
+func (w *Worker) callbackLogs(d task.Runtime, t task.Task) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), callbackLogTimeout)
+	defer cancel()
+
+	out := &tailWriter{max: w.callbackLogBytes()}
+	if err := d.StreamLogs(ctx, t.ContainerID, task.LogOptions{Tail: callbackLogLines}, out); err != nil {
+		log.Printf("[%s] Error reading logs of task %v for its callback: %v\n", t.CorrelationID, t.ID, err)
+		return "", false
+	}
+	return string(out.buf), out.truncated || out.lines >= callbackLogLines
+}
+
+func (w *Worker) callbackLogBytes() int {
+	if w.CallbackLogBytes > 0 {
+		return w.CallbackLogBytes
+	}
+	return defaultCallbackLogBytes
+}
+
+// tailWriter keeps the last max bytes written to it.
+type tailWriter struct {
+	max       int
+	buf       []byte
+	lines     int
+	truncated bool
+}
+
+func (tw *tailWriter) Write(p []byte) (int, error) {
+	tw.lines += bytes.Count(p, []byte("\n"))
+	tw.buf = append(tw.buf, p...)
+	if len(tw.buf) > tw.max {
+		tw.buf = append(tw.buf[:0], tw.buf[len(tw.buf)-tw.max:]...)
+		tw.truncated = true
+	}
+	return len(p), nil
+}
+
+// sendCallback posts res, retrying with exponential backoff.
+func (w *Worker) sendCallback(t task.Task, res CallbackResult) {
+	body, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("[%s] Error marshalling callback for task %v: %v\n", t.CorrelationID, t.ID, err)
+		return
+	}
+
+	delay := callbackBackoffBase
+	for i := 1; ; i++ {
+		err = w.postCallback(t.CallbackURL, body, t.CorrelationID)
+		if err == nil {
+			log.Printf("[%s] Sent result of task %v to %s\n", t.CorrelationID, t.ID, t.CallbackURL)
+			return
+		}
+		if i >= callbackAttempts {
+			log.Printf("[%s] Giving up on callback for task %v after %d attempts: %v\n", t.CorrelationID, t.ID, i, err)
+			return
+		}
+		log.Printf("[%s] Error sending callback for task %v, retrying in %v: %v\n", t.CorrelationID, t.ID, delay, err)
+		<-w.clock().After(delay)
+		delay *= 2
+		if delay > callbackBackoffMax {
+			delay = callbackBackoffMax
+		}
+	}
+}
+
+func (w *Worker) postCallback(url string, body []byte, correlationID string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if correlationID != "" {
+		req.Header.Set(RequestIDHeader, correlationID)
+	}
+	if w.CallbackSecret != "" {
+		mac := hmac.New(sha256.New, []byte(w.CallbackSecret))
+		mac.Write(body)
+		req.Header.Set(CallbackSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return nil
+}
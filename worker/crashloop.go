@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// crashLoopReset is how long a container has to stay up for its earlier
+// crashes to be forgotten.
+const crashLoopReset = 10 * time.Minute
+
+// crashLoop tracks a task whose container the worker restarts itself
+// with backoff.
+type crashLoop struct {
+	failures int
+	next     time.Time
+	// reason is why the container last crashed.
+	reason string
+}
+
+// scheduleRestart records a crash of a task with a RestartBackoff and
+// decides when to restart it. It returns false once the task has used up
+// its attempts. w.mu must be held.
+func (w *Worker) scheduleRestart(t *task.Task, reason string) bool {
+	if w.crashLoops == nil {
+		w.crashLoops = make(map[uuid.UUID]*crashLoop)
+	}
+	c, ok := w.crashLoops[t.ID]
+	if !ok || w.clock().Now().Sub(t.StartTime) > crashLoopReset {
+		c = &crashLoop{}
+		w.crashLoops[t.ID] = c
+	}
+	c.failures++
+	c.reason = reason
+
+	if c.failures > t.RestartBackoff.Attempts() {
+		delete(w.crashLoops, t.ID)
+		return false
+	}
+	delay := t.RestartBackoff.Delay(c.failures)
+	c.next = w.clock().Now().Add(delay)
+	t.StatusReason = fmt.Sprintf("%s; restarting in %v (attempt %d/%d)",
+		reason, delay, c.failures, t.RestartBackoff.Attempts())
+	return true
+}
+
+// waitingRestart reports whether the task's container has crashed and is
+// waiting out its backoff.
+func (w *Worker) waitingRestart(id uuid.UUID) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	c, ok := w.crashLoops[id]
+	return ok && !c.next.IsZero()
+}
+
+// restartCrashed replaces the containers of crashed tasks whose backoff
+// has elapsed.
+func (w *Worker) restartCrashed() {
+	w.mu.Lock()
+	var due []task.Task
+	reasons := make(map[uuid.UUID]string)
+	for id, c := range w.crashLoops {
+		t, ok := w.Db[id]
+		if !ok || t.State.Terminal() {
+			delete(w.crashLoops, id)
+			continue
+		}
+		if !c.next.IsZero() && w.clock().Now().After(c.next) {
+			c.next = time.Time{}
+			due = append(due, *t)
+			reasons[id] = c.reason
+		}
+	}
+	w.mu.Unlock()
+
+	for _, t := range due {
+		// The init containers finished before the container first
+		// started; a crash doesn't undo their work.
+		run := t
+		run.InitContainers = nil
+		d, err := w.runtime(&run)
+		if err != nil {
+			w.setState(t.ID, task.Failed, err.Error())
+			continue
+		}
+		if t.ContainerID != "" {
+			if result := d.Remove(t.ContainerID); result.Error != nil {
+				log.Printf("[%s] Error removing crashed container %s: %v\n", t.CorrelationID, t.ContainerID, result.Error)
+			}
+		}
+		pull := w.pull(d, t)
+		result := pull
+		if pull.Error == nil {
+			result = d.Run()
+			result.Timings = startTimings(t, pull, result)
+		}
+
+		w.mu.Lock()
+		stored, ok := w.Db[t.ID]
+		if !ok || stored.State.Terminal() {
+			w.mu.Unlock()
+			continue
+		}
+		stored.RestartCount++
+		stored.StartTime = w.clock().Now().UTC()
+		stored.RecordRestart(task.RestartEvent{
+			Time:        stored.StartTime,
+			Reason:      reasons[t.ID],
+			ExitCode:    t.ExitCode,
+			ContainerID: t.ContainerID,
+		})
+		stored.Timings = result.Timings
+		if result.Error != nil {
+			stored.ContainerID = ""
+			stored.State = task.Failed
+			stored.StatusReason = result.Error.Error()
+			stored.FinishTime = stored.StartTime
+			delete(w.crashLoops, t.ID)
+		} else {
+			stored.ContainerID = result.ContainerId
+			stored.StatusReason = ""
+			if stored.ReadinessProbe != nil {
+				stored.State = task.Starting
+			}
+		}
+		restarted := *stored
+		w.mu.Unlock()
+
+		if result.Error != nil {
+			log.Printf("[%s] Error restarting crashed task %v: %v\n", t.CorrelationID, t.ID, result.Error)
+			continue
+		}
+		w.followLogs(restarted)
+		if restarted.ReadinessProbe != nil || restarted.LivenessProbe != nil {
+			w.startProbes(restarted)
+		}
+		log.Printf("[%s] Restarted crashed task %v (restart %d)\n", t.CorrelationID, t.ID, restarted.RestartCount)
+	}
+}
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// registryAuths keeps the registry credentials tasks arrive with, so the
+// worker can pull their images again on restarts without them showing up
+// in the tasks it reports.
+type registryAuths struct {
+	mu   sync.Mutex
+	auth map[uuid.UUID]task.RegistryAuth
+}
+
+// keep takes t's credentials off it and holds on to them.
+func (a *registryAuths) keep(t *task.Task) {
+	if t.RegistryAuth == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.auth == nil {
+		a.auth = make(map[uuid.UUID]task.RegistryAuth)
+	}
+	a.auth[t.ID] = *t.RegistryAuth
+	t.RegistryAuth = nil
+}
+
+// get returns the credentials task id arrived with, or nil.
+func (a *registryAuths) get(id uuid.UUID) *task.RegistryAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	auth, ok := a.auth[id]
+	if !ok {
+		return nil
+	}
+	return &auth
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// ForceStop makes the next stop of the task skip its deregistration
+// delay.
+func (w *Worker) ForceStop(id uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.forceStops == nil {
+		w.forceStops = make(map[uuid.UUID]bool)
+	}
+	w.forceStops[id] = true
+}
+
+func (w *Worker) takeForceStop(id uuid.UUID) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	force := w.forceStops[id]
+	delete(w.forceStops, id)
+	return force
+}
+
+// deregister takes a running task out of service and stops it once its
+// DeregisterDelay has passed. The task is marked with a status reason so
+// anything routing traffic by what GET /tasks reports can stop sending it
+// requests. It returns whether the task is deregistering, including when
+// an earlier stop already started it, and false for tasks whose container
+// has already exited, which have nothing to drain and are stopped
+// straight away.
+func (w *Worker) deregister(t task.Task) bool {
+	w.mu.Lock()
+	already := w.deregistering[t.ID]
+	w.mu.Unlock()
+	if already {
+		return true
+	}
+
+	d, err := w.runtime(&t)
+	if err != nil {
+		return false
+	}
+	resp := d.Inspect(t.ContainerID)
+	if resp.Error != nil || resp.Container == nil || resp.Container.State == nil || !resp.Container.State.Running {
+		return false
+	}
+
+	w.mu.Lock()
+	if w.deregistering[t.ID] {
+		w.mu.Unlock()
+		return true
+	}
+	if w.deregistering == nil {
+		w.deregistering = make(map[uuid.UUID]bool)
+	}
+	w.deregistering[t.ID] = true
+	if stored, ok := w.Db[t.ID]; ok {
+		stored.StatusReason = fmt.Sprintf("deregistering: stopping in %v", t.DeregisterDelay)
+	}
+	w.mu.Unlock()
+
+	log.Printf("[%s] Deregistered task %v, stopping in %v\n", t.CorrelationID, t.ID, t.DeregisterDelay)
+	go func() {
+		<-w.clock().After(t.DeregisterDelay)
+		w.stopDeregistered(t.ID)
+	}()
+	return true
+}
+
+// stopDeregistered stops a task whose deregistration delay has passed.
+// The task is read afresh, since its container may have been replaced
+// during the delay or the task stopped some other way.
+func (w *Worker) stopDeregistered(id uuid.UUID) {
+	w.mu.Lock()
+	delete(w.deregistering, id)
+	stored, ok := w.Db[id]
+	if !ok || stored.State.Terminal() {
+		w.mu.Unlock()
+		return
+	}
+	t := *stored
+	w.mu.Unlock()
+
+	w.stopTask(t)
+}
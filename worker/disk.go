@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"syscall"
+
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultDiskPath = "/var/lib/docker"
+
+// diskFree returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func diskFree(path string) (uint64, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return 0, err
+	}
+	return fs.Bavail * uint64(fs.Bsize), nil
+}
+
+func (w *Worker) diskPath() string {
+	if w.DiskPath != "" {
+		return w.DiskPath
+	}
+	return defaultDiskPath
+}
+
+// ensureDiskSpace refuses a task when the runtime's disk is below
+// MinFreeDisk, so an image pull can't fill it. If PruneOnLowDisk is set,
+// dangling images are pruned first to try to recover space.
+func (w *Worker) ensureDiskSpace(t task.Task) error {
+	if w.MinFreeDisk == 0 {
+		return nil
+	}
+
+	free, err := diskFree(w.diskPath())
+	if err != nil {
+		return fmt.Errorf("checking free disk space: %w", err)
+	}
+	if free >= w.MinFreeDisk {
+		return nil
+	}
+
+	if w.PruneOnLowDisk {
+		if d, err := w.runtime(&t); err == nil {
+			result, err := d.PruneImages()
+			if err != nil {
+				log.Printf("Error pruning images: %v\n", err)
+			} else {
+				log.Printf("Pruned dangling images, reclaimed %d bytes\n", result.SpaceReclaimed)
+			}
+		}
+		if free, err = diskFree(w.diskPath()); err == nil && free >= w.MinFreeDisk {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d bytes free on %s, need %d", ErrLowDisk, free, w.diskPath(), w.MinFreeDisk)
+}
+
+// PruneImages removes dangling images, or with all every image that
+// neither a container nor a queued or active task uses.
+func (w *Worker) PruneImages(all bool) (task.ImagePruneResult, error) {
+	d, err := w.runtime(&task.Task{})
+	if err != nil {
+		return task.ImagePruneResult{}, err
+	}
+
+	var result task.ImagePruneResult
+	if all {
+		result, err = d.RemoveUnusedImages(w.imagesInUse())
+	} else {
+		result, err = d.PruneImages()
+	}
+	if err != nil {
+		return result, err
+	}
+	log.Printf("Pruned %d images, reclaimed %d bytes\n", len(result.ImagesDeleted), result.SpaceReclaimed)
+	return result, nil
+}
+
+// imagesInUse lists the images of tasks that are queued or not yet
+// finished, whose containers may not exist yet.
+func (w *Worker) imagesInUse() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var images []string
+	for _, t := range w.Db {
+		if !t.State.Terminal() {
+			images = append(images, t.Image)
+		}
+	}
+	for i := w.Queue.Len(); i > 0; i-- {
+		t := w.Queue.Dequeue().(task.Task)
+		images = append(images, t.Image)
+		w.Queue.Enqueue(t)
+	}
+	return images
+}
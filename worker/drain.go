@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultDrainTimeout = 30 * time.Second
+
+var managerClient = &http.Client{Timeout: 5 * time.Second}
+
+// DrainReport lists the tasks Shutdown stopped cleanly and the ones it
+// had to kill after the drain timeout.
+type DrainReport struct {
+	Stopped     []uuid.UUID
+	ForceKilled []uuid.UUID
+}
+
+// Shutdown stops every active task, waiting up to DrainTimeout for their
+// containers to exit before killing the rest. The manager is told first
+// so it can reschedule the tasks while they are still stopping.
+func (w *Worker) Shutdown() DrainReport {
+	w.mu.Lock()
+	var tasks []task.Task
+	for _, t := range w.Db {
+		if !t.State.Terminal() && t.ContainerID != "" {
+			tasks = append(tasks, *t)
+		}
+	}
+	w.mu.Unlock()
+
+	var report DrainReport
+	if len(tasks) == 0 {
+		return report
+	}
+
+	if w.Manager != "" {
+		if err := w.notifyDraining(tasks); err != nil {
+			log.Printf("Error notifying manager of shutdown: %v\n", err)
+		}
+	}
+
+	timeout := w.DrainTimeout
+	if timeout == 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	done := make(chan uuid.UUID, len(tasks))
+	for _, t := range tasks {
+		w.stopProbes(t.ID)
+		go func(t task.Task) {
+			d, err := w.runtime(&t)
+			if err != nil {
+				log.Printf("Error stopping task %v: %v\n", t.ID, err)
+				return
+			}
+			if result := w.stop(d, t); result.Error == nil {
+				done <- t.ID
+			}
+		}(t)
+	}
+
+	stopped := make(map[uuid.UUID]bool)
+	deadline := w.clock().After(timeout)
+wait:
+	for len(stopped) < len(tasks) {
+		select {
+		case id := <-done:
+			stopped[id] = true
+		case <-deadline:
+			break wait
+		}
+	}
+
+	for _, t := range tasks {
+		if stopped[t.ID] {
+			w.setState(t.ID, task.Completed, "worker shut down")
+			report.Stopped = append(report.Stopped, t.ID)
+			continue
+		}
+		if d, err := w.runtime(&t); err == nil {
+			d.Kill(t.ContainerID)
+		}
+		w.setState(t.ID, task.Failed, fmt.Sprintf("killed after not stopping within %v of worker shutdown", timeout))
+		report.ForceKilled = append(report.ForceKilled, t.ID)
+	}
+
+	log.Printf("Worker %s drained: %d tasks stopped, %d force-killed %v\n",
+		w.Name, len(report.Stopped), len(report.ForceKilled), report.ForceKilled)
+	return report
+}
+
+func (w *Worker) notifyDraining(tasks []task.Task) error {
+	ids := make([]uuid.UUID, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	body, err := json.Marshal(struct{ Tasks []uuid.UUID }{ids})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/nodes/%s/drain", w.Manager, w.Name), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.ManagerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+w.ManagerToken)
+	}
+	resp, err := managerClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("manager returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/sajalkmr/ordo/task"
+)
+
+// ErrResponse is the body of every API error.
+type ErrResponse struct {
+	Error ErrBody `json:"error"`
+}
+
+type ErrBody struct {
+	Code          string         `json:"code"`
+	Message       string         `json:"message"`
+	Details       map[string]any `json:"details,omitempty"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+}
+
+// Error codes are part of the API; the manager and other clients branch
+// on them, so existing codes must not change.
+const (
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeNotFound         = "NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	CodeTaskNotFound     = "TASK_NOT_FOUND"
+	CodeTaskNotRunning   = "TASK_NOT_RUNNING"
+	CodeTaskRunning      = "TASK_RUNNING"
+	CodeAtCapacity       = "AT_CAPACITY"
+	CodeLowDisk          = "LOW_DISK"
+	CodePullFailed       = "PULL_FAILED"
+	CodePullTimeout      = "PULL_TIMEOUT"
+	CodeNotManaged       = "NOT_MANAGED"
+	CodeInternal         = "INTERNAL_ERROR"
+)
+
+// errorCodes maps the worker's typed errors to a status and code. The
+// first match wins.
+var errorCodes = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrTaskNotFound, http.StatusNotFound, CodeTaskNotFound},
+	{ErrTaskNotRunning, http.StatusConflict, CodeTaskNotRunning},
+	{ErrTaskRunning, http.StatusConflict, CodeTaskRunning},
+	{ErrAtCapacity, http.StatusTooManyRequests, CodeAtCapacity},
+	{ErrLowDisk, http.StatusInsufficientStorage, CodeLowDisk},
+	{task.ErrImagePullTimeout, http.StatusGatewayTimeout, CodePullTimeout},
+	{ErrPullFailed, http.StatusBadGateway, CodePullFailed},
+	{ErrNotManaged, http.StatusConflict, CodeNotManaged},
+}
+
+// writeAPIError writes err with the status and code of the typed error it
+// wraps.
+func writeAPIError(w http.ResponseWriter, err error) {
+	body := ErrBody{Code: CodeInternal, Message: err.Error()}
+	status := http.StatusInternalServerError
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			status, body.Code = c.status, c.code
+			break
+		}
+	}
+	writeErrorBody(w, status, body)
+}
+
+// writeError writes an error that doesn't come from a typed error, such
+// as a malformed request, with a generic code for the status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	code := CodeInternal
+	switch status {
+	case http.StatusBadRequest:
+		code = CodeValidationFailed
+	case http.StatusNotFound:
+		code = CodeNotFound
+	case http.StatusMethodNotAllowed:
+		code = CodeMethodNotAllowed
+	}
+	writeErrorBody(w, status, ErrBody{Code: code, Message: msg})
+}
+
+func writeErrorBody(w http.ResponseWriter, status int, body ErrBody) {
+	body.CorrelationID = w.Header().Get(RequestIDHeader)
+	log.Printf("[%s] %s: %s\n", body.CorrelationID, body.Code, body.Message)
+	writeJSON(w, status, ErrResponse{Error: body})
+}
+
+// jsonErrors rewrites the plain-text errors written outside our handlers,
+// such as the router's 404 and 405 responses, into the JSON error format.
+func jsonErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&errorWriter{ResponseWriter: w}, r)
+	})
+}
+
+type errorWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (ew *errorWriter) WriteHeader(status int) {
+	if status >= http.StatusBadRequest && strings.HasPrefix(ew.Header().Get("Content-Type"), "text/plain") {
+		ew.replaced = true
+		ew.Header().Del("X-Content-Type-Options")
+		writeError(ew.ResponseWriter, status, http.StatusText(status))
+		return
+	}
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+func (ew *errorWriter) Write(b []byte) (int, error) {
+	if ew.replaced {
+		return len(b), nil
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// handlers can hijack the connection.
+func (ew *errorWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
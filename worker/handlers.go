@@ -0,0 +1,294 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+func (a *Api) StartTaskHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	te := task.TaskEvent{}
+	err := d.Decode(&te)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	if te.Task.CorrelationID == "" {
+		te.Task.CorrelationID = r.Header.Get(RequestIDHeader)
+	}
+
+	err = a.Worker.AddTask(te.Task)
+	if errors.Is(err, ErrAtCapacity) {
+		err = fmt.Errorf("%w: worker %s has %d tasks", err, a.Worker.Name, a.Worker.MaxTasks)
+	}
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	log.Printf("[%s] Added task %v\n", te.Task.CorrelationID, te.Task.ID)
+	te.Task.RegistryAuth = nil
+	writeJSON(w, http.StatusCreated, te.Task)
+}
+
+type ResourcePatch struct {
+	CPU    float64
+	Memory int64
+}
+
+func (a *Api) PatchResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	var p ResourcePatch
+	err = json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	err = a.Worker.UpdateTaskResources(id, p.CPU, p.Memory)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("updating task %v: %w", id, err))
+		return
+	}
+	t, _ := a.Worker.GetTask(id)
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (a *Api) GetTasksHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Worker.GetTasks())
+}
+
+// PruneImagesHandler removes dangling images, or with ?all=true every
+// image no container or pending task needs.
+func (a *Api) PruneImagesHandler(w http.ResponseWriter, r *http.Request) {
+	all := false
+	if v := r.URL.Query().Get("all"); v != "" {
+		var err error
+		all, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid all parameter %q", v))
+			return
+		}
+	}
+
+	result, err := a.Worker.PruneImages(all)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("pruning images: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// PullImageHandler pulls the image of the task in the body, using its
+// registry credentials, without starting it.
+func (a *Api) PullImageHandler(w http.ResponseWriter, r *http.Request) {
+	var t task.Task
+	err := json.NewDecoder(r.Body).Decode(&t)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+	if t.Image == "" {
+		writeError(w, http.StatusBadRequest, "No image given")
+		return
+	}
+	t.CorrelationID = r.Header.Get(RequestIDHeader)
+
+	result, err := a.Worker.PullImage(t)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GetContainersHandler lists the containers ordo created on this worker,
+// whether or not a task tracks them.
+func (a *Api) GetContainersHandler(w http.ResponseWriter, r *http.Request) {
+	containers, err := a.Worker.ManagedContainers()
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("listing containers: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, containers)
+}
+
+func (a *Api) AdoptContainerHandler(w http.ResponseWriter, r *http.Request) {
+	t, err := a.Worker.AdoptContainer(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (a *Api) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Worker.GetStats())
+}
+
+// GetTaskStatsHandler returns one task's usage, including network
+// traffic. The per-interface breakdown is left out unless
+// ?interfaces=true.
+func (a *Api) GetTaskStatsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+	interfaces := false
+	if v := r.URL.Query().Get("interfaces"); v != "" {
+		interfaces, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid interfaces parameter %q", v))
+			return
+		}
+	}
+
+	u, err := a.Worker.TaskStats(id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if !interfaces {
+		u.Network.Interfaces = nil
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (a *Api) GetTaskUsageHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Worker.TaskUsage())
+}
+
+func (a *Api) StopTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	t, ok := a.Worker.GetTask(id)
+	if !ok {
+		writeAPIError(w, fmt.Errorf("%w: %v", ErrTaskNotFound, id))
+		return
+	}
+	if force, _ := strconv.ParseBool(r.URL.Query().Get("force")); force {
+		a.Worker.ForceStop(id)
+	}
+
+	t.State = task.Completed
+	if err := a.Worker.AddTask(t); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	log.Printf("[%s] Added task %v to stop container %v\n", t.CorrelationID, t.ID, t.ContainerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestartRequest optionally switches a task to a new image as part of a
+// restart.
+type RestartRequest struct {
+	Image string
+	// RerunInit runs the task's init containers again before the new
+	// container starts. Restarts skip them otherwise.
+	RerunInit bool
+}
+
+func (a *Api) RestartTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	var req RestartRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	t, err := a.Worker.RestartTask(id, req)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("restarting task %v: %w", id, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (a *Api) RemoveContainerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	err = a.Worker.RemoveContainer(id)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("removing container of task %v: %w", id, err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) GetTaskDiffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	changes, err := a.Worker.DiffTask(id)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("diffing task %v: %w", id, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+type TaskPatch struct {
+	Name string
+}
+
+func (a *Api) PatchTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+
+	var p TaskPatch
+	err = json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+
+	err = a.Worker.RenameTask(id, p.Name)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("renaming task %v: %w", id, err))
+		return
+	}
+	t, _ := a.Worker.GetTask(id)
+	writeJSON(w, http.StatusOK, t)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// memInfo returns the host's total and used memory in bytes, counting
+// reclaimable page cache as free.
+func memInfo() (uint64, uint64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var total, available uint64
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = kb * 1024
+		case "MemAvailable:":
+			available = kb * 1024
+		}
+	}
+	if err := s.Err(); err != nil {
+		return 0, 0, err
+	}
+	if available > total {
+		return total, 0, nil
+	}
+	return total, total - available, nil
+}
+
+// cpuSample is the busy and total jiffies across all CPUs.
+type cpuSample struct {
+	busy, total uint64
+}
+
+func readCPUSample() (cpuSample, error) {
+	b, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuSample{}, err
+	}
+	line, _, _ := strings.Cut(string(b), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuSample{}, fmt.Errorf("unexpected /proc/stat line %q", line)
+	}
+
+	var s cpuSample
+	for i, f := range fields[1:] {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return cpuSample{}, err
+		}
+		s.total += v
+		// idle and iowait
+		if i != 3 && i != 4 {
+			s.busy += v
+		}
+	}
+	return s, nil
+}
+
+// busySince returns the fraction of CPU time spent busy since prev, or
+// since boot if there is no prev.
+func (s cpuSample) busySince(prev cpuSample) float64 {
+	if prev.total == 0 || s.total < prev.total || s.busy < prev.busy {
+		prev = cpuSample{}
+	}
+	if s.total == prev.total {
+		return 0
+	}
+	return float64(s.busy-prev.busy) / float64(s.total-prev.total)
+}
+
+// diskTotal returns the size of the filesystem holding path.
+func diskTotal(path string) (uint64, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return 0, err
+	}
+	return fs.Blocks * uint64(fs.Bsize), nil
+}
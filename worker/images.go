@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// imageRefs counts the tasks other than except that still need image:
+// unfinished tasks and queued starts.
+func (w *Worker) imageRefs(image string, except uuid.UUID) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	refs := 0
+	for _, t := range w.Db {
+		if t.ID != except && !t.State.Terminal() && t.Image == image {
+			refs++
+		}
+	}
+	for i := w.Queue.Len(); i > 0; i-- {
+		t := w.Queue.Dequeue().(task.Task)
+		if t.ID != except && t.State != task.Completed && t.Image == image {
+			refs++
+		}
+		w.Queue.Enqueue(t)
+	}
+	return refs
+}
+
+// releaseImage removes a finished task's image once no other task on the
+// worker uses it, if the task or the worker asks for that. Docker keeps
+// layers shared with other images, and an image a leftover container
+// still uses is kept rather than forced out.
+func (w *Worker) releaseImage(t task.Task) {
+	if !w.RemoveImagesOnStop && !t.RemoveImageOnStop {
+		return
+	}
+	if refs := w.imageRefs(t.Image, t.ID); refs > 0 {
+		log.Printf("[%s] Keeping image %s, still used by %d tasks\n", t.CorrelationID, t.Image, refs)
+		return
+	}
+
+	d, err := w.runtime(&t)
+	if err != nil {
+		log.Printf("[%s] Error removing image %s: %v\n", t.CorrelationID, t.Image, err)
+		return
+	}
+	result, err := d.RemoveImage(t.Image)
+	switch {
+	case errors.Is(err, task.ErrImageInUse):
+		log.Printf("[%s] Keeping image %s: %v\n", t.CorrelationID, t.Image, err)
+	case err != nil:
+		log.Printf("[%s] Error removing image %s: %v\n", t.CorrelationID, t.Image, err)
+	default:
+		log.Printf("[%s] Removed image %s (%d layers)\n", t.CorrelationID, t.Image, len(result.ImagesDeleted))
+	}
+}
+
+// ImagePull is the result of pulling an image ahead of any task that
+// uses it.
+type ImagePull struct {
+	Image string
+	// Source is the mirror or registry the image came from.
+	Source   string
+	Duration time.Duration
+}
+
+// PullImage pulls t's image without running anything, so tasks started
+// later find it cached. The pull takes a pull token like any other.
+func (w *Worker) PullImage(t task.Task) (ImagePull, error) {
+	t.Build = nil
+	d, err := w.runtime(&t)
+	if err != nil {
+		return ImagePull{}, err
+	}
+	result := w.pull(d, t)
+	if result.Error != nil {
+		return ImagePull{}, fmt.Errorf("%w: %s: %w", ErrPullFailed, t.Image, result.Error)
+	}
+	log.Printf("[%s] Pulled image %s from %s in %v\n", t.CorrelationID, t.Image, result.Result, result.Timings.Pull)
+	return ImagePull{Image: t.Image, Source: result.Result, Duration: result.Timings.Pull}, nil
+}
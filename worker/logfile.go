@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/sajalkmr/ordo/task"
+)
+
+const (
+	defaultLogMaxBytes = 10 << 20
+	defaultLogMaxFiles = 3
+)
+
+// followLogs copies the output of a task with LogToFile to its log file
+// until the container stops, and records the file's path on the task.
+func (w *Worker) followLogs(t task.Task) {
+	if !t.LogToFile || t.ContainerID == "" {
+		return
+	}
+	if w.LogDir == "" {
+		log.Printf("[%s] Task %v asks for a log file but the worker has no log directory\n", t.CorrelationID, t.ID)
+		return
+	}
+
+	shortID := t.ContainerID
+	if len(shortID) > 12 {
+		shortID = shortID[:12]
+	}
+	path := filepath.Join(w.LogDir, fmt.Sprintf("%s-%s.log", t.Name, shortID))
+
+	w.mu.Lock()
+	if stored, ok := w.Db[t.ID]; ok {
+		stored.LogFile = path
+	}
+	w.mu.Unlock()
+
+	d, err := w.runtime(&t)
+	if err != nil {
+		return
+	}
+	go func() {
+		f := &rotatingFile{path: path, maxBytes: w.LogMaxBytes, maxFiles: w.LogMaxFiles}
+		defer f.Close()
+		if err := d.FollowLogs(context.Background(), t.ContainerID, f); err != nil {
+			log.Printf("[%s] Error writing logs of task %v to %s: %v\n", t.CorrelationID, t.ID, path, err)
+		}
+	}()
+}
+
+// rotatingFile appends to path, renaming it to path.1 (and path.1 to
+// path.2 and so on) once it reaches maxBytes.
+type rotatingFile struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	f    *os.File
+	size int64
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	maxBytes := r.maxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultLogMaxBytes
+	}
+	if r.size > 0 && r.size+int64(len(p)) > maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+
+	maxFiles := r.maxFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultLogMaxFiles
+	}
+	os.Remove(fmt.Sprintf("%s.%d", r.path, maxFiles))
+	for i := maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// Logs writes the output of a task's container to out, following it
+// when opts.Follow is set.
+func (w *Worker) Logs(ctx context.Context, id uuid.UUID, opts task.LogOptions, out io.Writer) error {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if t.ContainerID == "" {
+		return fmt.Errorf("%w: task %v has no container", ErrTaskNotRunning, id)
+	}
+	d, err := w.runtime(&t)
+	if err != nil {
+		return err
+	}
+	return d.StreamLogs(ctx, t.ContainerID, opts, out)
+}
+
+// LogsHandler streams a task's output as plain text. It accepts
+// ?follow=true, ?tail=N for the last N lines and an RFC 3339 ?since=.
+func (a *Api) LogsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid task ID %q", r.PathValue("id")))
+		return
+	}
+	opts, err := parseLogOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	t, ok := a.Worker.GetTask(id)
+	if !ok {
+		writeAPIError(w, fmt.Errorf("%w: %v", ErrTaskNotFound, id))
+		return
+	}
+	if t.ContainerID == "" {
+		writeAPIError(w, fmt.Errorf("%w: task %v has no container", ErrTaskNotRunning, id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	err = a.Worker.Logs(r.Context(), id, opts, &flushWriter{w: w, rc: http.NewResponseController(w)})
+	if err != nil && r.Context().Err() == nil {
+		log.Printf("[%s] Error streaming logs of task %v: %v\n", r.Header.Get(RequestIDHeader), id, err)
+	}
+}
+
+func parseLogOptions(r *http.Request) (task.LogOptions, error) {
+	var opts task.LogOptions
+	q := r.URL.Query()
+	if v := q.Get("follow"); v != "" {
+		follow, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid follow parameter %q", v)
+		}
+		opts.Follow = follow
+	}
+	if v := q.Get("tail"); v != "" {
+		tail, err := strconv.Atoi(v)
+		if err != nil || tail < 0 {
+			return opts, fmt.Errorf("Invalid tail parameter %q", v)
+		}
+		opts.Tail = tail
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid since parameter %q", v)
+		}
+		opts.Since = since
+	}
+	return opts, nil
+}
+
+// flushWriter flushes every write so followed logs reach the client as
+// they're produced.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		f.rc.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const (
+	defaultMissingContainerGrace  = 30 * time.Second
+	defaultMissingContainerChecks = 3
+)
+
+// missingContainer tracks an active task whose container the runtime
+// says doesn't exist.
+type missingContainer struct {
+	containerID string
+	since       time.Time
+	checks      int
+}
+
+// containerMissing records that t's container wasn't found and reports
+// whether it has now been missing long enough, and often enough, to
+// count as gone. A daemon that's restarting can briefly lose track of
+// containers it still has, so one miss isn't enough. w.mu must be held.
+func (w *Worker) containerMissing(t task.Task) (gone bool, reason string) {
+	grace, checks := w.MissingContainerGrace, w.MissingContainerChecks
+	if grace <= 0 {
+		grace = defaultMissingContainerGrace
+	}
+	if checks <= 0 {
+		checks = defaultMissingContainerChecks
+	}
+
+	if w.missing == nil {
+		w.missing = make(map[uuid.UUID]*missingContainer)
+	}
+	now := w.clock().Now()
+	m, ok := w.missing[t.ID]
+	if !ok || m.containerID != t.ContainerID {
+		m = &missingContainer{containerID: t.ContainerID, since: now}
+		w.missing[t.ID] = m
+	}
+	m.checks++
+
+	missingFor := now.Sub(m.since)
+	if m.checks < checks || missingFor < grace {
+		log.Printf("[%s] Container %s of task %v not found (check %d, missing for %v), waiting before failing it\n",
+			t.CorrelationID, t.ContainerID, t.ID, m.checks, missingFor.Round(time.Second))
+		return false, ""
+	}
+	delete(w.missing, t.ID)
+	return true, fmt.Sprintf("container %s disappeared: not found in %d checks over %v",
+		t.ContainerID, m.checks, missingFor.Round(time.Second))
+}
+
+// containerFound forgets earlier misses of the task's container. w.mu
+// must be held.
+func (w *Worker) containerFound(id uuid.UUID) {
+	delete(w.missing, id)
+}
+
+// failMissing fails a task whose container has been missing past the
+// grace period.
+func (w *Worker) failMissing(t task.Task) {
+	w.mu.Lock()
+	finished := task.Task{}
+	if stored, ok := w.Db[t.ID]; ok && stored.ContainerID == t.ContainerID && !stored.State.Terminal() {
+		if gone, reason := w.containerMissing(t); gone {
+			stored.FinishTime = w.clock().Now().UTC()
+			stored.State = task.Failed
+			stored.StatusReason = reason
+			finished = *stored
+		}
+	}
+	w.mu.Unlock()
+	if !finished.State.Terminal() {
+		return
+	}
+
+	w.stopProbes(t.ID)
+	log.Printf("[%s] Task %v failed: %s\n", t.CorrelationID, t.ID, finished.StatusReason)
+	w.taskFinished(finished)
+	w.releaseImage(t)
+}
@@ -0,0 +1,198 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// startProbes runs the task's readiness probe, if any, and then its
+// liveness probe until the task stops.
+func (w *Worker) startProbes(t task.Task) {
+	w.stopProbes(t.ID)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.probes == nil {
+		w.probes = make(map[uuid.UUID]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.probes[t.ID] = cancel
+
+	go func() {
+		if t.ReadinessProbe != nil && !w.runReadinessProbe(ctx, t) {
+			return
+		}
+		if t.LivenessProbe != nil {
+			w.runLivenessProbe(ctx, t)
+		}
+	}()
+}
+
+func (w *Worker) stopProbes(id uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if cancel, ok := w.probes[id]; ok {
+		cancel()
+		delete(w.probes, id)
+	}
+}
+
+// runReadinessProbe keeps the task Starting until its readiness probe
+// passes and then marks it Running. If the probe fails FailureThreshold
+// times in a row the container is stopped and the task marked Failed.
+func (w *Worker) runReadinessProbe(ctx context.Context, t task.Task) bool {
+	p := t.ReadinessProbe
+	d, err := w.runtime(&t)
+	if err != nil {
+		w.setState(t.ID, task.Failed, err.Error())
+		return false
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-w.clock().After(p.InitialDelay):
+	}
+
+	timer := w.clock().NewTimer(p.PeriodOrDefault())
+	defer timer.Stop()
+
+	failures := 0
+	for {
+		err := probeContainer(ctx, d, t.ContainerID, p)
+		if ctx.Err() != nil {
+			return false
+		}
+		if err == nil {
+			log.Printf("[%s] Task %v is ready\n", t.CorrelationID, t.ID)
+			w.setState(t.ID, task.Running, "")
+			w.recordRunning(t.ID)
+			return true
+		}
+
+		failures++
+		log.Printf("[%s] Readiness probe for task %v failed (%d/%d): %v\n",
+			t.CorrelationID, t.ID, failures, p.FailureThresholdOrDefault(), err)
+		if failures >= p.FailureThresholdOrDefault() {
+			w.stop(d, t)
+			w.setState(t.ID, task.Failed, fmt.Sprintf("readiness probe failed: %v", err))
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C():
+			timer.Reset(p.PeriodOrDefault())
+		}
+	}
+}
+
+func (w *Worker) runLivenessProbe(ctx context.Context, t task.Task) {
+	p := t.LivenessProbe
+	d, err := w.runtime(&t)
+	if err != nil {
+		log.Printf("[%s] Error starting liveness probe for task %v: %v\n", t.CorrelationID, t.ID, err)
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-w.clock().After(p.InitialDelay):
+	}
+
+	timer := w.clock().NewTimer(p.PeriodOrDefault())
+	defer timer.Stop()
+
+	failures := 0
+	for {
+		err := probeContainer(ctx, d, t.ContainerID, p)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+		} else {
+			failures++
+			log.Printf("[%s] Liveness probe for task %v failed (%d/%d): %v\n",
+				t.CorrelationID, t.ID, failures, p.FailureThresholdOrDefault(), err)
+		}
+
+		if failures >= p.FailureThresholdOrDefault() {
+			log.Printf("[%s] Restarting task %v after failed liveness probes\n", t.CorrelationID, t.ID)
+			result := d.Restart(t.ContainerID)
+			if result.Error != nil {
+				log.Printf("[%s] Error restarting task %v: %v\n", t.CorrelationID, t.ID, result.Error)
+			}
+			failures = 0
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.clock().After(p.InitialDelay):
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+			timer.Reset(p.PeriodOrDefault())
+		}
+	}
+}
+
+// probeContainer runs the probe against the host port Docker published
+// the probe's container port on.
+func probeContainer(ctx context.Context, d task.Runtime, containerID string, p *task.Probe) error {
+	if p.Exec != nil {
+		return execProbe(ctx, d, containerID, p)
+	}
+
+	resp := d.Inspect(containerID)
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	port := nat.Port(fmt.Sprintf("%d/tcp", p.Port()))
+	bindings := resp.Container.NetworkSettings.Ports[port]
+	if len(bindings) == 0 {
+		return fmt.Errorf("container port %s is not published", port)
+	}
+
+	host := bindings[0].HostIP
+	if host == "" || host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+	return p.Check(ctx, net.JoinHostPort(host, bindings[0].HostPort))
+}
+
+// execProbe runs the probe's command in the container, failing on a
+// non-zero exit or when it outlives the probe's timeout.
+func execProbe(ctx context.Context, d task.Runtime, containerID string, p *task.Probe) error {
+	ctx, cancel := context.WithTimeout(ctx, p.TimeoutOrDefault())
+	defer cancel()
+
+	result, err := d.Exec(ctx, containerID, p.Exec.Command)
+	if err != nil {
+		return fmt.Errorf("exec probe %v: %w", p.Exec.Command, err)
+	}
+	if result.ExitCode != 0 {
+		out := strings.TrimSpace(result.Stderr)
+		if out == "" {
+			out = strings.TrimSpace(result.Stdout)
+		}
+		return fmt.Errorf("exec probe %v exited with code %d: %s", p.Exec.Command, result.ExitCode, out)
+	}
+	return nil
+}
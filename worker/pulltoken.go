@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/sajalkmr/ordo/task"
+)
+
+// defaultPullTokenTimeout bounds the wait for a pull token. Tasks start
+// from the worker's queue, so stops and deletes queued behind a start
+// wait with it.
+const defaultPullTokenTimeout = 2 * time.Minute
+
+// pullTokenClient has no timeout: the manager holds token requests open
+// while the cluster is at its pull limit. Requests are bounded by their
+// context instead.
+var pullTokenClient = &http.Client{}
+
+// pull pulls the task's image, first taking a pull token from the
+// manager so the cluster stays within its concurrent pull limit. If the
+// manager can't be asked the pull goes ahead anyway rather than leaving
+// the task stuck.
+func (w *Worker) pull(d task.Runtime, t task.Task) task.DockerResult {
+	if w.Manager != "" && t.Build == nil {
+		timeout := w.PullTokenTimeout
+		if timeout <= 0 {
+			timeout = defaultPullTokenTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		token, err := w.acquirePullToken(ctx, t.Image, t.CorrelationID)
+		switch {
+		case ctx.Err() != nil:
+			return task.DockerResult{Action: "pull", Error: fmt.Errorf("no pull token for %s within %v", t.Image, timeout)}
+		case err != nil:
+			log.Printf("[%s] Pulling %s without a pull token: %v\n", t.CorrelationID, t.Image, err)
+		case token != "":
+			defer w.releasePullToken(token, t.CorrelationID)
+		}
+	}
+	return d.Pull()
+}
+
+func (w *Worker) acquirePullToken(ctx context.Context, image, correlationID string) (string, error) {
+	body, err := json.Marshal(struct{ Node, Image string }{w.Name, image})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Manager+"/pulls/tokens", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if correlationID != "" {
+		req.Header.Set(RequestIDHeader, correlationID)
+	}
+
+	resp, err := pullTokenClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("manager returned %d", resp.StatusCode)
+	}
+	var token struct{ Token string }
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	return token.Token, nil
+}
+
+func (w *Worker) releasePullToken(token, correlationID string) {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/pulls/tokens/%s", w.Manager, token), nil)
+	if err != nil {
+		return
+	}
+	if correlationID != "" {
+		req.Header.Set(RequestIDHeader, correlationID)
+	}
+	resp, err := managerClient.Do(req)
+	if err != nil {
+		log.Printf("[%s] Error releasing pull token %s: %v\n", correlationID, token, err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/sajalkmr/ordo/task"
+)
+
+// Stats reports the worker's task counts and what the host is actually
+// using, as opposed to what the manager has allocated on it.
+type Stats struct {
+	TaskCount int
+	MaxTasks  int
+	DiskFree  uint64
+	DiskTotal uint64
+	MemTotal  uint64
+	MemUsed   uint64
+	CPUs      int
+	// CPUUsed is the number of cores' worth of CPU time in use.
+	CPUUsed float64
+	// UsernsRemap is the daemon's userns-remap mode, "disabled" or the
+	// host UID:GID container root maps to.
+	UsernsRemap string
+	// StatsCalls is how many container stats requests the worker has
+	// made to the runtime.
+	StatsCalls uint64
+	// Stops counts container stops, including those waiting for a slot.
+	Stops StopStats
+	// Network is the traffic of the running tasks as of their latest
+	// samples.
+	Network InterfaceUsage
+}
+
+func (w *Worker) GetStats() Stats {
+	free, err := diskFree(w.diskPath())
+	if err != nil {
+		log.Printf("Error reading free disk space on %s: %v\n", w.diskPath(), err)
+	}
+	disk, err := diskTotal(w.diskPath())
+	if err != nil {
+		log.Printf("Error reading disk size of %s: %v\n", w.diskPath(), err)
+	}
+	memTotal, memUsed, err := memInfo()
+	if err != nil {
+		log.Printf("Error reading memory usage: %v\n", err)
+	}
+	cpu, err := readCPUSample()
+	if err != nil {
+		log.Printf("Error reading CPU usage: %v\n", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// CPU usage is measured since the previous call rather than by
+	// sampling twice here, which would hold up every call.
+	var busy float64
+	if err == nil {
+		busy = cpu.busySince(w.cpuPrev)
+		w.cpuPrev = cpu
+	}
+
+	return Stats{
+		TaskCount: w.activeTasks(),
+		MaxTasks:  w.MaxTasks,
+		DiskFree:  free,
+		DiskTotal: disk,
+		MemTotal:  memTotal,
+		MemUsed:   memUsed,
+		CPUs:      runtime.NumCPU(),
+		CPUUsed:   busy * float64(runtime.NumCPU()),
+
+		UsernsRemap: w.usernsRemap.String(),
+		StatsCalls:  atomic.LoadUint64(&w.statsCalls),
+		Stops:       w.stops.snapshot(),
+		Network:     w.networkTotal(),
+	}
+}
+
+// networkTotal sums the network usage of the latest samples. w.mu must
+// be held.
+func (w *Worker) networkTotal() InterfaceUsage {
+	var total InterfaceUsage
+	for _, s := range w.samples {
+		total.add(s.usage.Network.InterfaceUsage)
+	}
+	return total
+}
+
+// DetectUsernsRemap asks the runtime whether it remaps user namespaces,
+// so mounts can be prepared for the remapped root user and the mode
+// reported in Stats. It fails if the runtime can't be set up.
+func (w *Worker) DetectUsernsRemap() error {
+	d, err := task.NewRuntime(w.Runtime, &task.Config{})
+	if err != nil {
+		return err
+	}
+	remap, err := d.UsernsRemap(context.Background())
+	if err != nil {
+		log.Printf("Error detecting userns-remap mode: %v\n", err)
+		return nil
+	}
+	if remap.Enabled {
+		log.Printf("Runtime remaps container root to host user %v\n", remap)
+	}
+
+	w.mu.Lock()
+	w.usernsRemap = remap
+	w.mu.Unlock()
+	return nil
+}
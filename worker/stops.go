@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/sajalkmr/ordo/task"
+)
+
+const defaultMaxConcurrentStops = 10
+
+// StopStats counts the worker's container stops. Queued stops are
+// waiting for one of the MaxConcurrentStops slots.
+type StopStats struct {
+	InFlight int
+	Queued   int
+	Stopped  uint64
+	Failed   uint64
+}
+
+// stopLimiter bounds how many containers are stopped at once, so tearing
+// down many tasks together doesn't swamp the daemon.
+type stopLimiter struct {
+	once  sync.Once
+	slots chan struct{}
+
+	mu    sync.Mutex
+	stats StopStats
+}
+
+// stop stops t's container, first waiting for a slot if
+// MaxConcurrentStops stops are already running.
+func (w *Worker) stop(d task.Runtime, t task.Task) task.DockerResult {
+	l := &w.stops
+	l.once.Do(func() {
+		limit := w.MaxConcurrentStops
+		if limit <= 0 {
+			limit = defaultMaxConcurrentStops
+		}
+		l.slots = make(chan struct{}, limit)
+	})
+
+	l.mu.Lock()
+	l.stats.Queued++
+	l.mu.Unlock()
+	l.slots <- struct{}{}
+	l.mu.Lock()
+	l.stats.Queued--
+	l.stats.InFlight++
+	l.mu.Unlock()
+
+	result := d.Stop(t.ContainerID)
+	<-l.slots
+
+	l.mu.Lock()
+	l.stats.InFlight--
+	if result.Error != nil {
+		l.stats.Failed++
+	} else {
+		l.stats.Stopped++
+	}
+	l.mu.Unlock()
+	return result
+}
+
+func (l *stopLimiter) snapshot() StopStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// startTimings combines the phases of pulling and running a task's
+// container. Without a readiness probe the task is Running as soon as
+// the container has started.
+func startTimings(t task.Task, pull, run task.DockerResult) task.Timings {
+	tm := run.Timings
+	if pull.Timings.Pull > 0 {
+		tm.Pull = pull.Timings.Pull
+	}
+	if t.ReadinessProbe == nil {
+		tm.StartToRunning = tm.Start
+	}
+	return tm
+}
+
+// recordRunning records how long a task took from container start to
+// passing its readiness probe.
+func (w *Worker) recordRunning(id uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.Db[id]; ok && !t.Timings.Started.IsZero() {
+		t.Timings.StartToRunning = time.Since(t.Timings.Started)
+	}
+}
@@ -0,0 +1,276 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+const (
+	defaultStatsBaseInterval = 10 * time.Second
+	defaultStatsMaxInterval  = 2 * time.Minute
+	// Tasks younger than statsWarmup, or using more than statsNearLimit
+	// of their CPU or memory limit, are always sampled at the base
+	// interval.
+	statsWarmup    = 5 * time.Minute
+	statsNearLimit = 0.8
+	// A sample within statsStableChange of the previous one counts as
+	// stable and doubles the task's interval.
+	statsStableChange = 0.05
+)
+
+// TaskUsage is what a running task's container is actually using. CPU is
+// the cores it averaged between the two latest samples, or since it
+// started for its first sample. SampledAt is when the sample was taken:
+// stable tasks are sampled less often, so it can be older than the
+// request.
+type TaskUsage struct {
+	ID        uuid.UUID
+	Name      string
+	CPU       float64
+	Memory    int64
+	Network   NetworkUsage
+	SampledAt time.Time
+}
+
+// InterfaceUsage is network traffic: the counters since the container
+// started, and the rates between the two latest samples.
+type InterfaceUsage struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+
+	RxBytesPerSec   float64
+	TxBytesPerSec   float64
+	RxPacketsPerSec float64
+	TxPacketsPerSec float64
+}
+
+func (u *InterfaceUsage) add(o InterfaceUsage) {
+	u.RxBytes += o.RxBytes
+	u.TxBytes += o.TxBytes
+	u.RxPackets += o.RxPackets
+	u.TxPackets += o.TxPackets
+	u.RxBytesPerSec += o.RxBytesPerSec
+	u.TxBytesPerSec += o.TxBytesPerSec
+	u.RxPacketsPerSec += o.RxPacketsPerSec
+	u.TxPacketsPerSec += o.TxPacketsPerSec
+}
+
+// NetworkUsage is a container's traffic summed over its interfaces, with
+// a breakdown by interface.
+type NetworkUsage struct {
+	InterfaceUsage
+	Interfaces map[string]InterfaceUsage `json:",omitempty"`
+}
+
+// statsSample is the latest sample taken of a task's container, and when
+// the next one is due.
+type statsSample struct {
+	containerID string
+	usage       TaskUsage
+	cpuTotal    uint64
+	interval    time.Duration
+	next        time.Time
+}
+
+// TaskUsage returns the usage of every running task, asking the runtime
+// only for tasks whose next sample is due.
+func (w *Worker) TaskUsage() []TaskUsage {
+	var usage []TaskUsage
+	running := make(map[uuid.UUID]bool)
+	for _, t := range w.GetTasks() {
+		if t.State != task.Running || t.ContainerID == "" {
+			continue
+		}
+		running[t.ID] = true
+
+		u, err := w.latestUsage(t)
+		if err != nil {
+			log.Printf("[%s] Error reading stats for task %v: %v\n", t.CorrelationID, t.ID, err)
+			continue
+		}
+		usage = append(usage, u)
+	}
+
+	w.mu.Lock()
+	for id := range w.samples {
+		if !running[id] {
+			delete(w.samples, id)
+		}
+	}
+	w.mu.Unlock()
+	return usage
+}
+
+// TaskStats returns the usage of one running task, sampling it if its
+// next sample is due.
+func (w *Worker) TaskStats(id uuid.UUID) (TaskUsage, error) {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return TaskUsage{}, ErrTaskNotFound
+	}
+	if t.State != task.Running || t.ContainerID == "" {
+		return TaskUsage{}, fmt.Errorf("%w: task %v is %v", ErrTaskNotRunning, id, t.State)
+	}
+	return w.latestUsage(t)
+}
+
+// latestUsage returns the task's latest sample, taking a new one if it's
+// due.
+func (w *Worker) latestUsage(t task.Task) (TaskUsage, error) {
+	now := w.clock().Now()
+	w.mu.Lock()
+	prev := w.samples[t.ID]
+	w.mu.Unlock()
+	if prev != nil && prev.containerID != t.ContainerID {
+		prev = nil
+	}
+	if prev != nil && now.Before(prev.next) {
+		return prev.usage, nil
+	}
+
+	s, err := w.sampleStats(t, prev, now)
+	if err != nil {
+		return TaskUsage{}, err
+	}
+	w.mu.Lock()
+	if w.samples == nil {
+		w.samples = make(map[uuid.UUID]*statsSample)
+	}
+	w.samples[t.ID] = s
+	w.mu.Unlock()
+	return s.usage, nil
+}
+
+func (w *Worker) sampleStats(t task.Task, prev *statsSample, now time.Time) (*statsSample, error) {
+	d, err := w.runtime(&t)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&w.statsCalls, 1)
+	stats, err := d.Stats(t.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+	return w.newStatsSample(t, prev, stats, now), nil
+}
+
+// newStatsSample turns stats read from the runtime at now into the
+// task's next sample.
+func (w *Worker) newStatsSample(t task.Task, prev *statsSample, stats *types.StatsJSON, now time.Time) *statsSample {
+	// Page cache is reclaimable, so it doesn't count as used.
+	mem := stats.MemoryStats.Usage
+	if cache, ok := stats.MemoryStats.Stats["inactive_file"]; ok && cache < mem {
+		mem -= cache
+	} else if cache, ok := stats.MemoryStats.Stats["cache"]; ok && cache < mem {
+		mem -= cache
+	}
+
+	// The CPU counter is divided by the time actually elapsed between
+	// samples, so it stays right however far apart they are.
+	total := stats.CPUStats.CPUUsage.TotalUsage
+	var cpu float64
+	if prev != nil && total >= prev.cpuTotal && now.After(prev.usage.SampledAt) {
+		cpu = float64(total-prev.cpuTotal) / float64(now.Sub(prev.usage.SampledAt)/time.Nanosecond)
+	} else if up := now.Sub(t.StartTime); up > 0 {
+		cpu = float64(total) / float64(up/time.Nanosecond)
+	}
+
+	s := &statsSample{
+		containerID: t.ContainerID,
+		usage: TaskUsage{
+			ID:        t.ID,
+			Name:      t.Name,
+			CPU:       cpu,
+			Memory:    int64(mem),
+			Network:   networkUsage(stats.Networks, prev, now),
+			SampledAt: now,
+		},
+		cpuTotal: total,
+	}
+	s.interval = w.nextStatsInterval(t, prev, s.usage, now)
+	s.next = now.Add(s.interval)
+	return s
+}
+
+// nextStatsInterval backs off sampling of a task whose usage is steady
+// and comfortably below its limits, and goes back to the base interval
+// as soon as it isn't.
+func (w *Worker) nextStatsInterval(t task.Task, prev *statsSample, u TaskUsage, now time.Time) time.Duration {
+	base, max := w.StatsBaseInterval, w.StatsMaxInterval
+	if base <= 0 {
+		base = defaultStatsBaseInterval
+	}
+	if max <= 0 {
+		max = defaultStatsMaxInterval
+	}
+	if max < base {
+		max = base
+	}
+
+	nearLimit := (t.Memory > 0 && float64(u.Memory) >= statsNearLimit*float64(t.Memory)) ||
+		(t.CPU > 0 && u.CPU >= statsNearLimit*t.CPU)
+	if prev == nil || now.Sub(t.StartTime) < statsWarmup || nearLimit || !stableUsage(prev.usage, u) {
+		return base
+	}
+	interval := prev.interval * 2
+	if interval > max {
+		interval = max
+	}
+	return interval
+}
+
+func stableUsage(a, b TaskUsage) bool {
+	memChange := math.Abs(float64(b.Memory - a.Memory))
+	cpuChange := math.Abs(b.CPU - a.CPU)
+	return memChange <= statsStableChange*float64(a.Memory) &&
+		cpuChange <= statsStableChange*math.Max(a.CPU, 1)
+}
+
+// networkUsage turns the runtime's per-interface counters into usage,
+// with rates over the time since the previous sample. Counters that went
+// backwards, as after an interface is recreated, give no rate.
+func networkUsage(networks map[string]types.NetworkStats, prev *statsSample, now time.Time) NetworkUsage {
+	var u NetworkUsage
+	if len(networks) == 0 {
+		return u
+	}
+	u.Interfaces = make(map[string]InterfaceUsage, len(networks))
+	for name, n := range networks {
+		iface := InterfaceUsage{
+			RxBytes:   n.RxBytes,
+			TxBytes:   n.TxBytes,
+			RxPackets: n.RxPackets,
+			TxPackets: n.TxPackets,
+		}
+		last, seen := InterfaceUsage{}, false
+		if prev != nil {
+			last, seen = prev.usage.Network.Interfaces[name]
+		}
+		if seen && now.After(prev.usage.SampledAt) {
+			secs := now.Sub(prev.usage.SampledAt).Seconds()
+			iface.RxBytesPerSec = counterRate(last.RxBytes, n.RxBytes, secs)
+			iface.TxBytesPerSec = counterRate(last.TxBytes, n.TxBytes, secs)
+			iface.RxPacketsPerSec = counterRate(last.RxPackets, n.RxPackets, secs)
+			iface.TxPacketsPerSec = counterRate(last.TxPackets, n.TxPackets, secs)
+		}
+		u.Interfaces[name] = iface
+		u.add(iface)
+	}
+	return u
+}
+
+func counterRate(prev, cur uint64, secs float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / secs
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// BenchmarkStatsCalls polls a node of 200 steady containers every 10s
+// for an hour and reports how many stats requests reach the daemon per
+// poll, sampling every task each poll ("before") and sparsely ("after").
+func BenchmarkStatsCalls(b *testing.B) {
+	const (
+		containers = 200
+		poll       = 10 * time.Second
+		polls      = 360
+	)
+	for _, bc := range []struct {
+		name string
+		max  time.Duration
+	}{
+		{"before", poll},
+		{"after", defaultStatsMaxInterval},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			w := &Worker{StatsBaseInterval: poll, StatsMaxInterval: bc.max}
+			start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			tasks := make([]task.Task, containers)
+			for i := range tasks {
+				tasks[i] = task.Task{
+					ID:          uuid.New(),
+					ContainerID: fmt.Sprintf("c%d", i),
+					Memory:      1 << 30,
+					CPU:         1,
+					StartTime:   start.Add(-time.Hour),
+				}
+			}
+
+			var calls int
+			for i := 0; i < b.N; i++ {
+				calls = 0
+				samples := make(map[uuid.UUID]*statsSample, containers)
+				for p := 0; p < polls; p++ {
+					now := start.Add(time.Duration(p) * poll)
+					for _, t := range tasks {
+						prev := samples[t.ID]
+						if prev != nil && now.Before(prev.next) {
+							continue
+						}
+						calls++
+						samples[t.ID] = w.newStatsSample(t, prev, steadyStats(now.Sub(t.StartTime)), now)
+					}
+				}
+			}
+			b.ReportMetric(float64(calls)/polls, "calls/poll")
+		})
+	}
+}
+
+// steadyStats is a container that has used a quarter of a core and
+// 256MiB since it started up seconds ago.
+func steadyStats(up time.Duration) *types.StatsJSON {
+	var s types.StatsJSON
+	s.CPUStats.CPUUsage.TotalUsage = uint64(up.Nanoseconds() / 4)
+	s.MemoryStats.Usage = 256 << 20
+	return &s
+}
+
+func TestStatsCPUWithVariableIntervals(t *testing.T) {
+	w := &Worker{}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tk := task.Task{ID: uuid.New(), ContainerID: "c", StartTime: start}
+
+	var prev *statsSample
+	for _, at := range []time.Duration{10 * time.Second, 20 * time.Second, 100 * time.Second, 101 * time.Second} {
+		prev = w.newStatsSample(tk, prev, steadyStats(at), start.Add(at))
+		if got := prev.usage.CPU; got < 0.249 || got > 0.251 {
+			t.Errorf("CPU at %v = %v, want 0.25", at, got)
+		}
+	}
+}
@@ -1,33 +1,647 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/docker/docker/errdefs"
 	"github.com/golang-collections/collections/queue"
 	"github.com/google/uuid"
 
+	"github.com/sajalkmr/ordo/clock"
 	"github.com/sajalkmr/ordo/task"
 )
 
+var (
+	ErrTaskNotFound   = errors.New("task not found")
+	ErrAtCapacity     = errors.New("worker is at task capacity")
+	ErrTaskNotRunning = errors.New("task is not running")
+	ErrLowDisk        = errors.New("not enough free disk space")
+	ErrTaskRunning    = errors.New("task is still running")
+	ErrPullFailed     = errors.New("image pull failed")
+)
+
 type Worker struct {
 	Name      string
 	Queue     queue.Queue
 	Db        map[uuid.UUID]*task.Task
 	TaskCount int
+	// MaxTasks caps the number of concurrent tasks on this worker. Zero
+	// means no cap.
+	MaxTasks int
+	// DrainTimeout is how long Shutdown waits for tasks to stop before
+	// killing them. Zero uses defaultDrainTimeout.
+	DrainTimeout time.Duration
+	// Manager is the base URL of the manager's API, used to tell it which
+	// tasks are going down on shutdown. Empty skips the notification.
+	Manager string
+	// ManagerToken is the manager's admin token, which it requires to
+	// drain a node.
+	ManagerToken string
+	// Runtime is the container runtime tasks run on, "docker" or
+	// "podman". Empty means Docker.
+	Runtime string
+	// MinFreeDisk is the free space, in bytes, DiskPath must have for the
+	// worker to accept a task. Zero disables the check.
+	MinFreeDisk uint64
+	// DiskPath is where the runtime stores images. Empty means
+	// /var/lib/docker.
+	DiskPath string
+	// PruneOnLowDisk prunes dangling images when free space is low before
+	// refusing a task.
+	PruneOnLowDisk bool
+	// Secrets resolves ${secret:name} references in task env. Tasks
+	// using them fail to start when it's nil.
+	Secrets task.SecretProvider
+	// Workspace is the directory relative mount sources are resolved
+	// against, and that every mount source must be inside. Tasks with
+	// mounts fail to start when it's empty.
+	Workspace string
+	// Clock drives probes, restart backoff and drain timeouts. Nil uses
+	// the wall clock.
+	Clock clock.Clock
+	// DefaultRestartPolicy applies to tasks that arrive without a restart
+	// policy.
+	DefaultRestartPolicy string
+	// RemoveImagesOnStop removes a finished task's image once no other
+	// task uses it, as if every task set RemoveImageOnStop.
+	RemoveImagesOnStop bool
+	// LogDir is where tasks with LogToFile have their output written, as
+	// <name>-<short container id>.log. Files are rotated once they reach
+	// LogMaxBytes, keeping LogMaxFiles old ones. Zero values use
+	// defaultLogMaxBytes and defaultLogMaxFiles.
+	LogDir      string
+	LogMaxBytes int64
+	LogMaxFiles int
+	// DetachKeys is the key sequence that detaches an attached client.
+	// Empty uses task.DefaultDetachKeys.
+	DetachKeys string
+	// PullTokenTimeout is how long a pull waits for a token from the
+	// manager before the task fails. The wait holds up the task queue,
+	// so it's always bounded; zero uses defaultPullTokenTimeout.
+	PullTokenTimeout time.Duration
+	// CallbackSecret signs the results posted to tasks' CallbackURLs.
+	// CallbackLogBytes is how much of the end of a task's output they
+	// include; zero uses defaultCallbackLogBytes.
+	CallbackSecret   string
+	CallbackLogBytes int
+	// StatsBaseInterval is how often TaskUsage samples a task's
+	// container when it's new, near its limits or changing. Steady tasks
+	// are sampled less and less often, down to once per
+	// StatsMaxInterval. Zero values use defaultStatsBaseInterval and
+	// defaultStatsMaxInterval.
+	StatsBaseInterval time.Duration
+	StatsMaxInterval  time.Duration
+	// A task whose container can't be found is only failed once it has
+	// been missing for MissingContainerGrace and in at least
+	// MissingContainerChecks checks. Zero values use
+	// defaultMissingContainerGrace and defaultMissingContainerChecks.
+	MissingContainerGrace  time.Duration
+	MissingContainerChecks int
+	missing                map[uuid.UUID]*missingContainer
+	// MaxConcurrentStops bounds how many containers are stopped at once;
+	// the rest queue. Zero uses defaultMaxConcurrentStops.
+	MaxConcurrentStops int
+	stops              stopLimiter
+	// auth holds the registry credentials tasks arrived with, kept off
+	// the tasks in Queue and Db.
+	auth registryAuths
+	// usernsRemap is the daemon's userns-remap mode, detected when the
+	// API starts.
+	usernsRemap task.UsernsRemap
+
+	// mu guards Queue, Db and probes. Docker calls are made without
+	// holding it.
+	mu         sync.Mutex
+	probes     map[uuid.UUID]context.CancelFunc
+	crashLoops map[uuid.UUID]*crashLoop
+	forceStops map[uuid.UUID]bool
+	// deregistering holds the tasks waiting out their DeregisterDelay.
+	deregistering map[uuid.UUID]bool
+	samples       map[uuid.UUID]*statsSample
+	// statsCalls counts the container stats requests made to the
+	// runtime.
+	statsCalls uint64
+	// cpuPrev is the /proc/stat reading GetStats last took, which the
+	// next one measures CPU usage against.
+	cpuPrev cpuSample
+}
+
+func (w *Worker) AddTask(t task.Task) error {
+	if t.State != task.Completed {
+		if err := w.ensureDiskSpace(t); err != nil {
+			return err
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t.State != task.Completed && w.MaxTasks > 0 && w.activeTasks() >= w.MaxTasks {
+		return ErrAtCapacity
+	}
+	w.auth.keep(&t)
+	w.Queue.Enqueue(t)
+	return nil
+}
+
+// activeTasks must be called with w.mu held.
+func (w *Worker) activeTasks() int {
+	n := w.Queue.Len()
+	for _, t := range w.Db {
+		if !t.State.Terminal() {
+			n++
+		}
+	}
+	return n
+}
+
+func (w *Worker) GetTask(id uuid.UUID) (task.Task, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	t, ok := w.Db[id]
+	if !ok {
+		return task.Task{}, false
+	}
+	return *t, true
+}
+
+func (w *Worker) GetTasks() []task.Task {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tasks := make([]task.Task, 0, len(w.Db))
+	for _, t := range w.Db {
+		tasks = append(tasks, *t)
+	}
+	return tasks
+}
+
+func (w *Worker) putTask(t task.Task) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.Db[t.ID] = &t
 }
 
 func (w *Worker) CollectStats() {
 	fmt.Println("Collect stats")
 }
 
-func (w *Worker) RunTask() {
-	fmt.Println("Runn task")
+func (w *Worker) RunTask() task.DockerResult {
+	w.mu.Lock()
+	t := w.Queue.Dequeue()
+	if t == nil {
+		w.mu.Unlock()
+		log.Println("No tasks in the queue")
+		return task.DockerResult{Error: nil}
+	}
+
+	taskQueued := t.(task.Task)
+	taskPersisted := w.Db[taskQueued.ID]
+	if taskPersisted == nil {
+		taskPersisted = &taskQueued
+		w.Db[taskQueued.ID] = &taskQueued
+	}
+	persisted := *taskPersisted
+	w.mu.Unlock()
+
+	var result task.DockerResult
+	switch taskQueued.State {
+	case task.Pending, task.Scheduled:
+		result = w.StartTask(taskQueued)
+	case task.Completed:
+		result = w.StopTask(persisted)
+	default:
+		result.Error = fmt.Errorf("task %v has unexpected state %v", taskQueued.ID, taskQueued.State)
+	}
+	return result
+}
+
+func (w *Worker) clock() clock.Clock {
+	return clock.OrReal(w.Clock)
+}
+
+// runtime returns the worker's configured container runtime bound to the
+// task's config.
+func (w *Worker) runtime(t *task.Task) (task.Runtime, error) {
+	c := task.NewConfig(t)
+	if c.RegistryAuth == nil {
+		c.RegistryAuth = w.auth.get(t.ID)
+	}
+	c.Secrets = w.Secrets
+	c.MountBase = w.Workspace
+	c.DetachKeys = w.DetachKeys
+	c.UsernsRemap = w.usernsRemap
+	if c.InjectMetadataEnv {
+		c.Env = append(slices.Clip(c.Env), task.MetadataEnv(*t, w.Name)...)
+	}
+	return task.NewRuntime(w.Runtime, c)
+}
+
+func (w *Worker) StartTask(t task.Task) task.DockerResult {
+	t.StartTime = w.clock().Now().UTC()
+	if t.RestartPolicy == "" {
+		t.RestartPolicy = w.DefaultRestartPolicy
+	}
+	var result task.DockerResult
+	d, err := w.runtime(&t)
+	if err == nil {
+		err = w.ensureDiskSpace(t)
+	}
+	if err != nil {
+		result.Error = err
+	} else if pull := w.pull(d, t); pull.Error != nil {
+		result = pull
+	} else {
+		result = d.Run()
+		result.Timings = startTimings(t, pull, result)
+	}
+	t.InitSteps = result.InitSteps
+	t.Timings = result.Timings
+	if result.Error != nil {
+		log.Printf("[%s] Error running task %v: %v\n", t.CorrelationID, t.ID, result.Error)
+		t.State = task.Failed
+		t.StatusReason = result.Error.Error()
+		t.FinishTime = w.clock().Now().UTC()
+		w.putTask(t)
+		w.taskFinished(t)
+		return result
+	}
+
+	t.ContainerID = result.ContainerId
+	t.State = task.Running
+	if t.ReadinessProbe != nil {
+		t.State = task.Starting
+	}
+	w.putTask(t)
+	w.followLogs(t)
+	if t.ReadinessProbe != nil || t.LivenessProbe != nil {
+		w.startProbes(t)
+	}
+	return result
+}
+
+// setState moves a task to a new state unless it has already reached a
+// terminal one, so a late probe result can't resurrect a stopped task.
+func (w *Worker) setState(id uuid.UUID, state task.State, reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	t, ok := w.Db[id]
+	if !ok || t.State.Terminal() {
+		return
+	}
+	t.State = state
+	t.StatusReason = reason
+	if state.Terminal() {
+		t.FinishTime = w.clock().Now().UTC()
+	}
+}
+
+// StopTask stops the task's container. A running task with a
+// DeregisterDelay is taken out of service first and stopped once the
+// delay has passed, without holding up the queue; ForceStop skips that.
+func (w *Worker) StopTask(t task.Task) task.DockerResult {
+	w.stopProbes(t.ID)
+	if force := w.takeForceStop(t.ID); !force && t.DeregisterDelay > 0 && t.State == task.Running && w.deregister(t) {
+		return task.DockerResult{ContainerId: t.ContainerID, Action: "stop", Result: "deregistering"}
+	}
+	return w.stopTask(t)
+}
+
+func (w *Worker) stopTask(t task.Task) task.DockerResult {
+	prev, known := w.GetTask(t.ID)
+	notify := known && !prev.State.Terminal() && t.CallbackURL != ""
+
+	// A task with a callback keeps its container through the stop so its
+	// logs can be read, and has it removed afterwards.
+	stopped := t
+	remove := notify && t.ContainerID != "" && (t.RemoveOnStop == nil || *t.RemoveOnStop)
+	if remove {
+		keep := false
+		stopped.RemoveOnStop = &keep
+	}
+
+	var result task.DockerResult
+	var logs string
+	var truncated bool
+	d, err := w.runtime(&stopped)
+	if err != nil {
+		result.Error = err
+	} else if t.ContainerID != "" {
+		result = w.stop(d, stopped)
+		if result.Error == nil && notify {
+			logs, truncated = w.callbackLogs(d, t)
+		}
+		if result.Error == nil && remove {
+			if rm := d.Remove(t.ContainerID); rm.Error != nil {
+				result.Error = rm.Error
+			}
+		}
+	}
+	if result.Error != nil {
+		// Leave the task as it was so the stop can be retried rather than
+		// recording a container as stopped when it may still be running.
+		log.Printf("[%s] Error stopping container %v: %v\n", t.CorrelationID, t.ContainerID, result.Error)
+		return result
+	}
+	t.Timings.Stop = result.Timings.Stop
+	t.FinishTime = w.clock().Now().UTC()
+	t.State = task.Completed
+	w.putTask(t)
+	kept := t.RemoveOnStop != nil && !*t.RemoveOnStop
+	if kept {
+		log.Printf("[%s] Stopped container %v for task %v, keeping it for inspection\n", t.CorrelationID, t.ContainerID, t.ID)
+	} else {
+		log.Printf("[%s] Stopped and removed container %v for task %v\n", t.CorrelationID, t.ContainerID, t.ID)
+	}
+	if notify {
+		w.sendResult(t, logs, truncated)
+	}
+	// A kept container still uses its image; RemoveContainer releases it.
+	if !kept {
+		w.releaseImage(t)
+	}
+	return result
+}
+
+// RestartTask replaces the task's container with a fresh one from the
+// same config, switching to req.Image if it isn't empty. Init containers
+// only run again if req.RerunInit is set. The image is pulled while the
+// old container keeps running; if the pull fails the old container is
+// left alone. The stored task keeps its previous state until the new
+// container is up, so readers never see it half restarted.
+func (w *Worker) RestartTask(id uuid.UUID, req RestartRequest) (task.Task, error) {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return task.Task{}, ErrTaskNotFound
+	}
+	if t.State.Terminal() {
+		return task.Task{}, ErrTaskNotRunning
+	}
+	image := req.Image
+	if image != "" {
+		t.Image = image
+	}
+
+	run := t
+	if !req.RerunInit {
+		run.InitContainers = nil
+	}
+	d, err := w.runtime(&run)
+	if err != nil {
+		return task.Task{}, err
+	}
+	pull := w.pull(d, t)
+	if pull.Error != nil {
+		return task.Task{}, fmt.Errorf("%w: %s: %w", ErrPullFailed, t.Image, pull.Error)
+	}
+	w.stopProbes(id)
+	if t.ContainerID != "" {
+		result := w.stop(d, t)
+		if result.Error == nil {
+			result = d.Remove(t.ContainerID)
+		}
+		if result.Error != nil {
+			return task.Task{}, result.Error
+		}
+	}
+	result := d.Run()
+
+	w.mu.Lock()
+	stored, ok := w.Db[id]
+	if !ok {
+		w.mu.Unlock()
+		return task.Task{}, ErrTaskNotFound
+	}
+	stored.RestartCount++
+	stored.Image = t.Image
+	stored.StartTime = w.clock().Now().UTC()
+	reason := "restarted by request"
+	if image != "" {
+		reason = fmt.Sprintf("restarted with image %s", image)
+	}
+	stored.RecordRestart(task.RestartEvent{
+		Time:        stored.StartTime,
+		Reason:      reason,
+		ExitCode:    stored.ExitCode,
+		ContainerID: t.ContainerID,
+	})
+	stored.Timings = startTimings(*stored, pull, result)
+	if req.RerunInit {
+		stored.InitSteps = result.InitSteps
+	}
+	if result.Error != nil {
+		stored.ContainerID = ""
+		stored.State = task.Failed
+		stored.StatusReason = result.Error.Error()
+		stored.FinishTime = stored.StartTime
+	} else {
+		stored.ContainerID = result.ContainerId
+		stored.State = task.Running
+		if stored.ReadinessProbe != nil {
+			stored.State = task.Starting
+		}
+		stored.StatusReason = ""
+	}
+	restarted := *stored
+	w.mu.Unlock()
+
+	if result.Error != nil {
+		return restarted, result.Error
+	}
+	w.followLogs(restarted)
+	if restarted.ReadinessProbe != nil || restarted.LivenessProbe != nil {
+		w.startProbes(restarted)
+	}
+	log.Printf("[%s] Restarted task %v in container %s (restart %d)\n",
+		restarted.CorrelationID, id, restarted.ContainerID, restarted.RestartCount)
+	return restarted, nil
+}
+
+// RemoveContainer deletes the container a stopped task kept for
+// inspection, then releases its image as a stop without keeping would
+// have.
+func (w *Worker) RemoveContainer(id uuid.UUID) error {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if !t.State.Terminal() {
+		return fmt.Errorf("%w: task %v is %v", ErrTaskRunning, id, t.State)
+	}
+	if t.ContainerID == "" {
+		return nil
+	}
+
+	d, err := w.runtime(&t)
+	if err != nil {
+		return err
+	}
+	if result := d.Remove(t.ContainerID); result.Error != nil {
+		return result.Error
+	}
+
+	w.mu.Lock()
+	if stored, ok := w.Db[id]; ok && stored.ContainerID == t.ContainerID {
+		stored.ContainerID = ""
+	}
+	w.mu.Unlock()
+
+	w.releaseImage(t)
+	return nil
+}
+
+// DiffTask lists the paths the task's container changed relative to its
+// image.
+func (w *Worker) DiffTask(id uuid.UUID) ([]task.ContainerChange, error) {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	if t.ContainerID == "" {
+		return nil, fmt.Errorf("%w: task %v has no container", ErrTaskNotRunning, id)
+	}
+
+	d, err := w.runtime(&t)
+	if err != nil {
+		return nil, err
+	}
+	return d.Diff(t.ContainerID)
 }
 
-func (w *Worker) StartTask() {
-	fmt.Println("Start task")
+func (w *Worker) InspectTask(t task.Task) task.DockerInspectResponse {
+	d, err := w.runtime(&t)
+	if err != nil {
+		return task.DockerInspectResponse{Error: err}
+	}
+	return d.Inspect(t.ContainerID)
 }
 
-func (w *Worker) StopTask() {
-	fmt.Println("Stop task")
+// UpdateTasks moves running tasks whose container has exited to a
+// terminal state, recording the exit code and why the task failed. Tasks
+// with a RestartBackoff are restarted instead until they run out of
+// attempts.
+func (w *Worker) UpdateTasks() {
+	w.restartCrashed()
+	for _, t := range w.GetTasks() {
+		if t.State != task.Running && t.State != task.Starting {
+			continue
+		}
+		if w.waitingRestart(t.ID) {
+			continue
+		}
+
+		resp := w.InspectTask(t)
+		if errdefs.IsNotFound(resp.Error) {
+			w.failMissing(t)
+			continue
+		}
+		if resp.Error != nil {
+			log.Printf("[%s] Error inspecting task %v: %v\n", t.CorrelationID, t.ID, resp.Error)
+			continue
+		}
+		w.mu.Lock()
+		w.containerFound(t.ID)
+		w.mu.Unlock()
+		if resp.Container.State.Running {
+			continue
+		}
+
+		w.stopProbes(t.ID)
+		code, err := task.ContainerExit(resp.Container)
+		state, reason := task.Completed, ""
+		switch {
+		case errors.Is(err, task.ErrOOMKilled):
+			state, reason = task.Failed, task.OOMKilledReason
+		case err != nil:
+			state, reason = task.Failed, err.Error()
+		}
+
+		w.mu.Lock()
+		restarting, finished := false, task.Task{}
+		if stored, ok := w.Db[t.ID]; ok && stored.ContainerID == t.ContainerID && !stored.State.Terminal() {
+			stored.ExitCode = code
+			if state == task.Failed && stored.WorkerRestarts() {
+				restarting = w.scheduleRestart(stored, reason)
+				if !restarting {
+					reason = fmt.Sprintf("%s; giving up after %d restarts", reason, stored.RestartBackoff.Attempts())
+				}
+			}
+			if !restarting {
+				stored.FinishTime = w.clock().Now().UTC()
+				stored.State = state
+				stored.StatusReason = reason
+				finished = *stored
+			}
+		}
+		w.mu.Unlock()
+		if restarting {
+			log.Printf("[%s] Task %v crashed with code %d, restart scheduled\n", t.CorrelationID, t.ID, code)
+			continue
+		}
+		log.Printf("[%s] Task %v exited with code %d: %v\n", t.CorrelationID, t.ID, code, state)
+		if finished.State.Terminal() {
+			w.taskFinished(finished)
+		}
+		w.releaseImage(t)
+	}
+}
+
+func (w *Worker) UpdateTaskResources(id uuid.UUID, cpu float64, memory int64) error {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	if t.ContainerID != "" {
+		d, err := w.runtime(&t)
+		if err != nil {
+			return err
+		}
+		result := d.UpdateResources(t.ContainerID, cpu, memory)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if stored, ok := w.Db[id]; ok {
+		stored.CPU = cpu
+		stored.Memory = memory
+	}
+	return nil
+}
+
+func (w *Worker) RenameTask(id uuid.UUID, name string) error {
+	t, ok := w.GetTask(id)
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	if t.ContainerID != "" {
+		d, err := w.runtime(&t)
+		if err != nil {
+			return err
+		}
+		result := d.Rename(t.ContainerID, name)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if stored, ok := w.Db[id]; ok {
+		stored.Name = name
+	}
+	return nil
 }
@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/golang-collections/collections/queue"
+	"github.com/google/uuid"
+	"github.com/sajalkmr/ordo/task"
+)
+
+// TestConcurrentTaskAccess submits tasks, moves them through their
+// states, polls their usage and lists them all at once. Run it with
+// -race: it only fails on its own if a listing comes back garbled.
+func TestConcurrentTaskAccess(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	w := &Worker{Name: "worker-1", Queue: *queue.New(), Db: make(map[uuid.UUID]*task.Task)}
+	a := &Api{Worker: w}
+	a.initRouter()
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var b bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&b).Encode(body)
+		}
+		rec := httptest.NewRecorder()
+		a.Router.ServeHTTP(rec, httptest.NewRequest(method, path, &b))
+		return rec
+	}
+
+	const tasks = 100
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Submit tasks through the API.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < tasks; i++ {
+			te := task.TaskEvent{ID: uuid.New(), Task: task.Task{ID: uuid.New(), Name: "task", Image: "alpine"}}
+			if rec := call(http.MethodPost, "/tasks", te); rec.Code != http.StatusCreated {
+				t.Errorf("POST /tasks returned %d: %s", rec.Code, rec.Body)
+			}
+		}
+	}()
+
+	// Stand in for the run loop: take tasks off the queue, start them and
+	// finish them, as RunTask and UpdateTasks do.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for finished := 0; finished < tasks; {
+			w.mu.Lock()
+			item := w.Queue.Dequeue()
+			w.mu.Unlock()
+			if item == nil {
+				continue
+			}
+			tk := item.(task.Task)
+			tk.State = task.Running
+			w.putTask(tk)
+			w.setState(tk.ID, task.Completed, "")
+			finished++
+		}
+	}()
+
+	// Poll usage and list tasks until the others are done.
+	readers := []func(){
+		func() { call(http.MethodGet, "/tasks/usage", nil) },
+		func() { w.TaskUsage() },
+		func() {
+			rec := call(http.MethodGet, "/tasks", nil)
+			var listed []task.Task
+			if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+				t.Errorf("GET /tasks returned unreadable JSON: %v", err)
+			}
+		},
+	}
+	var readerWg sync.WaitGroup
+	for _, read := range readers {
+		readerWg.Add(1)
+		go func(read func()) {
+			defer readerWg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					read()
+				}
+			}
+		}(read)
+	}
+
+	wg.Wait()
+	close(done)
+	readerWg.Wait()
+
+	for _, tk := range w.GetTasks() {
+		if tk.State != task.Completed {
+			t.Errorf("task %v is %v, want Completed", tk.ID, tk.State)
+		}
+	}
+	if got := len(w.GetTasks()); got != tasks {
+		t.Errorf("worker has %d tasks, want %d", got, tasks)
+	}
+}